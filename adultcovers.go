@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Values of adult_covers
+const (
+	adultCoversSpoiler = "spoiler" // cover sent as a spoilered attachment (default)
+	adultCoversOmit    = "omit"    // no cover at all
+	adultCoversInline  = "inline"  // cover embedded like any other title
+)
+
+// maxCoverBytes bounds the size of a cover re-uploaded as an attachment
+const maxCoverBytes = 4 << 20
+
+// adultCoverFiles takes the covers of adult titles out of their embeds (embeds[n] shows media[n])
+// and, with adult_covers "spoiler", returns them as spoilered attachments. Discord cannot blur an
+// embed image, so this is the only way to keep such covers hidden until clicked.
+func (h *handler) adultCoverFiles(embeds []*discordgo.MessageEmbed, media []*aniListMedia) []*discordgo.File {
+	if h.cfg().AdultCovers == adultCoversInline {
+		return nil
+	}
+	var files []*discordgo.File
+	for n, m := range media {
+		if n >= len(embeds) || m == nil || !m.Adult || m.CoverURL == "" {
+			continue
+		}
+		embeds[n].Image = nil
+		embeds[n].Thumbnail = nil
+		if h.cfg().AdultCovers != adultCoversSpoiler {
+			continue
+		}
+		f, err := downloadCover(m.CoverURL)
+		if err != nil {
+			log.Printf("search: failed to download cover of %q: %v", m.Title, err)
+			continue
+		}
+		ext := path.Ext(f.Name)
+		if ext == "" {
+			ext = ".jpg"
+		}
+		f.Name = fmt.Sprintf("SPOILER_cover%d%s", n+1, ext)
+		files = append(files, f)
+	}
+	return files
+}
+
+// downloadCover fetches a cover image as a message attachment
+func downloadCover(url string) (*discordgo.File, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("cover returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxCoverBytes {
+		return nil, fmt.Errorf("cover is larger than %d bytes", maxCoverBytes)
+	}
+	name := path.Base(strings.SplitN(url, "?", 2)[0])
+	return &discordgo.File{Name: name, ContentType: resp.Header.Get("Content-Type"), Reader: bytes.NewReader(body)}, nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const airingBucket = "airing_subscriptions"
+
+// airingReminder is how long before an episode airs subscribers get the first notification
+const airingReminder = time.Hour
+
+// airingMu serializes read-modify-write cycles on airing subscriptions
+var airingMu sync.Mutex
+
+// airingSubscription lists who follows one anime and which notifications were already sent
+type airingSubscription struct {
+	MediaID  int      `json:"media_id"`
+	Title    string   `json:"title"`
+	SiteURL  string   `json:"site_url"`
+	Users    []string `json:"users,omitempty"`
+	Channels []string `json:"channels,omitempty"`
+	// next episode as last reported by AniList
+	NextEpisode  int   `json:"next_episode"`
+	NextAiringAt int64 `json:"next_airing_at"`
+	// last episodes announced as upcoming and as aired
+	RemindedEpisode int `json:"reminded_episode"`
+	AiredEpisode    int `json:"aired_episode"`
+}
+
+func init() {
+	titleOption := &discordgo.ApplicationCommandOption{Type: discordgo.ApplicationCommandOptionString, Name: "title", Description: "Anime title", Required: true}
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "airing",
+		Description: "Get notified when new episodes of an anime air",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "subscribe", Description: "Follow an anime", Options: []*discordgo.ApplicationCommandOption{
+				titleOption,
+				{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "Post in this channel instead of DMing you (moderators only)", ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews}},
+			}},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "unsubscribe", Description: "Stop following an anime", Options: []*discordgo.ApplicationCommandOption{
+				titleOption,
+				{Type: discordgo.ApplicationCommandOptionChannel, Name: "channel", Description: "Remove the channel's subscription instead of yours (moderators only)", ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews}},
+			}},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "List the anime you follow"},
+		},
+	}, (*handler).handleAiringCommand)
+	requireStore("airing")
+}
+
+// handleAiringCommand implements /airing subscribe|unsubscribe|list
+func (h *handler) handleAiringCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
+	data := i.ApplicationCommandData()
+	if user == nil || len(data.Options) == 0 {
+		return
+	}
+	sub := data.Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+
+	if sub.Name == "list" {
+		respondEphemeral(s, i, h.listAiringSubscriptions(user.ID))
+		return
+	}
+
+	channelID := ""
+	if o, ok := opts["channel"]; ok {
+		if !h.interactionCanManage(s, i) {
+			respondEphemeral(s, i, "Only moderators can manage channel subscriptions.")
+			return
+		}
+		channelID = o.ChannelValue(nil).ID
+	}
+	title := strings.TrimSpace(opts["title"].StringValue())
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	}); err != nil {
+		log.Printf("airing: failed to acknowledge: %v", err)
+		return
+	}
+	reply := h.updateAiringSubscription(title, user.ID, channelID, sub.Name == "subscribe")
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &reply}); err != nil {
+		log.Printf("airing: failed to send result: %v", err)
+	}
+}
+
+// updateAiringSubscription adds or removes the user (or channel, when set) to the subscription
+// of the anime best matching title and returns the reply
+func (h *handler) updateAiringSubscription(title, userID, channelID string, subscribe bool) string {
+	media, err := searchAniList(title, "ANIME", false)
+	if err != nil {
+		log.Printf("airing: AniList error for %q: %v", title, err)
+		return "❌ Could not reach AniList, try again later."
+	}
+	if media == nil {
+		return fmt.Sprintf("No anime found for %q.", title)
+	}
+	key := strconv.Itoa(media.ID)
+
+	airingMu.Lock()
+	defer airingMu.Unlock()
+	sub := airingSubscription{MediaID: media.ID, Title: media.Title, SiteURL: media.SiteURL}
+	if _, err := h.store.Get(airingBucket, key, &sub); err != nil {
+		log.Printf("airing: failed to load subscription %s: %v", key, err)
+		return "❌ Could not load subscriptions, try again later."
+	}
+	target, id, who, follows, doesNot := &sub.Users, userID, "You", "follow", "do not"
+	if channelID != "" {
+		target, id, who, follows, doesNot = &sub.Channels, channelID, "<#"+channelID+">", "follows", "does not"
+	}
+	idx := -1
+	for n, v := range *target {
+		if v == id {
+			idx = n
+		}
+	}
+
+	var reply string
+	switch {
+	case subscribe && idx >= 0:
+		return fmt.Sprintf("%s already %s **%s**.", who, follows, sub.Title)
+	case subscribe:
+		*target = append(*target, id)
+		reply = fmt.Sprintf("%s will be notified an hour before each new episode of **%s** airs, and once it has aired.", who, sub.Title)
+	case idx < 0:
+		return fmt.Sprintf("%s %s follow **%s**.", who, doesNot, sub.Title)
+	default:
+		*target = append((*target)[:idx], (*target)[idx+1:]...)
+		reply = fmt.Sprintf("%s will no longer be notified about **%s**.", who, sub.Title)
+	}
+	if len(sub.Users) == 0 && len(sub.Channels) == 0 {
+		err = h.store.Delete(airingBucket, key)
+	} else {
+		err = h.store.Put(airingBucket, key, sub)
+	}
+	if err != nil {
+		log.Printf("airing: failed to save subscription %s: %v", key, err)
+		return "❌ Could not save the subscription, try again later."
+	}
+	return reply
+}
+
+// listAiringSubscriptions describes the anime a user follows
+func (h *handler) listAiringSubscriptions(userID string) string {
+	subs, err := h.loadAiringSubscriptions()
+	if err != nil {
+		log.Printf("airing: failed to list subscriptions: %v", err)
+		return "❌ Could not load subscriptions, try again later."
+	}
+	var lines []string
+	for _, sub := range subs {
+		for _, u := range sub.Users {
+			if u != userID {
+				continue
+			}
+			line := fmt.Sprintf("- [%s](<%s>)", sub.Title, sub.SiteURL)
+			if sub.NextAiringAt > 0 {
+				line += fmt.Sprintf(": episode %d <t:%d:R>", sub.NextEpisode, sub.NextAiringAt)
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "You do not follow any anime. Use `/airing subscribe` to start."
+	}
+	sort.Strings(lines)
+	return truncateRunes(strings.Join(lines, "\n"), 1900)
+}
+
+func (h *handler) loadAiringSubscriptions() ([]airingSubscription, error) {
+	raw, err := h.store.List(airingBucket)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]airingSubscription, 0, len(raw))
+	for key := range raw {
+		var sub airingSubscription
+		if found, err := h.store.Get(airingBucket, key, &sub); err == nil && found {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+// notifyAiring is the `airing-notify` job: it refreshes the next episode of every followed anime
+// and sends the "airs in 1 hour" and "has aired" notifications that are due
+func (h *handler) notifyAiring(ctx context.Context, job jobRecord) error {
+	airingMu.Lock()
+	defer airingMu.Unlock()
+	subs, err := h.loadAiringSubscriptions()
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	ids := make([]int, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.MediaID)
+	}
+	schedules, err := fetchNextEpisodes(ids)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, sub := range subs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		before := sub
+		// the episode we were waiting for has aired
+		if sub.NextEpisode > sub.AiredEpisode && sub.NextAiringAt > 0 && now.Unix() >= sub.NextAiringAt {
+			h.sendAiringNotice(sub, fmt.Sprintf("📺 Episode **%d** of **%s** has aired: <%s>", sub.NextEpisode, sub.Title, sub.SiteURL))
+			sub.AiredEpisode = sub.NextEpisode
+		}
+		next, ok := schedules[sub.MediaID]
+		if ok {
+			sub.NextEpisode, sub.NextAiringAt = next.Episode, next.AiringAt
+		} else if sub.AiredEpisode >= sub.NextEpisode {
+			sub.NextAiringAt = 0
+		}
+		if sub.NextAiringAt > 0 && sub.NextEpisode > sub.RemindedEpisode && time.Unix(sub.NextAiringAt, 0).Sub(now) <= airingReminder {
+			h.sendAiringNotice(sub, fmt.Sprintf("⏰ Episode **%d** of **%s** airs <t:%d:R>.", sub.NextEpisode, sub.Title, sub.NextAiringAt))
+			sub.RemindedEpisode = sub.NextEpisode
+		}
+		if sub.NextEpisode != before.NextEpisode || sub.NextAiringAt != before.NextAiringAt ||
+			sub.RemindedEpisode != before.RemindedEpisode || sub.AiredEpisode != before.AiredEpisode {
+			if err := h.store.Put(airingBucket, strconv.Itoa(sub.MediaID), sub); err != nil {
+				log.Printf("airing: failed to save subscription %d: %v", sub.MediaID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendAiringNotice posts a notification to every subscribed channel and DMs every subscribed user
+func (h *handler) sendAiringNotice(sub airingSubscription, text string) {
+	for _, channelID := range sub.Channels {
+		sendMessage(h.dg, channelID, text)
+	}
+	for _, userID := range sub.Users {
+		dm, err := h.dg.UserChannelCreate(userID)
+		if err != nil {
+			log.Printf("airing: failed to open DM with %s: %v", userID, err)
+			continue
+		}
+		sendMessage(h.dg, dm.ID, text)
+	}
+}
+
+// airingEpisode is the next scheduled episode of an anime
+type airingEpisode struct {
+	Episode  int   `json:"episode"`
+	AiringAt int64 `json:"airingAt"`
+}
+
+// fetchNextEpisodes returns the next airing episode of each of the anime that has one, 50 per request
+func fetchNextEpisodes(ids []int) (map[int]airingEpisode, error) {
+	out := map[int]airingEpisode{}
+	for start := 0; start < len(ids); start += 50 {
+		end := start + 50
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var data struct {
+			Page struct {
+				Media []struct {
+					ID                int            `json:"id"`
+					NextAiringEpisode *airingEpisode `json:"nextAiringEpisode"`
+				} `json:"media"`
+			} `json:"Page"`
+		}
+		err := aniListGraphQL(`query ($ids: [Int]) {
+			Page(page: 1, perPage: 50) {
+				media(id_in: $ids, type: ANIME) { id nextAiringEpisode { episode airingAt } }
+			}
+		}`, map[string]interface{}{"ids": ids[start:end]}, &data)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range data.Page.Media {
+			if m.NextAiringEpisode != nil {
+				out[m.ID] = *m.NextAiringEpisode
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	aniListEndpoint = "https://graphql.anilist.co"
+	// aniListAttempts is how often a request is tried when AniList is rate limiting or failing
+	aniListAttempts = 4
+	// aniListBackoff is the first retry delay of a failed request; it doubles for every retry
+	aniListBackoff = 500 * time.Millisecond
+	// aniListReserve is the number of remaining requests in the current window below which new
+	// requests wait for the window to reset
+	aniListReserve = 2
+	// aniListMaxWait bounds how long a request waits for the rate limit before giving up
+	aniListMaxWait = 30 * time.Second
+)
+
+// aniListToken holds the optional anilist_token (a string) sent with every request
+var aniListToken atomic.Value
+
+// aniListLimit follows AniList's rate limit headers across requests
+var aniListLimit = struct {
+	sync.Mutex
+	remaining int
+	// when the window resets or the Retry-After delay ends; zero when unknown
+	resetAt time.Time
+}{remaining: -1}
+
+// aniListRequest POSTs a GraphQL payload to AniList and returns the 200 response body. Requests
+// wait while the rate limit is (nearly) exhausted, and 429 and 5xx responses and network errors
+// are retried with jittered exponential backoff.
+func aniListRequest(payload []byte) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt < aniListAttempts; attempt++ {
+		if attempt > 0 {
+			delay := aniListBackoff << (attempt - 1)
+			time.Sleep(delay/2 + time.Duration(rand.Int63n(int64(delay))))
+		}
+		if err := waitForAniListLimit(); err != nil {
+			return nil, err
+		}
+		body, status, err := postAniList(payload)
+		switch {
+		case err != nil:
+			lastErr = err
+		case status == http.StatusOK:
+			return body, nil
+		case status == http.StatusTooManyRequests || status >= 500:
+			lastErr = fmt.Errorf("anilist returned status %d", status)
+		default:
+			log.Printf("search: AniList response status=%d body=%s", status, string(body))
+			return nil, fmt.Errorf("anilist returned status %d", status)
+		}
+		log.Printf("search: AniList attempt %d/%d failed: %v", attempt+1, aniListAttempts, lastErr)
+	}
+	return nil, lastErr
+}
+
+// waitForAniListLimit blocks until a request fits in AniList's rate limit. Requests queue for up
+// to aniListMaxWait; a longer window reported by AniList fails the lookup right away.
+func waitForAniListLimit() error {
+	aniListLimit.Lock()
+	wait := time.Duration(0)
+	if !aniListLimit.resetAt.IsZero() && aniListLimit.remaining >= 0 && aniListLimit.remaining <= aniListReserve {
+		wait = time.Until(aniListLimit.resetAt)
+	}
+	aniListLimit.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	if wait > aniListMaxWait {
+		return fmt.Errorf("anilist rate limit exhausted for %s", wait.Round(time.Second))
+	}
+	time.Sleep(wait)
+	return nil
+}
+
+// postAniList sends one request and records the rate limit headers of the response
+func postAniList(payload []byte) ([]byte, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", aniListEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", botUserAgent)
+	if token, _ := aniListToken.Load().(string); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+
+	aniListLimit.Lock()
+	defer aniListLimit.Unlock()
+	if n, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining")); err == nil {
+		aniListLimit.remaining = n
+	}
+	// AniList only sends the reset time with a 429. Without one the window is unknown, so requests
+	// are not held back on a guess; the 429 that follows says how long to wait.
+	if ts, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		aniListLimit.resetAt = time.Unix(ts, 0)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		aniListLimit.remaining = 0
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			aniListLimit.resetAt = time.Now().Add(time.Duration(secs) * time.Second)
+		}
+	}
+	return body, resp.StatusCode, nil
+}
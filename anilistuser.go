@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "anilist-user",
+		Description: "Show an AniList profile with watching and reading stats",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "AniList user name", Required: true},
+		},
+	}, (*handler).handleAniListUserCommand)
+}
+
+// aniListListStats are the statistics of one list (anime or manga) of an AniList user
+type aniListListStats struct {
+	Count           int     `json:"count"`
+	MeanScore       float64 `json:"meanScore"`
+	MinutesWatched  int     `json:"minutesWatched"`
+	EpisodesWatched int     `json:"episodesWatched"`
+	ChaptersRead    int     `json:"chaptersRead"`
+	VolumesRead     int     `json:"volumesRead"`
+	Genres          []struct {
+		Genre string `json:"genre"`
+		Count int    `json:"count"`
+	} `json:"genres"`
+}
+
+func (ls aniListListStats) topGenres() string {
+	var g []string
+	for _, genre := range ls.Genres {
+		g = append(g, genre.Genre)
+	}
+	if len(g) == 0 {
+		return "—"
+	}
+	return strings.Join(g, ", ")
+}
+
+// aniListUser is the subset of an AniList user profile shown by /anilist-user
+type aniListUser struct {
+	Name    string `json:"name"`
+	SiteURL string `json:"siteUrl"`
+	Avatar  struct {
+		Large string `json:"large"`
+	} `json:"avatar"`
+	BannerImage string `json:"bannerImage"`
+	Statistics  struct {
+		Anime aniListListStats `json:"anime"`
+		Manga aniListListStats `json:"manga"`
+	} `json:"statistics"`
+}
+
+// fetchAniListUser loads a public AniList profile by name, or nil when there is no such user
+func fetchAniListUser(name string) (*aniListUser, error) {
+	var data struct {
+		Page struct {
+			Users []aniListUser `json:"users"`
+		} `json:"Page"`
+	}
+	err := aniListGraphQL(`query ($name: String) {
+		Page(page: 1, perPage: 1) {
+			users(name: $name) {
+				name
+				siteUrl
+				avatar { large }
+				bannerImage
+				statistics {
+					anime { count meanScore minutesWatched episodesWatched genres(limit: 5, sort: COUNT_DESC) { genre count } }
+					manga { count meanScore chaptersRead volumesRead genres(limit: 5, sort: COUNT_DESC) { genre count } }
+				}
+			}
+		}
+	}`, map[string]interface{}{"name": name}, &data)
+	if err != nil || len(data.Page.Users) == 0 {
+		return nil, err
+	}
+	return &data.Page.Users[0], nil
+}
+
+func (u *aniListUser) toEmbed() *discordgo.MessageEmbed {
+	a, m := u.Statistics.Anime, u.Statistics.Manga
+	embed := &discordgo.MessageEmbed{
+		Title: u.Name,
+		URL:   u.SiteURL,
+		Color: 0x2f3136,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Anime", Value: fmt.Sprintf("%d titles\n%.1f days watched\n%d episodes\nmean score %.1f", a.Count, float64(a.MinutesWatched)/1440, a.EpisodesWatched, a.MeanScore), Inline: true},
+			{Name: "Manga", Value: fmt.Sprintf("%d titles\n%d chapters read\n%d volumes\nmean score %.1f", m.Count, m.ChaptersRead, m.VolumesRead, m.MeanScore), Inline: true},
+			{Name: "Favorite anime genres", Value: a.topGenres()},
+			{Name: "Favorite manga genres", Value: m.topGenres()},
+		},
+	}
+	if u.Avatar.Large != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: u.Avatar.Large}
+	}
+	if u.BannerImage != "" {
+		embed.Image = &discordgo.MessageEmbedImage{URL: u.BannerImage}
+	}
+	return embed
+}
+
+// handleAniListUserCommand implements /anilist-user name:<name>
+func (h *handler) handleAniListUserCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	name := strings.TrimSpace(slashOptions(i)["name"].StringValue())
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}); err != nil {
+		log.Printf("anilist-user: failed to acknowledge: %v", err)
+		return
+	}
+	user, err := fetchAniListUser(name)
+	if err != nil {
+		log.Printf("anilist-user: AniList error for %q: %v", name, err)
+	}
+	edit := &discordgo.WebhookEdit{}
+	switch {
+	case user != nil:
+		edit.Embeds = &[]*discordgo.MessageEmbed{user.toEmbed()}
+	case err != nil:
+		msg := "❌ Could not reach AniList, try again later."
+		edit.Content = &msg
+	default:
+		msg := fmt.Sprintf("No AniList user named %q (or the profile is private).", name)
+		edit.Content = &msg
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		log.Printf("anilist-user: failed to send result: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxArchivedAttachmentBytes skips attachments larger than this to keep archives bounded
+const maxArchivedAttachmentBytes = 25 << 20
+
+// archivedThread is the manifest written next to the archived attachments
+type archivedThread struct {
+	ThreadID    string            `json:"thread_id"`
+	ThreadName  string            `json:"thread_name"`
+	ForumID     string            `json:"forum_id"`
+	GuildID     string            `json:"guild_id"`
+	OwnerID     string            `json:"owner_id"`
+	DeletedBy   string            `json:"deleted_by"`
+	Reason      string            `json:"reason,omitempty"`
+	ArchivedAt  time.Time         `json:"archived_at"`
+	RetainUntil *time.Time        `json:"retain_until,omitempty"`
+	Messages    []archivedMessage `json:"messages"`
+}
+
+type archivedMessage struct {
+	ID          string               `json:"id"`
+	AuthorID    string               `json:"author_id"`
+	Author      string               `json:"author"`
+	Content     string               `json:"content"`
+	Timestamp   time.Time            `json:"timestamp"`
+	Attachments []archivedAttachment `json:"attachments,omitempty"`
+}
+
+type archivedAttachment struct {
+	Filename string `json:"filename"`
+	URL      string `json:"url"`
+	Size     int    `json:"size"`
+	// StoredAs is the key of the archived copy; empty when the file was too large or could not be downloaded
+	StoredAs string `json:"stored_as,omitempty"`
+}
+
+func init() {
+	registerThreadCommand("archive-delete", (*handler).handleArchiveDelete)
+}
+
+// fetchAllMessages returns every message of a channel, oldest first
+func fetchAllMessages(s *discordgo.Session, channelID string) ([]*discordgo.Message, error) {
+	var all []*discordgo.Message
+	before := ""
+	for {
+		batch, err := s.ChannelMessages(channelID, 100, before, "", "")
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, batch...)
+		if len(batch) < 100 {
+			break
+		}
+		before = batch[len(batch)-1].ID
+	}
+	// the API returns newest first
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	return all, nil
+}
+
+// handleArchiveDelete implements `.archive-delete [reason]`: archive the thread's content and
+// attachments to the configured storage, then delete the thread
+func (h *handler) handleArchiveDelete(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, args string) {
+	if h.archive == nil {
+		replyMessage(s, m.Message, "Archive storage is not configured; refusing to delete without an archive.")
+		return
+	}
+	replyMessage(s, m.Message, "Archiving this thread before deletion…")
+
+	msgs, err := fetchAllMessages(s, ch.ID)
+	if err != nil {
+		log.Printf("archive: failed to read messages of %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, "❌ Failed to read the thread; nothing was deleted.")
+		return
+	}
+	now := time.Now().UTC()
+	manifest := archivedThread{
+		ThreadID: ch.ID, ThreadName: ch.Name, ForumID: ch.ParentID, GuildID: ch.GuildID, OwnerID: ch.OwnerID,
+		DeletedBy: m.Author.ID, Reason: args, ArchivedAt: now,
+	}
+	meta := map[string]string{"thread-id": ch.ID, "deleted-by": m.Author.ID}
+	if days := h.cfg().Archive.RetentionDays; days > 0 {
+		until := now.AddDate(0, 0, days)
+		manifest.RetainUntil = &until
+		meta["retain-until"] = until.Format(time.RFC3339)
+	}
+	prefix := fmt.Sprintf("%s/%s-%s", ch.GuildID, ch.ID, now.Format("20060102T150405Z"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var failed []string
+	for _, msg := range msgs {
+		// skip our own "Archiving…" notice and the command that triggered it
+		if msg.ID == m.ID || (msg.Author != nil && s.State.User != nil && msg.Author.ID == s.State.User.ID && strings.HasPrefix(msg.Content, "Archiving this thread")) {
+			continue
+		}
+		am := archivedMessage{ID: msg.ID, Content: msg.Content, Timestamp: msg.Timestamp}
+		if msg.Author != nil {
+			am.AuthorID, am.Author = msg.Author.ID, msg.Author.String()
+		}
+		for _, a := range msg.Attachments {
+			aa := archivedAttachment{Filename: a.Filename, URL: a.URL, Size: a.Size}
+			if a.Size <= maxArchivedAttachmentBytes {
+				key := fmt.Sprintf("%s/attachments/%s-%s", prefix, a.ID, a.Filename)
+				if err := h.archiveAttachment(ctx, a, key, meta); err != nil {
+					log.Printf("archive: failed to store attachment %s: %v", a.ID, err)
+					failed = append(failed, a.Filename)
+				} else {
+					aa.StoredAs = key
+				}
+			}
+			am.Attachments = append(am.Attachments, aa)
+		}
+		manifest.Messages = append(manifest.Messages, am)
+	}
+	// a partial archive is no archive: the thread stays until every attachment is stored
+	if len(failed) > 0 {
+		replyMessage(s, m.Message, fmt.Sprintf("❌ Failed to store %d attachment(s) (%s); nothing was deleted.", len(failed), strings.Join(failed, ", ")))
+		return
+	}
+
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		log.Printf("archive: failed to encode manifest for %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, "❌ Failed to encode the archive; nothing was deleted.")
+		return
+	}
+	if err := h.archive.Put(ctx, prefix+"/thread.json", body, "application/json", meta); err != nil {
+		log.Printf("archive: failed to store manifest for %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, "❌ Failed to store the archive; nothing was deleted.")
+		return
+	}
+
+	if h.cfg().forGuild(ch.GuildID).TranscriptChannelID != "" {
+		if _, err := h.uploadTranscript(s, ch, msgs, m.Author.ID); err != nil {
+			log.Printf("archive: failed to upload transcript of %s: %v", ch.ID, err)
+			replyMessage(s, m.Message, fmt.Sprintf("Archived as `%s`, but uploading the transcript failed; the thread was not deleted.", prefix))
+			return
+		}
+	}
+
+	if _, err := s.ChannelDelete(ch.ID); err != nil {
+		log.Printf("archive: failed to delete thread %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, fmt.Sprintf("Archived as `%s`, but deleting the thread failed. The bot needs Manage Threads.", prefix))
+		return
+	}
+	log.Printf("archive: thread %s (%q) archived as %s and deleted by %s", ch.ID, ch.Name, prefix, m.Author.ID)
+	if id := h.cfg().Archive.LogChannelID; id != "" {
+		note := fmt.Sprintf("🗄️ <@%s> archived and deleted **%s** (`%s`, %d messages) → `%s`", m.Author.ID, ch.Name, ch.ID, len(manifest.Messages), prefix)
+		if args != "" {
+			note += "\nReason: " + args
+		}
+		h.sendFeatureMessage(s, featureArchive, id, note)
+	}
+}
+
+func (h *handler) archiveAttachment(ctx context.Context, a *discordgo.MessageAttachment, key string, meta map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxArchivedAttachmentBytes+1))
+	if err != nil {
+		return err
+	}
+	return h.archive.Put(ctx, key, body, a.ContentType, meta)
+}
+
+// pruneLocalArchives deletes local archives whose retention period has expired. S3 archives carry
+// the same retain-until metadata and are expected to be expired with bucket lifecycle rules.
+func (h *handler) pruneLocalArchives(ctx context.Context, job jobRecord) error {
+	manifests, err := filepath.Glob(filepath.Join(h.cfg().Archive.Path, "*", "*", "thread.json"))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, path := range manifests {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var manifest archivedThread
+		if err := json.Unmarshal(b, &manifest); err != nil || manifest.RetainUntil == nil || manifest.RetainUntil.After(now) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+			log.Printf("archive: failed to prune %s: %v", filepath.Dir(path), err)
+			continue
+		}
+		log.Printf("archive: pruned expired archive %s", filepath.Dir(path))
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveSink stores archived files under slash-separated keys
+type archiveSink interface {
+	Put(ctx context.Context, key string, body []byte, contentType string, meta map[string]string) error
+}
+
+// newArchiveSink builds the sink selected by the archive config, or nil when archiving is disabled
+func newArchiveSink(ac ArchiveConfig) (archiveSink, error) {
+	switch ac.Backend {
+	case "":
+		return nil, nil
+	case "local":
+		if ac.Path == "" {
+			return nil, errors.New("archive.path is required for the local backend")
+		}
+		return &localArchiveSink{root: ac.Path}, nil
+	case "s3":
+		if ac.S3Bucket == "" || ac.S3AccessKey == "" || ac.S3SecretKey == "" {
+			return nil, errors.New("archive.s3_bucket, s3_access_key and s3_secret_key are required for the s3 backend")
+		}
+		region := ac.S3Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := strings.TrimRight(ac.S3Endpoint, "/")
+		if endpoint == "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+		return &s3ArchiveSink{endpoint: endpoint, bucket: ac.S3Bucket, region: region, accessKey: ac.S3AccessKey, secretKey: ac.S3SecretKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown archive backend %q (use \"local\" or \"s3\")", ac.Backend)
+	}
+}
+
+// localArchiveSink writes files below a directory
+type localArchiveSink struct {
+	root string
+}
+
+func (l *localArchiveSink) Put(ctx context.Context, key string, body []byte, contentType string, meta map[string]string) error {
+	path := filepath.Join(l.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, body, 0o600)
+}
+
+// s3ArchiveSink uploads objects to S3 or an S3-compatible service (path-style URLs, SigV4)
+type s3ArchiveSink struct {
+	endpoint, bucket, region string
+	accessKey, secretKey     string
+}
+
+func (a *s3ArchiveSink) Put(ctx context.Context, key string, body []byte, contentType string, meta map[string]string) error {
+	uri := "/" + a.bucket + "/" + awsURIEncodePath(key)
+	req, err := http.NewRequestWithContext(ctx, "PUT", a.endpoint+uri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(body)
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType != "" {
+		headers["content-type"] = contentType
+	}
+	for k, v := range meta {
+		headers["x-amz-meta-"+strings.ToLower(k)] = v
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + strings.TrimSpace(headers[k]) + "\n")
+		if k != "host" {
+			req.Header.Set(k, headers[k])
+		}
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{"PUT", uri, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := now.Format("20060102") + "/" + a.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key4 := hmacSHA256([]byte("AWS4"+a.secretKey), now.Format("20060102"))
+	key4 = hmacSHA256(key4, a.region)
+	key4 = hmacSHA256(key4, "s3")
+	key4 = hmacSHA256(key4, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key4, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", a.accessKey, scope, signedHeaders, signature))
+
+	resp, err := (&http.Client{Timeout: 60 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("s3 PUT %s returned status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsURIEncodePath percent-encodes every byte except unreserved characters and the path separator
+func awsURIEncodePath(p string) string {
+	var sb strings.Builder
+	for _, b := range []byte(p) {
+		switch {
+		case b >= 'A' && b <= 'Z', b >= 'a' && b <= 'z', b >= '0' && b <= '9', b == '-', b == '_', b == '.', b == '~', b == '/':
+			sb.WriteByte(b)
+		default:
+			sb.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// autoCloseJobKind is the scheduler kind of the auto_close_after timers, one one-shot job per thread
+const autoCloseJobKind = "thread-autoclose"
+
+// defaultAutoCloseStatuses start the auto_close_after timer in forums without auto_close_statuses
+var defaultAutoCloseStatuses = []string{"solved", "duplicate", "false", "wrong"}
+
+// autoClosePayload is the payload of an auto-close job
+type autoClosePayload struct {
+	ThreadID string `json:"thread_id"`
+	// Status is the status that started the timer; the thread stays open when it changed since
+	Status string `json:"status"`
+}
+
+func autoCloseJobID(threadID string) string {
+	return "autoclose:" + threadID
+}
+
+// compileAutoClose checks the auto_close_after and auto_close_statuses settings of a forum
+func (fc *ForumConfig) compileAutoClose(field string) error {
+	if fc.AutoCloseAfter == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(fc.AutoCloseAfter)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("%s.auto_close_after: invalid duration %q (e.g. \"48h\")", field, fc.AutoCloseAfter)
+	}
+	fc.autoCloseAfter = d
+	for _, cmd := range fc.AutoCloseStatuses {
+		if _, ok := commandConfig[cmd]; !ok {
+			return fmt.Errorf("%s.auto_close_statuses: unknown status %q (use one of the status commands, e.g. \"solved\")", field, cmd)
+		}
+	}
+	return nil
+}
+
+// closesWith reports whether status cmd starts the forum's auto_close_after timer
+func (fc ForumConfig) closesWith(cmd string) bool {
+	statuses := fc.AutoCloseStatuses
+	if len(statuses) == 0 {
+		statuses = defaultAutoCloseStatuses
+	}
+	for _, s := range statuses {
+		if s == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleAutoClose starts the auto-close timer of a thread that got status cmd, or stops it when
+// the new status does not close threads. The timer is a scheduler job, so it survives restarts.
+func (h *handler) scheduleAutoClose(ch *discordgo.Channel, cmd string) {
+	fc := h.cfg().Forums[ch.ParentID]
+	id := autoCloseJobID(ch.ID)
+	if fc.autoCloseAfter <= 0 || !fc.closesWith(cmd) {
+		h.sched.Cancel(id)
+		return
+	}
+	at := time.Now().Add(fc.autoCloseAfter)
+	if err := h.sched.Once(id, autoCloseJobKind, at, autoClosePayload{ThreadID: ch.ID, Status: cmd}); err != nil {
+		log.Printf("autoclose: failed to schedule %s: %v", ch.ID, err)
+	}
+}
+
+// runAutoClose archives a thread whose auto_close_after timer ran out. Threads that got another
+// status, were closed already or were deleted are left alone.
+func (h *handler) runAutoClose(ctx context.Context, job jobRecord) error {
+	var p autoClosePayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return err
+	}
+	th, err := h.dg.Channel(p.ThreadID)
+	if err != nil {
+		if restErr, ok := err.(*discordgo.RESTError); ok && restErr.Response != nil && restErr.Response.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	if th.ThreadMetadata != nil && th.ThreadMetadata.Archived {
+		return nil
+	}
+	if status := h.threadStatus(h.dg, th); status != p.Status {
+		log.Printf("autoclose: %s is now %q, keeping it open", th.ID, status)
+		return nil
+	}
+	archived := true
+	if _, err := h.dg.ChannelEdit(th.ID, &discordgo.ChannelEdit{Archived: &archived}); err != nil {
+		return err
+	}
+	log.Printf("autoclose: archived %s (%s) after auto_close_after", th.ID, th.Name)
+	return nil
+}
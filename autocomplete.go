@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// autocompleteDelay is how long a keystroke waits for the next one before AniList is queried
+	autocompleteDelay = 300 * time.Millisecond
+	// autocompleteTTL is how long suggestions for a prefix are reused
+	autocompleteTTL = 10 * time.Minute
+	// autocompleteCacheSize bounds the cached prefixes
+	autocompleteCacheSize = 500
+)
+
+type autocompleteEntry struct {
+	choices []*discordgo.ApplicationCommandOptionChoice
+	fetched time.Time
+}
+
+// titleSuggestions caches AniList suggestions by media type and query, and tracks the latest
+// keystroke of each user so superseded requests can be dropped
+var titleSuggestions = struct {
+	sync.Mutex
+	cache  map[string]autocompleteEntry
+	latest map[string]string
+}{cache: map[string]autocompleteEntry{}, latest: map[string]string{}}
+
+func init() {
+	registerAutocomplete("anime", (*handler).handleTitleAutocomplete)
+	registerAutocomplete("manga", (*handler).handleTitleAutocomplete)
+}
+
+// handleTitleAutocomplete suggests up to 10 AniList titles for the title option of /anime and /manga
+func (h *handler) handleTitleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	var query string
+	for _, o := range data.Options {
+		if o.Name == "title" && o.Focused {
+			query = strings.TrimSpace(o.StringValue())
+		}
+	}
+	mediaType := strings.ToUpper(data.Name)
+	key := mediaType + ":" + strings.ToLower(query)
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	if len([]rune(query)) >= 2 {
+		titleSuggestions.Lock()
+		entry, cached := titleSuggestions.cache[key]
+		cached = cached && time.Since(entry.fetched) < autocompleteTTL
+		user := interactionUser(i)
+		if !cached && user != nil {
+			titleSuggestions.latest[user.ID] = i.ID
+		}
+		titleSuggestions.Unlock()
+
+		if cached {
+			choices = entry.choices
+		} else {
+			// Discord sends one request per keystroke; only the last one of a burst asks AniList
+			time.Sleep(autocompleteDelay)
+			if user != nil {
+				titleSuggestions.Lock()
+				superseded := titleSuggestions.latest[user.ID] != i.ID
+				titleSuggestions.Unlock()
+				if superseded {
+					return
+				}
+			}
+			ch, _ := s.Channel(i.ChannelID)
+			var err error
+			choices, err = fetchTitleSuggestions(query, mediaType, ch != nil && ch.NSFW)
+			if err != nil {
+				log.Printf("autocomplete: AniList error for %q: %v", query, err)
+			} else {
+				kept := choices[:0]
+				for _, c := range choices {
+					if title, _ := c.Value.(string); !h.blockedTitle(title) {
+						kept = append(kept, c)
+					}
+				}
+				choices = kept
+				storeSuggestions(key, choices)
+			}
+		}
+	}
+	if choices == nil {
+		choices = []*discordgo.ApplicationCommandOptionChoice{}
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+	if err != nil {
+		log.Printf("autocomplete: failed to respond: %v", err)
+	}
+}
+
+// storeSuggestions caches choices, dropping expired entries (or everything) when the cache is full
+func storeSuggestions(key string, choices []*discordgo.ApplicationCommandOptionChoice) {
+	titleSuggestions.Lock()
+	defer titleSuggestions.Unlock()
+	if len(titleSuggestions.cache) >= autocompleteCacheSize {
+		for k, e := range titleSuggestions.cache {
+			if time.Since(e.fetched) >= autocompleteTTL {
+				delete(titleSuggestions.cache, k)
+			}
+		}
+		if len(titleSuggestions.cache) >= autocompleteCacheSize {
+			titleSuggestions.cache = map[string]autocompleteEntry{}
+		}
+	}
+	titleSuggestions.cache[key] = autocompleteEntry{choices: choices, fetched: time.Now()}
+}
+
+// fetchTitleSuggestions returns up to 10 AniList titles matching query as autocomplete choices
+func fetchTitleSuggestions(query, mediaType string, allowAdult bool) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	vars := map[string]interface{}{"search": query, "type": mediaType}
+	if !allowAdult {
+		vars["isAdult"] = false
+	}
+	var data struct {
+		Page struct {
+			Media []struct {
+				Title struct {
+					Romaji  string `json:"romaji"`
+					English string `json:"english"`
+				} `json:"title"`
+				Format    string `json:"format"`
+				StartDate struct {
+					Year int `json:"year"`
+				} `json:"startDate"`
+			} `json:"media"`
+		} `json:"Page"`
+	}
+	err := aniListGraphQL(`query ($search: String, $type: MediaType, $isAdult: Boolean) {
+		Page(page: 1, perPage: 10) {
+			media(search: $search, type: $type, isAdult: $isAdult, sort: SEARCH_MATCH) {
+				title { romaji english }
+				format
+				startDate { year }
+			}
+		}
+	}`, vars, &data)
+	if err != nil {
+		return nil, err
+	}
+	choices := []*discordgo.ApplicationCommandOptionChoice{}
+	for _, m := range data.Page.Media {
+		title := m.Title.English
+		if title == "" {
+			title = m.Title.Romaji
+		}
+		if title == "" {
+			continue
+		}
+		label := title
+		if m.Format != "" && m.StartDate.Year > 0 {
+			label = fmt.Sprintf("%s (%s, %d)", title, humanizeEnum(m.Format), m.StartDate.Year)
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: truncateRunes(label, 100), Value: truncateRunes(title, 100)})
+	}
+	return choices, nil
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
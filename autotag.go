@@ -0,0 +1,35 @@
+package main
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// matchAutoTags returns the tags of every rule that matches the text, in rule order
+func matchAutoTags(rules []AutoTagRule, text string) []string {
+	var tags []string
+	for _, r := range rules {
+		if r.re != nil && r.re.MatchString(text) {
+			tags = append(tags, r.Tag)
+		}
+	}
+	return tags
+}
+
+// applyAutoTags adds the topic tags whose keywords or patterns match a new post. Tags the author
+// already picked are kept; editThreadTags skips duplicates and stops at Discord's tag limit.
+func (h *handler) applyAutoTags(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message, fc ForumConfig) {
+	tags := matchAutoTags(fc.AutoTags, th.Name+"\n"+starter.Content)
+	if len(tags) == 0 {
+		return
+	}
+	added, err := editThreadTags(s, th, tags, nil)
+	if err != nil {
+		log.Printf("auto-tag: failed to tag %s with %v: %v", th.ID, tags, err)
+		return
+	}
+	if len(added) > 0 {
+		log.Printf("auto-tag: tagged %s with %v", th.ID, added)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"regexp"
+)
+
+// BlockedTitle is an entry of search_blocklist: an AniList media ID, a case-insensitive regex
+// matched against every known title of a result, or both
+type BlockedTitle struct {
+	AniListID int    `yaml:"anilist_id"`
+	Pattern   string `yaml:"pattern"`
+	re        *regexp.Regexp
+}
+
+// blocked reports whether a search result is on the blocklist. AniList IDs only apply to results
+// served by AniList, as the other providers have their own IDs.
+func (h *handler) blocked(m *aniListMedia) bool {
+	for _, b := range h.cfg().SearchBlocklist {
+		if b.AniListID != 0 && m.Provider == trackerAniList && m.ID == b.AniListID {
+			return true
+		}
+	}
+	return h.blockedTitle(append([]string{m.Title}, m.AltTitles...)...)
+}
+
+// blockedTitle reports whether any of the titles matches a pattern of the blocklist
+func (h *handler) blockedTitle(titles ...string) bool {
+	for _, b := range h.cfg().SearchBlocklist {
+		if b.re == nil {
+			continue
+		}
+		for _, t := range titles {
+			if b.re.MatchString(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withoutBlocked drops the blocked results, keeping the order of the others
+func (h *handler) withoutBlocked(results []*aniListMedia) []*aniListMedia {
+	if len(h.cfg().SearchBlocklist) == 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, m := range results {
+		if h.blocked(m) {
+			log.Printf("search: skipping blocked title %q (%s %d)", m.Title, m.Provider, m.ID)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
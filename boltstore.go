@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltStore keeps each bucket of the state in a bucket of a bbolt file. Like SQLite it only
+// writes what changes, but needs no cgo and has no schema to migrate.
+type boltStore struct {
+	db *bolt.DB
+}
+
+// openBoltStore opens (or creates) the bbolt file at path. A new file takes over the state of a
+// state.json file next to it. The file is locked while the bot runs, so a second instance waits
+// a few seconds and then fails to open it.
+//
+// A dry run opens an existing file read-only, or starts from an empty in-memory store, and keeps
+// changes in memory.
+func openBoltStore(path string) (Store, error) {
+	opts := &bolt.Options{Timeout: 5 * time.Second}
+	if dryRun {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return newOverlayStore(&fileStore{buckets: map[string]map[string]json.RawMessage{}}), nil
+		}
+		opts.ReadOnly = true
+	} else if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	_, statErr := os.Stat(path)
+	db, err := bolt.Open(path, 0o600, opts)
+	if err != nil {
+		return nil, err
+	}
+	st := &boltStore{db: db}
+	if dryRun {
+		return newOverlayStore(st), nil
+	}
+	if legacy := filepath.Join(filepath.Dir(path), "state.json"); os.IsNotExist(statErr) && legacy != path {
+		if err := st.importFileStore(legacy); err != nil {
+			db.Close()
+			os.Remove(path)
+			return nil, fmt.Errorf("importing %s: %v", legacy, err)
+		}
+	}
+	return st, nil
+}
+
+// importFileStore copies the buckets of the JSON state file at path into the new bbolt file
+func (st *boltStore) importFileStore(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	fs, err := openFileStore(path)
+	if err != nil {
+		return err
+	}
+	n := 0
+	err = st.db.Update(func(tx *bolt.Tx) error {
+		for bucket, values := range fs.buckets {
+			b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+			if err != nil {
+				return err
+			}
+			for key, raw := range values {
+				if err := b.Put([]byte(key), raw); err != nil {
+					return err
+				}
+				n++
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		log.Printf("store: imported %d entries from %s", n, path)
+	}
+	return err
+}
+
+func (st *boltStore) Get(bucket, key string, v interface{}) (bool, error) {
+	found := false
+	err := st.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		raw := b.Get([]byte(key))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		// raw is only valid inside the transaction, so it is decoded here
+		return json.Unmarshal(raw, v)
+	})
+	return found, err
+}
+
+func (st *boltStore) Put(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return st.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), raw)
+	})
+}
+
+func (st *boltStore) Delete(bucket, key string) error {
+	return st.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+func (st *boltStore) List(bucket string) (map[string]json.RawMessage, error) {
+	out := map[string]json.RawMessage{}
+	err := st.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, raw []byte) error {
+			out[string(k)] = append(json.RawMessage(nil), raw...)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func (st *boltStore) Close() error {
+	return st.db.Close()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// releaseVersionRe matches release tags that are plain versions ("v7.7.1", "8.0")
+var releaseVersionRe = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)$`)
+
+// handleChangelog implements `.changelog <from> <to>`: condense the release notes of every stable
+// release after from up to and including to
+func (h *handler) handleChangelog(s *discordgo.Session, m *discordgo.MessageCreate, args string) {
+	repo := h.cfg().Releases.Repo
+	parts := strings.Fields(args)
+	if repo == "" || len(parts) != 2 {
+		replyMessage(s, m.Message, fmt.Sprintf("usage: %[1]s <from version> <to version>, e.g. %[1]s 7.6 7.7.1", h.cfg().forGuild(m.GuildID).command("changelog")))
+		return
+	}
+	from, to := strings.TrimPrefix(parts[0], "v"), strings.TrimPrefix(parts[1], "v")
+	if compareVersions(from, to) > 0 {
+		from, to = to, from
+	}
+	releases, err := fetchReleases(h.cfg().GitHubToken, repo, 100)
+	if err != nil {
+		log.Printf("changelog: failed to fetch releases of %s: %v", repo, err)
+		replyMessage(s, m.Message, "❌ Failed to fetch the release notes, try again later.")
+		return
+	}
+
+	var blocks []string
+	for _, r := range releases {
+		mv := releaseVersionRe.FindStringSubmatch(strings.ToLower(r.TagName))
+		if mv == nil || r.Draft || r.Prerelease {
+			continue
+		}
+		if compareVersions(mv[1], from) <= 0 || compareVersions(mv[1], to) > 0 {
+			continue
+		}
+		block := fmt.Sprintf("**[%s](%s)** · <t:%d:d>\n%s", r.TagName, r.HTMLURL, r.PublishedAt.Unix(), releaseHighlights(r.Body, 6))
+		block = truncateRunes(block, 1800)
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		replyMessage(s, m.Message, fmt.Sprintf("No releases found after %s up to %s.", from, to))
+		return
+	}
+	title := fmt.Sprintf("Changes from %s to %s (%d releases)", from, to, len(blocks))
+	if err := sendPaged(s, m.ChannelID, embedPages(title, blocks, 2)); err != nil {
+		log.Printf("changelog: failed to send: %v", err)
+	}
+}
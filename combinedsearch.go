@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// mediaTypeAny is the media type of {title} lookups with search_combined: anime and manga are
+// both searched
+const mediaTypeAny = "ANY"
+
+// companionMinMatch is how closely the other media type's best match must resemble the query to
+// be linked as the same title in the other format
+const companionMinMatch = 0.5
+
+// searchBothTypes searches name as anime and as manga at once. It returns the candidates of the
+// type matching the query best, that type, and the best match of the other type when it looks
+// like the same title (e.g. the manga an anime adapts).
+func (h *handler) searchBothTypes(ch *discordgo.Channel, name string, allowAdult bool) (results []*aniListMedia, mediaType string, companion *aniListMedia, err error) {
+	var wg sync.WaitGroup
+	var anime, manga []*aniListMedia
+	var animeErr, mangaErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		anime, animeErr = h.searchMediaCandidates(ch, name, "ANIME", allowAdult, searchCandidates)
+	}()
+	go func() {
+		defer wg.Done()
+		manga, mangaErr = h.searchMediaCandidates(ch, name, "MANGA", allowAdult, searchCandidates)
+	}()
+	wg.Wait()
+	switch {
+	case len(anime) == 0 && len(manga) == 0:
+		if animeErr == nil {
+			animeErr = mangaErr
+		}
+		return nil, "ANIME", nil, animeErr
+	case len(manga) == 0:
+		return anime, "ANIME", nil, nil
+	case len(anime) == 0:
+		return manga, "MANGA", nil, nil
+	}
+	a, m := titleMatch(name, anime[0]), titleMatch(name, manga[0])
+	results, mediaType, other, otherMatch := anime, "ANIME", manga[0], m
+	if m > a || m == a && manga[0].Popularity > anime[0].Popularity {
+		results, mediaType, other, otherMatch = manga, "MANGA", anime[0], a
+	}
+	if otherMatch >= companionMinMatch {
+		companion = other
+	}
+	return results, mediaType, companion, nil
+}
+
+// titleMatch returns how closely the best-matching title of media resembles the query (0..1)
+func titleMatch(query string, media *aniListMedia) float64 {
+	q := trigrams(query)
+	best := 0.0
+	for _, t := range append([]string{media.Title}, media.AltTitles...) {
+		if score := jaccard(q, trigrams(t)); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// addCompanionField links the other format of a title (its manga, or its anime) in the embed
+func addCompanionField(tr localizer, emb *discordgo.MessageEmbed, companion *aniListMedia, companionType string) {
+	key := "search.also_anime"
+	if companionType == "MANGA" {
+		key = "search.also_manga"
+	}
+	emb.Fields = append(emb.Fields, &discordgo.MessageEmbedField{
+		Name:  tr.T(key),
+		Value: truncateRunes(fmt.Sprintf("[%s](%s)", companion.Title, companion.SiteURL), 1024),
+	})
+}
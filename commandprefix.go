@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// defaultCommandPrefix starts the text commands (".solved") unless command_prefixes says otherwise
+const defaultCommandPrefix = "."
+
+// checkCommandPrefixes validates a command_prefixes list; field names it in errors
+func checkCommandPrefixes(field string, prefixes []string) error {
+	for i, p := range prefixes {
+		if p == "" || strings.IndexFunc(p, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("%s[%d]: %q cannot be empty or contain spaces", field, i, p)
+		}
+	}
+	return nil
+}
+
+// commandPrefix returns the command prefix content starts with, or "" when it is not a command.
+// The longest matching prefix wins, so "!!" is not taken for "!".
+func (cfg *Config) commandPrefix(content string) string {
+	prefixes := cfg.CommandPrefixes
+	if len(prefixes) == 0 {
+		prefixes = []string{defaultCommandPrefix}
+	}
+	match := ""
+	for _, p := range prefixes {
+		if len(p) > len(match) && len(content) > len(p) && strings.EqualFold(content[:len(p)], p) {
+			match = p
+		}
+	}
+	return match
+}
+
+// command returns how members type the text command name, with the first configured prefix
+func (cfg *Config) command(name string) string {
+	if len(cfg.CommandPrefixes) == 0 {
+		return defaultCommandPrefix + name
+	}
+	return cfg.CommandPrefixes[0] + name
+}
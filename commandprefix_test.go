@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestCommandPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		content  string
+		want     string
+	}{
+		{"default prefix", nil, ".solved", "."},
+		{"no prefix", nil, "solved", ""},
+		{"prefix alone", nil, ".", ""},
+		{"empty message", nil, "", ""},
+		{"custom prefix", []string{"!"}, "!solved", "!"},
+		{"default not used with custom prefixes", []string{"!"}, ".solved", ""},
+		{"longest prefix wins", []string{"!", "!!"}, "!!solved", "!!"},
+		{"shorter prefix still matches", []string{"!", "!!"}, "!solved", "!"},
+		{"case-insensitive", []string{"kb:"}, "KB:solved", "kb:"},
+		{"multi-byte prefix", []string{"→"}, "→solved", "→"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{CommandPrefixes: tt.prefixes}
+			if got := cfg.commandPrefix(tt.content); got != tt.want {
+				t.Errorf("commandPrefix(%q) with %q = %q, want %q", tt.content, tt.prefixes, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,22 +5,43 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-// commandConfig maps a short command to the title prefix and expected forum tag name
+// commandConfig maps a short command to the title prefix, its status_style: emoji variant and the
+// expected forum tag name
 var commandConfig = map[string]struct {
 	Prefix  string
+	Emoji   string
 	TagName string
 }{
-	"solved":    {Prefix: "[Solved]", TagName: ".Solved"},
-	"aware":     {Prefix: "[Devs aware]", TagName: ".Devs aware"},
-	"duplicate": {Prefix: "[Duplicate]", TagName: ".Duplicate"},
-	"false":     {Prefix: "[False report]", TagName: ".False report"},
-	"known":     {Prefix: "[Known issue]", TagName: ".Known issue"},
-	"wrong":     {Prefix: "[Wrong channel]", TagName: ".Wrong channel"},
+	"solved":    {Prefix: "[Solved]", Emoji: "✅", TagName: ".Solved"},
+	"aware":     {Prefix: "[Devs aware]", Emoji: "🔁", TagName: ".Devs aware"},
+	"duplicate": {Prefix: "[Duplicate]", Emoji: "📑", TagName: ".Duplicate"},
+	"false":     {Prefix: "[False report]", Emoji: "❌", TagName: ".False report"},
+	"known":     {Prefix: "[Known issue]", Emoji: "⚠️", TagName: ".Known issue"},
+	"wrong":     {Prefix: "[Wrong channel]", Emoji: "↪️", TagName: ".Wrong channel"},
+}
+
+// threadCommandFunc handles a moderator command typed inside a watched forum thread. args is the
+// message content after the command token.
+type threadCommandFunc func(h *handler, s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, args string)
+
+// threadCommands holds moderator-only thread commands other than the status commands in
+// commandConfig. Features add themselves from an init function via registerThreadCommand.
+var threadCommands = map[string]threadCommandFunc{}
+
+func registerThreadCommand(name string, fn threadCommandFunc) {
+	threadCommands[name] = fn
+}
+
+// featureCommands are the text commands handled by their own features before the thread checks
+var featureCommands = map[string]bool{
+	"link-github": true, "unlink-github": true, "find": true, "issue": true, "source": true, "version": true,
+	"changelog": true, "stores": true, "translations": true, "watch": true, "unwatch": true,
 }
 
 // onMessageCreate handles MessageCreate events
@@ -35,12 +56,33 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		return
 	}
 
-	// If the message is not a command (doesn't start with '.'), consider running the search feature
-	if !strings.HasPrefix(content, ".") {
+	// direct messages only get lookups, when search_in_dms allows them
+	if m.GuildID == "" {
+		if !h.cfg().SearchInDMs || h.cfg().commandPrefix(content) != "" {
+			return
+		}
+		ch, err := s.Channel(m.ChannelID)
+		if err != nil {
+			log.Printf("failed to fetch DM channel %s: %v", m.ChannelID, err)
+			return
+		}
+		if err := h.trySearchInMessage(s, m, ch); err != nil {
+			log.Printf("search handler error: %v", err)
+		}
+		return
+	}
+
+	// If the message is not a command (doesn't start with a command prefix), consider running the search feature
+	prefix := h.cfg().forGuild(m.GuildID).commandPrefix(content)
+	if prefix == "" {
 		// run the search flow if enabled in config and allowed in this channel
 		// Fetch channel info first so we can evaluate NSFW and config channel restrictions
 		ch, err := s.Channel(m.ChannelID)
 		if err == nil {
+			// authors of posts flagged as missing report fields may be supplying them now
+			go h.checkNeedsInfoReply(s, m, ch)
+			go h.expandIssueReferences(s, m, ch)
+			go h.recordIssueLink(s, m, ch)
 			// do not block other flows if search fails
 			go func() {
 				if err := h.trySearchInMessage(s, m, ch); err != nil {
@@ -54,12 +96,62 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 
 	// parse command token (first word)
 	token := strings.Fields(content)[0]
-	cmd := strings.TrimPrefix(strings.ToLower(token), ".")
+	cmd := strings.ToLower(token[len(prefix):])
+
+	args := strings.TrimSpace(content[len(token):])
+	_, ok := commandConfig[cmd]
+	threadCmd, isThreadCmd := threadCommands[cmd]
+	if !ok && !isThreadCmd && !featureCommands[cmd] && cmd != "list-tags" {
+		return
+	}
+
+	// gateway replays after a reconnect can deliver the same command again
+	if !h.claimCommandMessage(m.ID) {
+		log.Printf("debug: command message %s was already handled, ignoring the replay", m.ID)
+		return
+	}
 
 	// Special admin-only helper: .list-tags (moved down after channel fetch)
 
-	cfg, ok := commandConfig[cmd]
-	if !ok {
+	// Commands that do not change a thread's status are handled by their own features
+	if (cmd == "link-github" || cmd == "unlink-github") && h.storeDegraded() {
+		replyMessage(s, m.Message, degradedNotice)
+		return
+	}
+	// the cases below are listed in featureCommands as well
+	switch cmd {
+	case "link-github":
+		h.handleLinkGitHub(s, m)
+		return
+	case "unlink-github":
+		h.handleUnlinkGitHub(s, m)
+		return
+	case "find":
+		h.handleFind(s, m, strings.TrimSpace(content[len(token):]))
+		return
+	case "issue":
+		h.handleIssueSearch(s, m, strings.TrimSpace(content[len(token):]))
+		return
+	case "source":
+		h.handleSource(s, m, strings.TrimSpace(content[len(token):]))
+		return
+	case "version":
+		h.handleVersion(s, m, strings.TrimSpace(content[len(token):]))
+		return
+	case "changelog":
+		h.handleChangelog(s, m, strings.TrimSpace(content[len(token):]))
+		return
+	case "stores":
+		h.handleStores(s, m)
+		return
+	case "translations":
+		h.handleTranslations(s, m, strings.TrimSpace(content[len(token):]))
+		return
+	case "watch":
+		h.handleWatchCommand(s, m, strings.TrimSpace(content[len(token):]))
+		return
+	case "unwatch":
+		h.handleUnwatchCommand(s, m, strings.TrimSpace(content[len(token):]))
 		return
 	}
 
@@ -70,31 +162,28 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		return
 	}
 
-	// must be a thread
-	if !isThreadChannel(ch) {
-		// ignore: not a thread
+	// must be a thread in one of the watched parents if configured
+	if !isThreadChannel(ch) || !h.inWatchedForum(ch) {
+		h.replyUnwatchedCommand(s, m, ch)
 		return
 	}
 
-	// must be in watched parents if configured
-	if len(h.watchedParents) > 0 {
-		if ch.ParentID == "" || !h.watchedParents[ch.ParentID] {
-			return
-		}
-	}
-
+	tr := h.localizer(ch.GuildID, ch.ID, ch.ParentID)
+	data := threadReplyData(s, ch, m.Author.ID)
 	// check if user has moderator-level permission in the guild
 	has, err := h.userCanManagePosts(s, m.Author.ID, ch)
 	if err != nil {
 		log.Printf("permission check failed: %v", err)
 		return
 	}
+	// Thread authors may mark their own post solved when op_can_solve is enabled
+	if !has && cmd == "solved" && h.cfg() != nil && h.cfg().OpCanSolve && ch.OwnerID == m.Author.ID {
+		has = true
+	}
 	// If the command is list-tags, reply with available tags and applied tags (admin-only)
 	if cmd == "list-tags" {
 		if !has {
-			if _, e := s.ChannelMessageSend(m.ChannelID, "you don't have permission to list tags"); e != nil {
-				log.Printf("failed to send permission message: %v", e)
-			}
+			replyMessage(s, m.Message, tr.R("command.no_permission_tags", data))
 			return
 		}
 
@@ -157,28 +246,58 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 			sb.WriteString(id)
 			sb.WriteString("\n")
 		}
-		if _, e := s.ChannelMessageSend(m.ChannelID, sb.String()); e != nil {
-			log.Printf("failed to send tag list: %v", e)
-		}
+		replyMessage(s, m.Message, sb.String())
 		return
 	}
 	if !has {
 		// optionally notify
-		if _, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("<@%s> you don't have permission to run that command.", m.Author.ID)); err != nil {
-			log.Printf("failed to send permission message: %v", err)
+		replyMessage(s, m.Message, tr.R("command.no_permission", data))
+		return
+	}
+
+	if isThreadCmd {
+		if statefulCommands[cmd] && h.storeDegraded() {
+			replyMessage(s, m.Message, degradedNotice)
+			return
 		}
+		defer showTyping(s, ch.ID)()
+		threadCmd(h, s, m, ch, args)
+		return
+	}
+
+	defer showTyping(s, ch.ID)()
+	newName, ok := h.applyStatus(s, ch, cmd)
+	if !ok {
 		return
 	}
+	h.refreshTriagePanel(s, ch.ID, cmd, m.Author.ID)
+
+	// success reaction or message
+	data.Thread, data.Tag = newName, h.cfg().statusTag(ch.ParentID, cmd)
+	replyMessage(s, m.Message, tr.R("command.updated_thread", data, newName))
+}
 
+// applyStatus gives the thread the title prefix of the status command cmd in the server's
+// status_style and swaps the thread's dot-tag for the status tag. Failures are reported in the
+// thread; it returns the new thread name and whether the edit succeeded.
+func (h *handler) applyStatus(s *discordgo.Session, ch *discordgo.Channel, cmd string) (string, bool) {
+	tagName := h.cfg().statusTag(ch.ParentID, cmd)
+	// tags of the other statuses are swapped out along with the dot-tags
+	statusTags := map[string]bool{}
+	for other := range commandConfig {
+		statusTags[strings.ToLower(h.cfg().statusTag(ch.ParentID, other))] = true
+	}
 	// Debug: log channel identifiers to help diagnose access problems
 	log.Printf("debug: message in channel=%s parent=%s guild=%s", ch.ID, ch.ParentID, ch.GuildID)
+	tr := h.localizer(ch.GuildID, ch.ID, ch.ParentID)
 
 	// Fetch parent (forum) channel using discordgo to read available tags
 	parent, err := s.Channel(ch.ParentID)
 	if err != nil {
 		log.Printf("failed to fetch parent channel: %v", err)
-		return
+		return "", false
 	}
+	data := replyData{Thread: ch.Name, Tag: tagName, Forum: parent.Name}
 
 	// Find the tag ID from available forum tags. Some discordgo versions expose tags
 	// at top-level as `available_tags`, whereas the API may return them under
@@ -214,7 +333,7 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 	}
 	if err := json.Unmarshal(parentJSON, &parentData); err != nil {
 		log.Printf("failed to parse parent channel tags: %v", err)
-		return
+		return "", false
 	}
 
 	// Prefer top-level available_tags, fallback to forum_metadata.available_tags
@@ -231,22 +350,22 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 	log.Printf("debug: found %d available tags in forum %s", len(available), ch.ParentID)
 	for _, t := range available {
 		log.Printf("debug: available tag: %q (id=%s)", t.Name, t.ID)
-		if strings.HasPrefix(t.Name, ".") {
+		if strings.HasPrefix(t.Name, ".") || statusTags[strings.ToLower(t.Name)] {
 			dotTagIDs[t.ID] = true
 		}
 		// Case-insensitive tag name matching
-		if strings.EqualFold(t.Name, cfg.TagName) {
+		if strings.EqualFold(t.Name, tagName) {
 			tagID = t.ID
 		}
 	}
 	if tagID == "" {
-		if _, e := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Tag %s not found in the forum. Please create it first.", cfg.TagName)); e != nil {
+		if _, e := s.ChannelMessageSend(ch.ID, tr.R("command.tag_missing", data, tagName)); e != nil {
 			log.Printf("failed to send tag missing message: %v", e)
 		}
-		log.Printf("debug: looking for tag %q but not found among available tags", cfg.TagName)
-		return
+		log.Printf("debug: looking for tag %q but not found among available tags", tagName)
+		return "", false
 	}
-	log.Printf("debug: matched tag %q to id=%s", cfg.TagName, tagID)
+	log.Printf("debug: matched tag %q to id=%s", tagName, tagID)
 
 	// fetch this thread channel via REST to read applied_tags reliably
 	var threadJSON []byte
@@ -256,7 +375,7 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		thread, err2 := s.Channel(ch.ID)
 		if err2 != nil {
 			log.Printf("failed to fetch thread channel: %v", err2)
-			return
+			return "", false
 		}
 		threadJSON, _ = json.Marshal(thread)
 	} else {
@@ -268,7 +387,7 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 	}
 	if err := json.Unmarshal(threadJSON, &chData); err != nil {
 		log.Printf("failed to parse thread applied tags: %v", err)
-		return
+		return "", false
 	}
 
 	// compute new applied tags: remove other dot-tags, keep non-dot tags
@@ -290,17 +409,17 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		newApplied = append(newApplied, tagID)
 	}
 
-	// edit thread title (prefix if missing)
-	newName := addPrefixIfMissing(ch.Name, cfg.Prefix)
+	// replace the title's status prefix; tag-only styles may leave the title as it is
+	newName := h.cfg().forGuild(ch.GuildID).statusTitle(ch.Name, cmd)
 
 	// Log before editing
 	log.Printf("debug: editing thread name: old=%q new=%q", ch.Name, newName)
 	log.Printf("debug: newApplied tag IDs: %v", newApplied)
 
 	// Use discordgo's ChannelEdit properly with the correct struct
-	edit := &discordgo.ChannelEdit{
-		Name:        newName,
-		AppliedTags: &newApplied,
+	edit := &discordgo.ChannelEdit{AppliedTags: &newApplied}
+	if newName != ch.Name {
+		edit.Name = newName
 	}
 
 	// Wrap ChannelEdit in a timeout to prevent indefinite blocking
@@ -333,10 +452,10 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 		log.Printf("debug: ChannelEdit returned")
 	case <-time.After(15 * time.Second):
 		log.Printf("ERROR: ChannelEdit timed out after 15 seconds")
-		if _, e := s.ChannelMessageSend(m.ChannelID, "command timed out (Discord API not responding)"); e != nil {
+		if _, e := s.ChannelMessageSend(ch.ID, tr.R("command.timeout", data)); e != nil {
 			log.Printf("failed to send timeout message: %v", e)
 		}
-		return
+		return "", false
 	}
 
 	if err != nil {
@@ -346,6 +465,7 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 			if restErr.Response != nil {
 				status = restErr.Response.StatusCode
 			}
+			data.Code = status
 			log.Printf("Discord API error: StatusCode=%d, Message=%q, ResponseBody=%s", status, restErr.Message, string(restErr.ResponseBody))
 
 			// Provide user-friendly messages based on error type
@@ -353,7 +473,7 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 			case 429:
 				// Build a message including rate limit headers so moderators can see why the bot was throttled
 				var sb strings.Builder
-				sb.WriteString("⏱️ Discord rate limit reached. The bot is being throttled. Please wait a moment and try again.\n")
+				sb.WriteString(tr.R("command.rate_limited", data) + "\n")
 				if restErr.Response != nil && restErr.Response.Header != nil {
 					h := restErr.Response.Header
 					sb.WriteString("Rate limit headers:\n")
@@ -366,42 +486,81 @@ func (h *handler) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCrea
 				} else {
 					sb.WriteString("(no rate-limit headers available)\n")
 				}
-				if _, e := s.ChannelMessageSend(m.ChannelID, sb.String()); e != nil {
+				if _, e := s.ChannelMessageSend(ch.ID, sb.String()); e != nil {
 					log.Printf("failed to send rate limit message: %v", e)
 				}
 			case 403:
-				if _, e := s.ChannelMessageSend(m.ChannelID, "❌ Permission denied. The bot lacks the required permissions (Manage Threads, Manage Messages)."); e != nil {
+				if _, e := s.ChannelMessageSend(ch.ID, tr.R("command.bot_permissions", data)); e != nil {
 					log.Printf("failed to send permission error message: %v", e)
 				}
 			case 404:
-				if _, e := s.ChannelMessageSend(m.ChannelID, "⚠️ Thread or forum not found. The post may have been deleted."); e != nil {
+				if _, e := s.ChannelMessageSend(ch.ID, tr.R("command.thread_not_found", data)); e != nil {
 					log.Printf("failed to send not found message: %v", e)
 				}
 			case 500, 502, 503, 504:
-				if _, e := s.ChannelMessageSend(m.ChannelID, "🔧 Discord API is experiencing issues. Please try again in a moment."); e != nil {
+				if _, e := s.ChannelMessageSend(ch.ID, tr.R("command.discord_unavailable", data)); e != nil {
 					log.Printf("failed to send server error message: %v", e)
 				}
 			default:
-				if _, e := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("❌ Failed to update thread (Error %d). Check bot permissions or try again.", status)); e != nil {
+				if _, e := s.ChannelMessageSend(ch.ID, tr.R("command.update_failed", data, status)); e != nil {
 					log.Printf("failed to send generic error message: %v", e)
 				}
 			}
-			return
+			return "", false
 		}
 		// Fallback for non-REST errors
-		if _, e := s.ChannelMessageSend(m.ChannelID, "❌ Failed to update thread (unknown error). Please check logs or try again."); e != nil {
+		if _, e := s.ChannelMessageSend(ch.ID, tr.R("command.update_failed_nocode", data)); e != nil {
 			log.Printf("failed to send fallback error message: %v", e)
 		}
-		return
+		return "", false
 	}
 	log.Printf("debug: ChannelEdit succeeded: name=%q applied_tags=%v", updated.Name, updated.AppliedTags)
+	h.scheduleAutoClose(ch, cmd)
+	return newName, true
+}
 
-	// success reaction or message
-	if _, err := s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("Updated thread: %s", newName)); err != nil {
-		log.Printf("failed to send confirmation message: %v", err)
+// sendMessage posts content to a channel, split over several messages when it is too long for
+// one, and logs failures
+func sendMessage(s *discordgo.Session, channelID, content string) {
+	for _, chunk := range splitContent(content, maxMessageLength) {
+		if _, err := s.ChannelMessageSend(channelID, chunk); err != nil {
+			log.Printf("failed to send message to %s: %v", channelID, err)
+			return
+		}
+	}
+}
+
+// replyMessage answers the message that triggered a command, see asReply. Content too long for
+// one message continues in further replies.
+func replyMessage(s *discordgo.Session, m *discordgo.Message, content string) {
+	for _, chunk := range splitContent(content, maxMessageLength) {
+		if _, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{Content: chunk}, m)); err != nil {
+			log.Printf("failed to reply to %s: %v", m.ID, err)
+			return
+		}
 	}
 }
 
+// asReply turns send into a reply to m that mentions nobody, not even m's author, so busy
+// channels show which message produced which answer without extra pings. The reply is still
+// sent as a plain message when m was deleted in the meantime.
+func asReply(send *discordgo.MessageSend, m *discordgo.Message) *discordgo.MessageSend {
+	failIfMissing := false
+	ref := m.Reference()
+	ref.FailIfNotExists = &failIfMissing
+	send.Reference = ref
+	send.AllowedMentions = &discordgo.MessageAllowedMentions{}
+	return send
+}
+
+// inWatchedForum reports whether a thread belongs to one of the watched forum parents (or any forum when none are configured for its server)
+func (h *handler) inWatchedForum(ch *discordgo.Channel) bool {
+	if ch.ParentID != "" && h.isWatchedParent(ch.ParentID) {
+		return true
+	}
+	return len(h.watchedParentIDs(ch.GuildID)) == 0
+}
+
 func isThreadChannel(ch *discordgo.Channel) bool {
 	// thread channel types: 11 public thread, 12 private thread, 15 announcement thread in some libs
 	switch ch.Type {
@@ -412,13 +571,14 @@ func isThreadChannel(ch *discordgo.Channel) bool {
 	}
 }
 
-// addPrefixIfMissing adds prefix + space if the name doesn't already start with that prefix
-func addPrefixIfMissing(name, prefix string) string {
+// stripStatusPrefixes removes the bot's status prefixes, in every style, and the extra ones from
+// the start of a thread title
+func stripStatusPrefixes(name string, extra ...string) string {
 	// Only remove our known status prefixes at the start (e.g., [Solved], [Duplicate], etc.)
 	// This preserves user-added brackets like "[Help!] my issue"
-	knownPrefixes := []string{
-		"[Solved]", "[Devs aware]", "[Duplicate]",
-		"[False report]", "[Known issue]", "[Wrong channel]",
+	knownPrefixes := append([]string(nil), extra...)
+	for _, c := range commandConfig {
+		knownPrefixes = append(knownPrefixes, c.Prefix, c.Emoji)
 	}
 
 	stripped := strings.TrimSpace(name)
@@ -436,9 +596,7 @@ func addPrefixIfMissing(name, prefix string) string {
 			break
 		}
 	}
-
-	// Now prepend the desired prefix
-	return prefix + " " + stripped
+	return stripped
 }
 
 // userCanManagePosts checks if a user has MANAGE_MESSAGES or MANAGE_CHANNELS (moderator-like)
@@ -448,15 +606,16 @@ func (h *handler) userCanManagePosts(s *discordgo.Session, userID string, ch *di
 	if err != nil {
 		return false, err
 	}
+	cfg := h.cfg().forGuild(ch.GuildID)
 	// If the config defines allowed role IDs, check whether the member has one of those roles
-	if h.cfg != nil && len(h.cfg.AllowedRoleIDs) > 0 {
+	if cfg != nil && len(cfg.AllowedRoleIDs) > 0 {
 		// fetch member to examine roles
 		member, err := s.GuildMember(ch.GuildID, userID)
 		if err != nil {
 			return false, err
 		}
 		for _, r := range member.Roles {
-			for _, allowed := range h.cfg.AllowedRoleIDs {
+			for _, allowed := range cfg.AllowedRoleIDs {
 				if r == allowed {
 					return true, nil
 				}
@@ -466,25 +625,10 @@ func (h *handler) userCanManagePosts(s *discordgo.Session, userID string, ch *di
 	}
 
 	// If the config defines allowed permission names, map them to bits and require at least one
-	if h.cfg != nil && len(h.cfg.AllowedPermissions) > 0 {
-		for _, name := range h.cfg.AllowedPermissions {
-			switch name {
-			case "ADMINISTRATOR":
-				if perms&discordgo.PermissionAdministrator != 0 {
-					return true, nil
-				}
-			case "MANAGE_CHANNELS":
-				if perms&discordgo.PermissionManageChannels != 0 {
-					return true, nil
-				}
-			case "MANAGE_ROLES":
-				if perms&discordgo.PermissionManageRoles != 0 {
-					return true, nil
-				}
-			case "MANAGE_MESSAGES":
-				if perms&discordgo.PermissionManageMessages != 0 {
-					return true, nil
-				}
+	if cfg != nil && len(cfg.AllowedPermissions) > 0 {
+		for _, name := range cfg.AllowedPermissions {
+			if perms&permissionNames[name] != 0 {
+				return true, nil
 			}
 		}
 		return false, nil
@@ -494,3 +638,30 @@ func (h *handler) userCanManagePosts(s *discordgo.Session, userID string, ch *di
 	const needed = discordgo.PermissionManageChannels | discordgo.PermissionManageRoles | discordgo.PermissionManageMessages | discordgo.PermissionAdministrator
 	return (perms & needed) != 0, nil
 }
+
+// typingRenewal is how often the typing indicator is renewed; Discord clears it after ten seconds
+const typingRenewal = 8 * time.Second
+
+// showTyping shows that the bot is typing in a channel until the returned stop is called, so
+// users can tell a slow lookup or moderation action is in progress. Sending a message also
+// clears the indicator.
+func showTyping(s *discordgo.Session, channelID string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(typingRenewal)
+		defer ticker.Stop()
+		for {
+			if err := s.ChannelTyping(channelID); err != nil {
+				log.Printf("failed to show typing in %s: %v", channelID, err)
+				return
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"text/template"
+	"time"
 
 	yaml "gopkg.in/yaml.v3"
 )
@@ -19,23 +25,405 @@ type Config struct {
 	// Search feature configuration. If SearchEnabled is omitted, the default is true.
 	SearchEnabled  *bool    `yaml:"search_enabled"`
 	SearchChannels []string `yaml:"search_channels"`
+	// Best matches less popular (users listing them) or lower rated (out of 100) than this are
+	// treated as low-confidence: the other matches are offered, or a disclaimer is shown when
+	// there are none. 0 disables; titles whose provider reports no figure are not affected.
+	SearchMinPopularity int `yaml:"search_min_popularity"`
+	SearchMinScore      int `yaml:"search_min_score"`
+	// Let a single {title} find manga as well as anime: the better match is shown, with a link to
+	// the same title in the other format when there is one. <title> stays manga-only.
+	SearchCombined bool `yaml:"search_combined"`
+	// Answer {title} and <title> lookups and the search slash commands in direct messages with
+	// the bot; search_channels does not apply there
+	SearchInDMs bool `yaml:"search_in_dms"`
+	// Providers tried in order by message searches until one has a result: anilist, mal (via
+	// Jikan), kitsu, shikimori, mangadex (manga only). Defaults to anilist then mal.
+	SearchProviders []string `yaml:"search_providers"`
+	// Provider order overrides keyed by channel ID (threads inherit their parent's), e.g. shikimori
+	// first in Russian-language channels
+	SearchChannelProviders map[string][]string `yaml:"search_channel_providers"`
+	// Append the official publishers from MangaUpdates to manga lookups
+	WhereToRead bool `yaml:"where_to_read"`
+	// Titles searches must never return; blocked results are skipped for the next match
+	SearchBlocklist []BlockedTitle `yaml:"search_blocklist"`
+	// Optional AniList API token, sent as a Bearer token with every AniList request; raises the
+	// rate limit for busy servers. Can be set via ANILIST_TOKEN.
+	AniListToken string `yaml:"anilist_token"`
+	// Reacting with this emoji to a message runs its lookups again (default 🔍, "none" disables)
+	SearchReactionEmoji string `yaml:"search_reaction_emoji"`
+	// Rate limits of passive lookups ({title} and <title>)
+	SearchLimits SearchLimitsConfig `yaml:"search_limits"`
+	// A title looked up again in the same channel within this window (e.g. "10m") gets a link to
+	// the earlier result instead of a new embed; default 10m, "0" disables
+	SearchRepeatWindow string `yaml:"search_repeat_window"`
+	searchRepeatWindow time.Duration
+	// Optional machine translation of descriptions in search results
+	DescriptionTranslation TranslationConfig `yaml:"description_translation"`
+	// How long and how many AniList searches are cached
+	SearchCache SearchCacheConfig `yaml:"search_cache"`
+	// How covers of adult titles are shown in NSFW channels: "spoiler" (default, a spoilered
+	// attachment instead of the inline image), "omit" or "inline"
+	AdultCovers string `yaml:"adult_covers"`
+	// Language of bot replies and embed labels (default "en"), with overrides keyed by guild ID and
+	// by channel ID (threads inherit their parent's); other than "en", each needs a catalog in
+	// LocalesDir
+	Locale         string            `yaml:"locale"`
+	GuildLocales   map[string]string `yaml:"guild_locales"`
+	ChannelLocales map[string]string `yaml:"channel_locales"`
+	// Directory of message catalogs, one <locale>.yaml file per language (see locales/)
+	LocalesDir string `yaml:"locales_dir"`
+	catalogs   map[string]messageCatalog
+	// Replacements for the welcome message and the replies of the status commands, keyed by
+	// message key ("command.updated_thread"), as Go text/template with {{.User}}, {{.Thread}},
+	// {{.Tag}}, {{.Forum}} and {{.Code}}
+	Templates map[string]string `yaml:"templates"`
+	templates map[string]*template.Template
+	// Title shown in lookups when a title has several: "english" (default), "romaji" or "native";
+	// members can choose their own with /titlelanguage
+	TitleLanguage string `yaml:"title_language"`
+	// What to do when a command is used outside the watched forums: "silent" (default), "explain"
+	// (a short self-removing notice) or "hint" (lists the watched forums and offers admins a
+	// button to watch the current one)
+	UnwatchedCommandReply string `yaml:"unwatched_command_reply"`
+	// How status commands mark thread titles: "brackets" (default, "[Solved] title"), "emoji"
+	// ("✅ title") or "tag" (the status tag only, titles are left alone). StatusPrefixes sets the
+	// prefix of single statuses, keyed by command; "" leaves the title alone for that status.
+	StatusStyle    string            `yaml:"status_style"`
+	StatusPrefixes map[string]string `yaml:"status_prefixes"`
+	// Characters that start text commands (default "."), e.g. ["!", "k."] where "." is taken by
+	// another bot. The first one is used in the bot's own hints.
+	CommandPrefixes []string `yaml:"command_prefixes"`
+	// Optional: allow the thread creator to run `.solved` in their own thread without moderator permissions.
+	OpCanSolve bool `yaml:"op_can_solve"`
+	// Community vote-to-solve: when the thread author or SolveVoteThreshold members react with
+	// SolveVoteEmoji to a reply, the thread is marked solved and the reply is linked as the fix.
+	SolveVoteEnabled   bool   `yaml:"solve_vote_enabled"`
+	SolveVoteEmoji     string `yaml:"solve_vote_emoji"`
+	SolveVoteThreshold int    `yaml:"solve_vote_threshold"`
+	// GitHub integration. GitHubToken is optional for public data but raises API rate limits.
+	GitHubToken string `yaml:"github_token"`
+	// Repository ("owner/name", e.g. KotatsuApp/Kotatsu) `.escalate` files issues in, using
+	// GitHubToken, with optional EscalationLabels
+	EscalationRepo   string   `yaml:"escalation_repo"`
+	EscalationLabels []string `yaml:"escalation_labels"`
+	// Reply to "#1234" in watched forum threads with the title and state of that issue in
+	// EscalationRepo (or GitHubRepo); `.issue <query>` searches the same repository
+	IssueReferences bool `yaml:"issue_references"`
+	// Follow the GitHub issues linked to threads and announce when they are closed
+	IssueSync IssueSyncConfig `yaml:"issue_sync"`
+	// Announce new GitHub releases in a channel
+	Releases ReleasesConfig `yaml:"releases"`
+	// Announce successful runs of the nightly build workflow
+	Nightly NightlyConfig `yaml:"nightly"`
+	// Weblate project whose progress `.translations` reports
+	Translations TranslationsConfig `yaml:"translations"`
+	// Catalog of the manga sources in kotatsu-parsers, with broken-source alerts
+	Parsers ParsersConfig `yaml:"parsers"`
+	// Optional contributor role sync: members run `.link-github`, authorize the OAuth app
+	// GitHubClientID via device flow, and get ContributorRoleID while they are a member of
+	// GitHubOrg or a contributor to GitHubRepo ("owner/name").
+	GitHubClientID    string `yaml:"github_client_id"`
+	GitHubOrg         string `yaml:"github_org"`
+	GitHubRepo        string `yaml:"github_repo"`
+	ContributorRoleID string `yaml:"contributor_role_id"`
+	// Optional per-forum settings keyed by forum parent ID
+	Forums map[string]ForumConfig `yaml:"forums"`
+	// Optional per-server settings keyed by guild ID (see GuildConfig)
+	Guilds       map[string]GuildConfig `yaml:"guilds"`
+	guildConfigs map[string]*Config
+	// Storage used by `.archive-delete` to keep a copy of threads before they are deleted
+	Archive ArchiveConfig `yaml:"archive"`
+	// Channel that receives thread transcripts from `.transcript` and before `.archive-delete`
+	TranscriptChannelID string `yaml:"transcript_channel_id"`
+	// "markdown" (default) or "html"
+	TranscriptFormat string `yaml:"transcript_format"`
+	// Optional retention policies: messages older than the limit are deleted by the hourly
+	// `retention-sweep` job in ephemeral channels such as bot-spam
+	Retention []RetentionPolicy `yaml:"retention"`
+	// Features whose messages should not show Discord's automatic link previews, e.g.
+	// {search: true, releases: false}. Known features: search, triage, welcome, archive, releases.
+	SuppressLinkEmbeds map[string]bool `yaml:"suppress_link_embeds"`
+	// Optional health monitoring of upstream services Kotatsu depends on
+	StatusMonitor StatusMonitorConfig `yaml:"status_monitor"`
+	// Let `.find` also match the first message of threads, not just titles
+	FindSearchBodies bool `yaml:"find_search_bodies"`
+	// Optional private ops channel that receives an hourly heartbeat with job, error, cache and
+	// API usage counters (schedule adjustable through `jobs.heartbeat`)
+	HeartbeatChannelID string `yaml:"heartbeat_channel_id"`
+	// Where scheduled jobs and other runtime data are persisted: "file" (default), "sqlite", "bolt"
+	// or "postgres"
+	StoreBackend string `yaml:"store"`
+	// Connection string of the PostgreSQL database of store: postgres, e.g.
+	// "postgres://bot:secret@db:5432/kotatsu?sslmode=disable". Overridden by DATABASE_URL.
+	DatabaseURL string `yaml:"database_url"`
+	// Where hot state (search results, forum tags, lookup limits and cooldowns) is kept: "memory"
+	// (default, per process) or "redis" to share it between replicas and shards
+	Cache string `yaml:"cache"`
+	// Address of the Redis server of cache: redis, e.g. "redis://:secret@redis:6379/0".
+	// Overridden by REDIS_URL.
+	RedisURL string `yaml:"redis_url"`
+	// Prefix of the bot's Redis keys, so deployments can share a server; default "kotatsu:"
+	RedisPrefix string `yaml:"redis_prefix"`
+	// Path of the state file used to persist scheduled jobs and other runtime data. Defaults to
+	// data/state.json, data/state.db with the sqlite store or data/state.bolt with the bolt store.
+	DataPath string `yaml:"data_path"`
+	// Snapshot file of the local thread index used by `.find` and duplicate detection. Defaults to
+	// thread_index.json next to the state file.
+	ThreadIndexPath string `yaml:"thread_index_path"`
+	// File the settings changed with admin commands (.watch, /config import) are written to and
+	// read back from on startup. Defaults to overrides.yaml next to the state file.
+	OverridesPath string `yaml:"overrides_path"`
+	// Optional per-job overrides for the scheduler, keyed by job ID (see `/jobs list`).
+	Jobs map[string]JobConfig `yaml:"jobs"`
+	// IANA time zone ("Europe/Berlin") of job schedules, day boundaries and dates in transcripts.
+	// Without it schedules follow the server's zone and dates are UTC.
+	Timezone string `yaml:"timezone"`
+	location *time.Location
+	// Go time layout of dates in transcripts (default "2006-01-02 15:04 MST")
+	DateFormat string `yaml:"date_format"`
+}
+
+// ForumConfig holds settings for a single watched forum
+type ForumConfig struct {
+	// Posted in every new thread of the forum. Go text/template with {{.User}} (author mention),
+	// {{.Thread}} (post title) and {{.Forum}} (forum name).
+	WelcomeMessage string `yaml:"welcome_message"`
+	// Post a pinned triage panel with status buttons in every new thread. The panel follows the
+	// thread's status and is removed once the thread is resolved and locked.
+	TriagePanel bool `yaml:"triage_panel"`
+	// Fields every new post must contain. Posts missing any of them get NeedsInfoTag and the
+	// author is asked to supply the missing details.
+	RequiredFields []RequiredField `yaml:"required_fields"`
+	// Tag applied to posts with missing fields (default "Needs info")
+	NeedsInfoTag string `yaml:"needs_info_tag"`
+	// Topic tags applied automatically to new posts whose title or first message matches
+	AutoTags []AutoTagRule `yaml:"auto_tags"`
+	// Tag new posts with the app version they report (e.g. "v7.7.1") when the forum has a tag
+	// named after that version or its minor series ("v7.7", "7.x")
+	VersionTags bool `yaml:"version_tags"`
+	// Ask authors of new posts reporting a version older than the latest release to update first
+	OutdatedVersionNotice bool `yaml:"outdated_version_notice"`
+	// Tag applied instead when the post reports a nightly build
+	NightlyTag string `yaml:"nightly_tag"`
+	// Android major version ("14") to tag name mapping for new posts
+	AndroidVersionTags map[string]string `yaml:"android_version_tags"`
+	// Device/OEM detection rules for new posts
+	DeviceRules []DeviceRule `yaml:"device_rules"`
+	// Post a short note listing the detected Android version and device in new posts
+	AnnotateDevice bool `yaml:"annotate_device"`
+	// Reply to new posts with links to similar existing threads
+	DuplicateDetection bool `yaml:"duplicate_detection"`
+	// Minimum similarity (0..1) for a thread to be suggested; default 0.35
+	DuplicateThreshold float64 `yaml:"duplicate_threshold"`
+	// Forum tag of each status command in this forum, keyed by command, replacing the built-in
+	// names (e.g. solved: ".Planned" in a suggestions forum)
+	StatusTags map[string]string `yaml:"status_tags"`
+	// Archive threads this long (e.g. "72h") after they got one of AutoCloseStatuses, unless their
+	// status changes meanwhile. The timers are kept in the state store and survive restarts.
+	AutoCloseAfter string `yaml:"auto_close_after"`
+	autoCloseAfter time.Duration
+	// Statuses that start the auto_close_after timer; default solved, duplicate, false and wrong
+	AutoCloseStatuses []string `yaml:"auto_close_statuses"`
+}
+
+// DeviceRule recognises a device family by a case-insensitive regex; Tag is optional
+type DeviceRule struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	Tag     string `yaml:"tag"`
+	re      *regexp.Regexp
+}
+
+// AutoTagRule applies Tag when any keyword (case-insensitive, whole word) or the regex Pattern matches
+type AutoTagRule struct {
+	Tag      string   `yaml:"tag"`
+	Keywords []string `yaml:"keywords"`
+	Pattern  string   `yaml:"pattern"`
+	re       *regexp.Regexp
+}
+
+// RequiredField is a named regular expression a bug report must match (case-insensitive)
+type RequiredField struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+	re      *regexp.Regexp
+}
+
+// ArchiveConfig selects where `.archive-delete` stores thread archives
+type ArchiveConfig struct {
+	// "local" or "s3"; empty disables `.archive-delete`
+	Backend string `yaml:"backend"`
+	// Directory for the local backend
+	Path string `yaml:"path"`
+	// S3 or S3-compatible storage. S3Endpoint defaults to AWS for S3Region.
+	S3Endpoint  string `yaml:"s3_endpoint"`
+	S3Region    string `yaml:"s3_region"`
+	S3Bucket    string `yaml:"s3_bucket"`
+	S3AccessKey string `yaml:"s3_access_key"`
+	S3SecretKey string `yaml:"s3_secret_key"`
+	// How long archives must be kept; recorded in the archive metadata. Local archives are pruned after it.
+	RetentionDays int `yaml:"retention_days"`
+	// Optional channel that receives a notice for every archived deletion
+	LogChannelID string `yaml:"log_channel_id"`
+}
+
+// RetentionPolicy auto-deletes old messages in one channel
+type RetentionPolicy struct {
+	ChannelID  string `yaml:"channel_id"`
+	MaxAgeDays int    `yaml:"max_age_days"`
+	// Pinned messages are kept unless this is explicitly false
+	KeepPinned *bool `yaml:"keep_pinned"`
+	// Messages from these users (e.g. rules or FAQ posters) are never deleted
+	ExemptUserIDs []string `yaml:"exempt_user_ids"`
+}
+
+// StatusMonitorConfig configures the `status-probe` job
+type StatusMonitorConfig struct {
+	// Channel that receives outage and recovery notices
+	ChannelID string `yaml:"channel_id"`
+	// Consecutive failed probes before a service is considered down (default 3)
+	FailureThreshold int                `yaml:"failure_threshold"`
+	Services         []MonitoredService `yaml:"services"`
+}
+
+// MonitoredService is an upstream endpoint probed periodically
+type MonitoredService struct {
+	Name   string `yaml:"name"`
+	URL    string `yaml:"url"`
+	Method string `yaml:"method"`
+	// Status codes counted as healthy; by default anything below 500 is
+	ExpectStatus []int `yaml:"expect_status"`
+	// New posts mentioning any keyword (default: the service name) get an outage banner while it is down
+	Keywords []string `yaml:"keywords"`
+}
+
+// IssueSyncConfig configures the `github-issue-sync` job
+type IssueSyncConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Status command (e.g. "solved", "known") applied when the issue is closed as completed or
+	// as not planned; empty only posts the notice
+	CompletedStatus  string `yaml:"completed_status"`
+	NotPlannedStatus string `yaml:"not_planned_status"`
+}
+
+// ReleasesConfig configures the `release-watch` job
+type ReleasesConfig struct {
+	// Repository to watch ("owner/name"); defaults to github_repo
+	Repo string `yaml:"repo"`
+	// Channel for announcements; empty disables them
+	ChannelID string `yaml:"channel_id"`
+	// Publish announcements to following servers (the channel must be an announcement channel)
+	Crosspost bool `yaml:"crosspost"`
+	// Also announce releases marked as pre-release, and nightly builds
+	IncludePrereleases bool `yaml:"include_prereleases"`
+	IncludeNightly     bool `yaml:"include_nightly"`
+	// App package on F-Droid, and an optional F-Droid compatible repository index (index-v1.json)
+	// publishing the same package, compared by `.stores`
+	FDroidPackage string `yaml:"fdroid_package"`
+	RepoIndexURL  string `yaml:"repo_index_url"`
+}
+
+// NightlyConfig configures the `nightly-watch` job
+type NightlyConfig struct {
+	// Channel for nightly build notifications; empty disables them
+	ChannelID string `yaml:"channel_id"`
+	// Repository running the workflow; defaults to github_repo
+	Repo string `yaml:"repo"`
+	// Workflow file name or ID, e.g. "nightly.yml"
+	Workflow string `yaml:"workflow"`
+	// Only builds of this branch (optional)
+	Branch string `yaml:"branch"`
+}
+
+// TranslationsConfig points `.translations` at a Weblate project
+type TranslationsConfig struct {
+	// Weblate instance; defaults to https://hosted.weblate.org
+	URL string `yaml:"url"`
+	// Project slug; empty disables the command
+	Project string `yaml:"project"`
+	// Optional API token (WEBLATE_TOKEN), raises the anonymous rate limit
+	Token string `yaml:"token"`
+}
+
+// TranslationConfig selects the service translating search result descriptions
+type TranslationConfig struct {
+	// "deepl" or "libretranslate"; empty disables translation
+	Service string `yaml:"service"`
+	// API endpoint; required for libretranslate, DeepL's is used by default
+	URL string `yaml:"url"`
+	// API key (TRANSLATION_API_KEY); optional for self-hosted LibreTranslate
+	APIKey string `yaml:"api_key"`
+	// Language code to translate into, e.g. "de" or "pt-br"
+	TargetLanguage string `yaml:"target_language"`
+}
+
+// SearchLimitsConfig caps passive lookups; 0 means unlimited
+type SearchLimitsConfig struct {
+	// Lookups per channel (or thread) within a rolling minute
+	ChannelPerMinute int `yaml:"channel_per_minute"`
+	// Lookups per user per UTC day
+	UserPerDay int `yaml:"user_per_day"`
+}
+
+// SearchCacheConfig sizes the cache of AniList search results
+type SearchCacheConfig struct {
+	// How long results are reused, e.g. "30m"; default 1h, "0" disables the cache
+	TTL string `yaml:"ttl"`
+	// Maximum number of cached searches; default 500
+	Size int `yaml:"size"`
+	ttl  time.Duration
+}
+
+// ParsersConfig configures the `parser-scan` job
+type ParsersConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Repository and branch to scan; default KotatsuApp/kotatsu-parsers, master
+	Repo   string `yaml:"repo"`
+	Branch string `yaml:"branch"`
+	// Dev channel told when sources become broken or are fixed
+	AlertChannelID string `yaml:"alert_channel_id"`
+	// Reply in new posts that mention a source currently marked broken
+	ReplyInThreads bool `yaml:"reply_in_threads"`
+}
+
+// JobConfig overrides the defaults of a scheduled job
+type JobConfig struct {
+	// Cron expression ("*/10 * * * *", "@hourly", "@every 90m")
+	Schedule string `yaml:"schedule"`
+	// Random delay added to each run, e.g. "30s", to avoid thundering herds
+	Jitter string `yaml:"jitter"`
+	// What to do with runs missed while the bot was offline: "once" (default) or "skip"
+	CatchUp string `yaml:"catch_up"`
+	// Maximum number of overlapping runs of this job (default 1)
+	MaxConcurrency int   `yaml:"max_concurrency"`
+	Paused         *bool `yaml:"paused"`
 }
 
 // LoadConfig reads config.yaml if present and merges with environment variables (env overrides file)
 func LoadConfig(path string) (*Config, error) {
 	cfg := &Config{}
+	var problems []error
 	if _, err := os.Stat(path); err == nil {
 		b, err := ioutil.ReadFile(path)
 		if err != nil {
 			return nil, err
 		}
-		if err := yaml.Unmarshal(b, cfg); err != nil {
+		var doc yaml.Node
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return nil, err
+		}
+		problems = expandEnv(&doc)
+		decodeProblems, err := decodeConfigStrict(b, &doc, cfg)
+		if err != nil {
 			return nil, err
 		}
+		problems = append(problems, decodeProblems...)
 	}
 
-	// env overrides
-	if t := os.Getenv("DISCORD_TOKEN"); t != "" {
+	// env overrides; secrets can also be read from the file named by <NAME>_FILE
+	if t, err := secretEnv("DISCORD_TOKEN"); err != nil {
+		problems = append(problems, err)
+	} else if t != "" {
 		cfg.DiscordToken = t
 	}
 	if p := os.Getenv("FORUM_PARENT_IDS"); p != "" {
@@ -77,11 +465,347 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.SearchChannels = parts
 	}
 
+	if o := os.Getenv("OP_CAN_SOLVE"); o != "" {
+		lowered := strings.ToLower(strings.TrimSpace(o))
+		cfg.OpCanSolve = lowered == "1" || lowered == "true" || lowered == "yes"
+	}
+
+	if cfg.SolveVoteEmoji == "" {
+		cfg.SolveVoteEmoji = "✅"
+	}
+	if cfg.SolveVoteThreshold <= 0 {
+		cfg.SolveVoteThreshold = 3
+	}
+
+	if t, err := secretEnv("GITHUB_TOKEN"); err != nil {
+		problems = append(problems, err)
+	} else if t != "" {
+		cfg.GitHubToken = t
+	}
+	if c := os.Getenv("GITHUB_CLIENT_ID"); c != "" {
+		cfg.GitHubClientID = c
+	}
+	if t, err := secretEnv("ANILIST_TOKEN"); err != nil {
+		problems = append(problems, err)
+	} else if t != "" {
+		cfg.AniListToken = t
+	}
+	if k, err := secretEnv("TRANSLATION_API_KEY"); err != nil {
+		problems = append(problems, err)
+	} else if k != "" {
+		cfg.DescriptionTranslation.APIKey = k
+	}
+	if t, err := secretEnv("WEBLATE_TOKEN"); err != nil {
+		problems = append(problems, err)
+	} else if t != "" {
+		cfg.Translations.Token = t
+	}
+
+	if k, err := secretEnv("ARCHIVE_S3_ACCESS_KEY"); err != nil {
+		problems = append(problems, err)
+	} else if k != "" {
+		cfg.Archive.S3AccessKey = k
+	}
+	if k, err := secretEnv("ARCHIVE_S3_SECRET_KEY"); err != nil {
+		problems = append(problems, err)
+	} else if k != "" {
+		cfg.Archive.S3SecretKey = k
+	}
+
+	if u, err := secretEnv("DATABASE_URL"); err != nil {
+		problems = append(problems, err)
+	} else if u != "" {
+		cfg.DatabaseURL = u
+	}
+
+	if u, err := secretEnv("REDIS_URL"); err != nil {
+		problems = append(problems, err)
+	} else if u != "" {
+		cfg.RedisURL = u
+	}
+	switch cfg.Cache {
+	case "", cacheMemory:
+		cfg.Cache = cacheMemory
+	case cacheRedis:
+		if cfg.RedisURL == "" {
+			problems = append(problems, fmt.Errorf("cache: redis needs redis_url (or REDIS_URL)"))
+		}
+		if cfg.RedisPrefix == "" {
+			cfg.RedisPrefix = "kotatsu:"
+		}
+	default:
+		problems = append(problems, fmt.Errorf("cache: unknown backend %q (use %q or %q)", cfg.Cache, cacheMemory, cacheRedis))
+	}
+
+	if d := os.Getenv("DATA_PATH"); d != "" {
+		cfg.DataPath = d
+	}
+	switch cfg.StoreBackend {
+	case "", storeFile:
+		cfg.StoreBackend = storeFile
+		if cfg.DataPath == "" {
+			cfg.DataPath = "data/state.json"
+		}
+	case storeSQLite:
+		if cfg.DataPath == "" {
+			cfg.DataPath = "data/state.db"
+		}
+	case storeBolt:
+		if cfg.DataPath == "" {
+			cfg.DataPath = "data/state.bolt"
+		}
+	case storePostgres:
+		// data_path still places the thread index and overrides, and names the state file an
+		// empty database takes over
+		if cfg.DataPath == "" {
+			cfg.DataPath = "data/state.json"
+		}
+		if cfg.DatabaseURL == "" {
+			problems = append(problems, fmt.Errorf("store: postgres needs database_url (or DATABASE_URL)"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("store: unknown backend %q (use %q, %q, %q or %q)", cfg.StoreBackend, storeFile, storeSQLite, storeBolt, storePostgres))
+	}
+	if cfg.ThreadIndexPath == "" {
+		cfg.ThreadIndexPath = filepath.Join(filepath.Dir(cfg.DataPath), "thread_index.json")
+	}
+	if cfg.OverridesPath == "" {
+		cfg.OverridesPath = filepath.Join(filepath.Dir(cfg.DataPath), "overrides.yaml")
+	}
+
 	// Default: enable search if not specified in file or environment
 	if cfg.SearchEnabled == nil {
 		defaultEnabled := true
 		cfg.SearchEnabled = &defaultEnabled
 	}
 
+	// unknown settings and bad list entries are reported together
+	if problems = append(problems, cfg.checkFields()...); len(problems) > 0 {
+		return nil, errors.Join(problems...)
+	}
+	if err := cfg.mergeGuildForums(); err != nil {
+		return nil, err
+	}
+	if err := cfg.compile(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
+
+// resolveProviderAliases replaces alternative provider names ("jikan") in place
+func resolveProviderAliases(providers []string) {
+	for i, p := range providers {
+		if name, ok := providerAliases[strings.ToLower(p)]; ok {
+			providers[i] = name
+		}
+	}
+}
+
+// checkTrackers rejects names that are not registered media providers
+func checkTrackers(field string, providers []string) error {
+	for _, p := range providers {
+		if _, ok := mediaProviders[p]; !ok {
+			return fmt.Errorf("%s: unknown provider %q (use %s)", field, p, strings.Join(mediaProviderNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// compile fills defaults and precompiles the patterns of per-forum settings
+func (cfg *Config) compile() error {
+	switch cfg.UnwatchedCommandReply {
+	case "", unwatchedSilent, unwatchedExplain, unwatchedHint:
+	default:
+		return fmt.Errorf("unwatched_command_reply: unknown value %q (use %q, %q or %q)", cfg.UnwatchedCommandReply, unwatchedSilent, unwatchedExplain, unwatchedHint)
+	}
+	if err := cfg.compileTimezone(); err != nil {
+		return err
+	}
+	if err := checkCommandPrefixes("command_prefixes", cfg.CommandPrefixes); err != nil {
+		return err
+	}
+	if err := checkStatusStyle("", cfg.StatusStyle, cfg.StatusPrefixes); err != nil {
+		return err
+	}
+	switch cfg.AdultCovers {
+	case "":
+		cfg.AdultCovers = adultCoversSpoiler
+	case adultCoversSpoiler, adultCoversOmit, adultCoversInline:
+	default:
+		return fmt.Errorf("adult_covers: unknown value %q (use %q, %q or %q)", cfg.AdultCovers, adultCoversSpoiler, adultCoversOmit, adultCoversInline)
+	}
+	switch cfg.TranscriptFormat {
+	case "":
+		cfg.TranscriptFormat = transcriptMarkdown
+	case transcriptMarkdown, transcriptHTML:
+	default:
+		return fmt.Errorf("transcript_format: unknown value %q (use %q or %q)", cfg.TranscriptFormat, transcriptMarkdown, transcriptHTML)
+	}
+	for _, st := range []string{cfg.IssueSync.CompletedStatus, cfg.IssueSync.NotPlannedStatus} {
+		if _, ok := commandConfig[st]; st != "" && !ok {
+			return fmt.Errorf("issue_sync: unknown status %q (use one of the status commands, e.g. \"solved\")", st)
+		}
+	}
+	if len(cfg.SearchProviders) == 0 {
+		cfg.SearchProviders = []string{trackerAniList, trackerMAL}
+	}
+	resolveProviderAliases(cfg.SearchProviders)
+	for _, providers := range cfg.SearchChannelProviders {
+		resolveProviderAliases(providers)
+	}
+	if err := checkTrackers("search_providers", cfg.SearchProviders); err != nil {
+		return err
+	}
+	for id, providers := range cfg.SearchChannelProviders {
+		if len(providers) == 0 {
+			return fmt.Errorf("search_channel_providers[%s]: at least one provider is required", id)
+		}
+		if err := checkTrackers("search_channel_providers["+id+"]", providers); err != nil {
+			return err
+		}
+	}
+	switch cfg.SearchReactionEmoji {
+	case "":
+		cfg.SearchReactionEmoji = "🔍"
+	case "none":
+		cfg.SearchReactionEmoji = ""
+	}
+	switch tr := cfg.DescriptionTranslation; tr.Service {
+	case "":
+	case translatorDeepL, translatorLibreTranslate:
+		if tr.TargetLanguage == "" {
+			return fmt.Errorf("description_translation: target_language is required")
+		}
+		if tr.Service == translatorDeepL && tr.APIKey == "" {
+			return fmt.Errorf("description_translation: DeepL needs api_key")
+		}
+		if tr.Service == translatorLibreTranslate && tr.URL == "" {
+			return fmt.Errorf("description_translation: LibreTranslate needs url")
+		}
+	default:
+		return fmt.Errorf("description_translation.service: unknown value %q (use %q or %q)", tr.Service, translatorDeepL, translatorLibreTranslate)
+	}
+	if cfg.SearchRepeatWindow == "" {
+		cfg.SearchRepeatWindow = "10m"
+	}
+	window, err := time.ParseDuration(cfg.SearchRepeatWindow)
+	if err != nil || window < 0 {
+		return fmt.Errorf("search_repeat_window: invalid duration %q", cfg.SearchRepeatWindow)
+	}
+	cfg.searchRepeatWindow = window
+	if cfg.SearchCache.TTL == "" {
+		cfg.SearchCache.TTL = "1h"
+	}
+	if cfg.SearchCache.Size == 0 {
+		cfg.SearchCache.Size = 500
+	}
+	ttl, err := time.ParseDuration(cfg.SearchCache.TTL)
+	if err != nil || ttl < 0 {
+		return fmt.Errorf("search_cache.ttl: invalid duration %q", cfg.SearchCache.TTL)
+	}
+	cfg.SearchCache.ttl = ttl
+	for i := range cfg.SearchBlocklist {
+		b := &cfg.SearchBlocklist[i]
+		if b.AniListID == 0 && b.Pattern == "" {
+			return fmt.Errorf("search_blocklist[%d]: anilist_id or pattern is required", i)
+		}
+		if b.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + b.Pattern)
+			if err != nil {
+				return fmt.Errorf("search_blocklist[%d]: invalid pattern: %v", i, err)
+			}
+			b.re = re
+		}
+	}
+	if cfg.SearchMinPopularity < 0 || cfg.SearchMinScore < 0 || cfg.SearchMinScore > 100 {
+		return fmt.Errorf("search_min_popularity must be >= 0 and search_min_score between 0 and 100")
+	}
+	switch cfg.TitleLanguage {
+	case "":
+		cfg.TitleLanguage = titleEnglish
+	case titleEnglish, titleRomaji, titleNative:
+	default:
+		return fmt.Errorf("title_language: unknown value %q (use %q, %q or %q)", cfg.TitleLanguage, titleEnglish, titleRomaji, titleNative)
+	}
+	templates, err := compileTemplates("templates", cfg.Templates)
+	if err != nil {
+		return err
+	}
+	cfg.templates = templates
+	if err := cfg.checkLocales(); err != nil {
+		return err
+	}
+	if cfg.Nightly.Repo == "" {
+		cfg.Nightly.Repo = cfg.GitHubRepo
+	}
+	if cfg.Translations.URL == "" {
+		cfg.Translations.URL = "https://hosted.weblate.org"
+	}
+	if cfg.Parsers.Repo == "" {
+		cfg.Parsers.Repo = "KotatsuApp/kotatsu-parsers"
+	}
+	if cfg.Parsers.Branch == "" {
+		cfg.Parsers.Branch = "master"
+	}
+	if cfg.Releases.Repo == "" {
+		cfg.Releases.Repo = cfg.GitHubRepo
+	}
+	for i, rp := range cfg.Retention {
+		if rp.ChannelID == "" || rp.MaxAgeDays <= 0 {
+			return fmt.Errorf("retention[%d]: channel_id and a positive max_age_days are required", i)
+		}
+	}
+	for id, fc := range cfg.Forums {
+		if fc.NeedsInfoTag == "" {
+			fc.NeedsInfoTag = "Needs info"
+		}
+		for cmd, tag := range fc.StatusTags {
+			if _, ok := commandConfig[cmd]; !ok {
+				return fmt.Errorf("forums.%s.status_tags: unknown status %q (use one of the status commands, e.g. \"solved\")", id, cmd)
+			}
+			if strings.TrimSpace(tag) == "" {
+				return fmt.Errorf("forums.%s.status_tags.%s: tag name is required", id, cmd)
+			}
+		}
+		if err := fc.compileAutoClose("forums." + id); err != nil {
+			return err
+		}
+		for i := range fc.RequiredFields {
+			f := &fc.RequiredFields[i]
+			re, err := regexp.Compile("(?i)" + f.Pattern)
+			if err != nil {
+				return fmt.Errorf("forums.%s.required_fields[%d] (%s): invalid pattern: %v", id, i, f.Name, err)
+			}
+			f.re = re
+		}
+		for i := range fc.AutoTags {
+			r := &fc.AutoTags[i]
+			var alts []string
+			for _, kw := range r.Keywords {
+				alts = append(alts, `\b`+regexp.QuoteMeta(kw)+`\b`)
+			}
+			if r.Pattern != "" {
+				alts = append(alts, "(?:"+r.Pattern+")")
+			}
+			if r.Tag == "" || len(alts) == 0 {
+				return fmt.Errorf("forums.%s.auto_tags[%d]: tag and at least one keyword or pattern are required", id, i)
+			}
+			re, err := regexp.Compile("(?i)" + strings.Join(alts, "|"))
+			if err != nil {
+				return fmt.Errorf("forums.%s.auto_tags[%d] (%s): invalid pattern: %v", id, i, r.Tag, err)
+			}
+			r.re = re
+		}
+		for i := range fc.DeviceRules {
+			r := &fc.DeviceRules[i]
+			re, err := regexp.Compile("(?i)" + r.Pattern)
+			if err != nil || r.Name == "" || r.Pattern == "" {
+				return fmt.Errorf("forums.%s.device_rules[%d] (%s): name and a valid pattern are required: %v", id, i, r.Name, err)
+			}
+			r.re = re
+		}
+		cfg.Forums[id] = fc
+	}
+	return cfg.compileGuilds()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// envRefRe matches ${VAR} and ${VAR:-default} in config.yaml; $${ is a literal "${"
+var envRefRe = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv replaces the ${VAR} references in the values and keys of a parsed configuration file
+// with the values of the environment variables, so tokens and IDs can come from the environment
+// while the rest stays in the file. Only scalars are expanded, after parsing, so a value cannot
+// change the structure of the file and references in comments are left alone. An unquoted
+// reference is typed by its value, e.g. a number for an int setting. It returns one error per
+// unset variable without a default.
+func expandEnv(n *yaml.Node) []error {
+	var errs []error
+	if n.Kind == yaml.ScalarNode && strings.Contains(n.Value, "${") {
+		n.Value = envRefRe.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			m := envRefRe.FindStringSubmatch(ref)
+			v, ok := os.LookupEnv(m[1])
+			if !ok {
+				if !strings.Contains(ref, ":-") {
+					errs = append(errs, fmt.Errorf("line %d: environment variable %s is not set", n.Line, m[1]))
+					return ref
+				}
+				v = m[2]
+			}
+			return v
+		})
+		if n.Style == 0 {
+			// resolved again from the expanded text when decoded
+			n.Tag = ""
+		}
+	}
+	for _, c := range n.Content {
+		errs = append(errs, expandEnv(c)...)
+	}
+	return errs
+}
+
+// secretEnv returns the value of the environment variable name or, when NAME_FILE is set
+// instead, the content of the file it points to, as mounted by Docker and Kubernetes secrets.
+// Surrounding whitespace, such as the trailing newline of most secret files, is dropped.
+func secretEnv(name string) (string, error) {
+	v := os.Getenv(name)
+	path := os.Getenv(name + "_FILE")
+	switch {
+	case path == "":
+		return v, nil
+	case v != "":
+		return "", fmt.Errorf("%s and %s_FILE are both set; use one of them", name, name)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s_FILE: %v", name, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
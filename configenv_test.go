@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "abc")
+	t.Setenv("TEST_SIZE", "42")
+	t.Setenv("TEST_YAMLISH", "b: c # d")
+	t.Setenv("TEST_BRACKET", "[1, 2")
+	tests := []struct {
+		name     string
+		src      string
+		want     map[string]interface{}
+		wantErrs int
+	}{
+		{"plain reference", "a: ${TEST_TOKEN}", map[string]interface{}{"a": "abc"}, 0},
+		{"quoted reference", `a: "${TEST_TOKEN}"`, map[string]interface{}{"a": "abc"}, 0},
+		{"inside a value", "a: x-${TEST_TOKEN}-${TEST_SIZE}", map[string]interface{}{"a": "x-abc-42"}, 0},
+		{"unquoted takes the value's type", "a: ${TEST_SIZE}", map[string]interface{}{"a": 42}, 0},
+		{"quoted stays a string", `a: "${TEST_SIZE}"`, map[string]interface{}{"a": "42"}, 0},
+		{"YAML syntax in the value", "a: ${TEST_YAMLISH}\nb: 1", map[string]interface{}{"a": "b: c # d", "b": 1}, 0},
+		{"leading bracket in the value", "a: ${TEST_BRACKET}", map[string]interface{}{"a": "[1, 2"}, 0},
+		{"map key", "${TEST_TOKEN}: 1", map[string]interface{}{"abc": 1}, 0},
+		{"list item", "a:\n  - ${TEST_TOKEN}", map[string]interface{}{"a": []interface{}{"abc"}}, 0},
+		{"default", "a: ${TEST_UNSET:-fallback}", map[string]interface{}{"a": "fallback"}, 0},
+		{"empty default", `a: "${TEST_UNSET:-}"`, map[string]interface{}{"a": ""}, 0},
+		{"set variable wins over default", "a: ${TEST_TOKEN:-fallback}", map[string]interface{}{"a": "abc"}, 0},
+		{"unset variable", "a: ${TEST_UNSET}", map[string]interface{}{"a": "${TEST_UNSET}"}, 1},
+		{"literal", "a: $${TEST_TOKEN}", map[string]interface{}{"a": "${TEST_TOKEN}"}, 0},
+		{"comment", "a: x # ${TEST_UNSET}\n# ${TEST_UNSET}", map[string]interface{}{"a": "x"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.src), &doc); err != nil {
+				t.Fatal(err)
+			}
+			errs := expandEnv(&doc)
+			if len(errs) != tt.wantErrs {
+				t.Errorf("got errors %v, want %d", errs, tt.wantErrs)
+			}
+			var got map[string]interface{}
+			if err := doc.Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretEnv(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "token")
+	if err := os.WriteFile(secret, []byte("  from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		value   string
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{"neither set", "", "", "", false},
+		{"variable", "from-env", "", "from-env", false},
+		{"file, whitespace trimmed", "", secret, "from-file", false},
+		{"both set", "from-env", secret, "", true},
+		{"missing file", "", filepath.Join(dir, "missing"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_SECRET", tt.value)
+			t.Setenv("TEST_SECRET_FILE", tt.file)
+			got, err := secretEnv("TEST_SECRET")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("secretEnv() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("secretEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
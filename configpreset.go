@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// forumConfigsBucket held imported forum settings before they moved to the overrides file
+	forumConfigsBucket = "forum_configs"
+	// maxPresetBytes bounds the size of an imported preset file
+	maxPresetBytes = 256 << 10
+	// pendingImportTTL is how long an import preview can be applied
+	pendingImportTTL = 15 * time.Minute
+)
+
+// configPreset is the shareable form of a guild's per-forum settings. Forums are keyed by name
+// because channel IDs differ between servers.
+type configPreset struct {
+	Version int                    `yaml:"version"`
+	Forums  map[string]ForumConfig `yaml:"forums"`
+}
+
+// pendingImport is a validated preset waiting for the Apply button
+type pendingImport struct {
+	guildID string
+	userID  string
+	forums  map[string]ForumConfig // keyed by forum ID
+	created time.Time
+}
+
+var (
+	pendingImportsMu sync.Mutex
+	pendingImports   = map[string]*pendingImport{}
+)
+
+func init() {
+	manageServer := int64(discordgo.PermissionManageServer)
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:                     "config",
+		Description:              "Share forum settings between servers",
+		DefaultMemberPermissions: &manageServer,
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "export", Description: "Export this server's forum settings as a preset file"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "import", Description: "Preview and apply a preset file", Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionAttachment, Name: "file", Description: "Preset exported with /config export (YAML or JSON)", Required: true},
+			}},
+		},
+	}, (*handler).handleConfigCommand)
+	registerComponentHandler("config:", (*handler).handleConfigButton)
+}
+
+// guildForums returns the forum channels of a guild
+func guildForums(s *discordgo.Session, guildID string) ([]*discordgo.Channel, error) {
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*discordgo.Channel
+	for _, ch := range channels {
+		if ch.Type == discordgo.ChannelTypeGuildForum {
+			out = append(out, ch)
+		}
+	}
+	return out, nil
+}
+
+// handleConfigCommand implements /config export|import
+func (h *handler) handleConfigCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" || i.Member == nil || i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) == 0 {
+		respondEphemeral(s, i, h.interactionReply(s, i, "permission.config"))
+		return
+	}
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	forums, err := guildForums(s, i.GuildID)
+	if err != nil {
+		log.Printf("config: failed to list forums of %s: %v", i.GuildID, err)
+		respondEphemeral(s, i, "Failed to read this server's forums.")
+		return
+	}
+	switch sub := data.Options[0]; sub.Name {
+	case "export":
+		preset := configPreset{Version: 1, Forums: map[string]ForumConfig{}}
+		for _, f := range forums {
+			if fc, ok := h.cfg().Forums[f.ID]; ok {
+				preset.Forums[f.Name] = fc
+			}
+		}
+		if len(preset.Forums) == 0 {
+			respondEphemeral(s, i, "No forum in this server has bot settings to export.")
+			return
+		}
+		out, err := yaml.Marshal(preset)
+		if err != nil {
+			log.Printf("config: failed to encode preset: %v", err)
+			return
+		}
+		err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("Settings of %d forum(s). Import them elsewhere with `/config import`.", len(preset.Forums)),
+				Files:   []*discordgo.File{{Name: "kotatsu-bot-preset.yaml", ContentType: "application/yaml", Reader: bytes.NewReader(out)}},
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			log.Printf("config: failed to send export: %v", err)
+		}
+	case "import":
+		att := data.Resolved.Attachments[sub.Options[0].Value.(string)]
+		if att == nil {
+			return
+		}
+		h.previewImport(s, i, att, forums)
+	}
+}
+
+// previewImport validates an uploaded preset, maps it onto this guild's forums by name and shows
+// a diff with Apply/Cancel buttons
+func (h *handler) previewImport(s *discordgo.Session, i *discordgo.InteractionCreate, att *discordgo.MessageAttachment, forums []*discordgo.Channel) {
+	if att.Size > maxPresetBytes {
+		respondEphemeral(s, i, "That file is too large to be a preset.")
+		return
+	}
+	resp, err := http.Get(att.URL)
+	if err != nil {
+		respondEphemeral(s, i, "Failed to download the preset.")
+		return
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxPresetBytes))
+	if err != nil {
+		respondEphemeral(s, i, "Failed to download the preset.")
+		return
+	}
+	// JSON is valid YAML, so one decoder handles both formats
+	var preset configPreset
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&preset); err != nil {
+		respondEphemeral(s, i, "Not a valid preset: "+err.Error())
+		return
+	}
+	if preset.Version != 1 {
+		respondEphemeral(s, i, fmt.Sprintf("Unsupported preset version %d.", preset.Version))
+		return
+	}
+
+	byName := map[string]*discordgo.Channel{}
+	for _, f := range forums {
+		byName[strings.ToLower(f.Name)] = f
+	}
+	mapped := map[string]ForumConfig{}
+	var unmatched []string
+	for name, fc := range preset.Forums {
+		if f, ok := byName[strings.ToLower(name)]; ok {
+			mapped[f.ID] = fc
+		} else {
+			unmatched = append(unmatched, name)
+		}
+	}
+	if len(mapped) == 0 {
+		respondEphemeral(s, i, "None of the preset's forums exist in this server (forums are matched by name).")
+		return
+	}
+	check := &Config{Forums: mapped}
+	if err := check.compile(); err != nil {
+		respondEphemeral(s, i, "The preset is invalid: "+err.Error())
+		return
+	}
+
+	ids := make([]string, 0, len(mapped))
+	for id := range mapped {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	diff := &strings.Builder{}
+	for _, id := range ids {
+		oldYAML, newYAML := "", ""
+		if old, ok := h.cfg().Forums[id]; ok {
+			b, _ := yaml.Marshal(old)
+			oldYAML = string(b)
+		}
+		b, _ := yaml.Marshal(mapped[id])
+		newYAML = string(b)
+		fmt.Fprintf(diff, "# %s\n", forumName(forums, id))
+		if oldYAML == newYAML {
+			diff.WriteString("  (unchanged)\n")
+			continue
+		}
+		diff.WriteString(lineDiff(oldYAML, newYAML))
+	}
+	preview := diff.String()
+	if r := []rune(preview); len(r) > 1500 {
+		preview = string(r[:1500]) + "\n…"
+	}
+	msg := &strings.Builder{}
+	fmt.Fprintf(msg, "Importing settings for %d forum(s):\n```diff\n%s```", len(mapped), preview)
+	if len(unmatched) > 0 {
+		sort.Strings(unmatched)
+		fmt.Fprintf(msg, "\nSkipped (no forum with that name here): %s", strings.Join(unmatched, ", "))
+	}
+
+	key := strconv.FormatInt(time.Now().UnixNano(), 36)
+	pendingImportsMu.Lock()
+	for k, p := range pendingImports {
+		if time.Since(p.created) > pendingImportTTL {
+			delete(pendingImports, k)
+		}
+	}
+	pendingImports[key] = &pendingImport{guildID: i.GuildID, userID: i.Member.User.ID, forums: check.Forums, created: time.Now()}
+	pendingImportsMu.Unlock()
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg.String(),
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Apply", Style: discordgo.DangerButton, CustomID: "config:apply:" + key},
+				discordgo.Button{Label: "Cancel", Style: discordgo.SecondaryButton, CustomID: "config:cancel:" + key},
+			}}},
+		},
+	})
+	if err != nil {
+		log.Printf("config: failed to send import preview: %v", err)
+	}
+}
+
+// handleConfigButton applies or discards a previewed import
+func (h *handler) handleConfigButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if len(parts) != 3 || i.Member == nil {
+		return
+	}
+	pendingImportsMu.Lock()
+	p, ok := pendingImports[parts[2]]
+	delete(pendingImports, parts[2])
+	pendingImportsMu.Unlock()
+
+	content := "Import cancelled."
+	switch {
+	case !ok || p.guildID != i.GuildID || p.userID != i.Member.User.ID:
+		content = "This preview has expired; run `/config import` again."
+	case parts[1] == "apply":
+		// the live config is shared with running handlers, so the import goes into a compiled copy
+		// that replaces it, as on a reload
+		var compileErr error
+		err := h.updateOverrides(func(o *runtimeOverrides) {
+			next := *h.cfg()
+			next.Forums = make(map[string]ForumConfig, len(next.Forums)+len(p.forums))
+			for id, fc := range h.cfg().Forums {
+				next.Forums[id] = fc
+			}
+			for id, fc := range p.forums {
+				next.Forums[id] = fc
+			}
+			if compileErr = next.compile(); compileErr != nil {
+				return
+			}
+			if o.Forums == nil {
+				o.Forums = map[string]ForumConfig{}
+			}
+			for id, fc := range p.forums {
+				o.Forums[id] = fc
+			}
+			h.liveCfg.Store(&next)
+		})
+		if compileErr != nil {
+			log.Printf("config: imported forum settings are invalid: %v", compileErr)
+			content = fmt.Sprintf("❌ The imported settings are invalid: %v", compileErr)
+			break
+		}
+		if err != nil {
+			log.Printf("config: failed to save imported forum settings: %v", err)
+		}
+		log.Printf("config: %s imported settings for %d forum(s) in guild %s", p.userID, len(p.forums), p.guildID)
+		content = fmt.Sprintf("✅ Imported settings for %d forum(s).", len(p.forums))
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{Content: content, Components: []discordgo.MessageComponent{}},
+	})
+	if err != nil {
+		log.Printf("failed to respond to interaction: %v", err)
+	}
+}
+
+func forumName(forums []*discordgo.Channel, id string) string {
+	for _, f := range forums {
+		if f.ID == id {
+			return f.Name
+		}
+	}
+	return id
+}
+
+// lineDiff returns a minimal line diff of a and b in unified style ("-" removed, "+" added)
+func lineDiff(a, b string) string {
+	al, bl := strings.Split(strings.TrimRight(a, "\n"), "\n"), strings.Split(strings.TrimRight(b, "\n"), "\n")
+	if a == "" {
+		al = nil
+	}
+	if b == "" {
+		bl = nil
+	}
+	// lcs[i][j] is the length of the longest common subsequence of al[i:] and bl[j:]
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	sb := &strings.Builder{}
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			i, j = i+1, j+1
+		case i < len(al) && (j == len(bl) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + al[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + bl[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"both empty", "", "", ""},
+		{"from nothing", "", "a\nb\n", "+ a\n+ b\n"},
+		{"to nothing", "a\n", "", "- a\n"},
+		{"changed line", "a\nb\nc\n", "a\nx\nc\n", "- b\n+ x\n"},
+		{"added line", "a\nc\n", "a\nb\nc\n", "+ b\n"},
+		{"removed line", "a\nb\nc\n", "a\nc\n", "- b\n"},
+		{"trailing newline ignored", "a\nb", "a\nb\n", ""},
+		{"moved line", "a\nb\nc\n", "b\nc\na\n", "- a\n+ a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineDiff(tt.a, tt.b); got != tt.want {
+				t.Errorf("lineDiff(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
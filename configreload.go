@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// configPollInterval is how often config.yaml is checked for changes
+const configPollInterval = 5 * time.Second
+
+// cfg returns the live configuration. Callers that read several settings should keep the
+// returned pointer instead of calling cfg again, so a reload in between cannot mix two versions.
+func (h *handler) cfg() *Config {
+	return h.liveCfg.Load()
+}
+
+// watchConfig reloads the configuration on SIGHUP and when the file at path changes. The
+// returned function stops watching.
+func (h *handler) watchConfig(path string) func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+		last := configFileVersion(path)
+		for {
+			select {
+			case <-done:
+				return
+			case <-hup:
+				log.Printf("config: SIGHUP received, reloading %s", path)
+			case <-ticker.C:
+				if configFileVersion(path) == last {
+					continue
+				}
+				log.Printf("config: %s changed, reloading", path)
+			}
+			last = configFileVersion(path)
+			if err := h.reloadConfig(path); err != nil {
+				log.Printf("config: reload failed, keeping the current configuration: %v", err)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(done)
+	}
+}
+
+// configFileVersion identifies the content of the file at path by modification time and size
+func configFileVersion(path string) string {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", fi.ModTime(), fi.Size())
+}
+
+// reloadConfig reads the configuration at path and swaps it in as a whole, together with the
+// watched forums and the slash command table. Settings used only while starting up keep their
+// old values until the next restart.
+func (h *handler) reloadConfig(path string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	old := h.cfg()
+	var restart []string
+	for name, changed := range map[string]bool{
+		"discord_token":     cfg.DiscordToken != old.DiscordToken,
+		"store":             cfg.StoreBackend != old.StoreBackend,
+		"database_url":      cfg.DatabaseURL != old.DatabaseURL,
+		"cache":             cfg.Cache != old.Cache || cfg.RedisURL != old.RedisURL || cfg.RedisPrefix != old.RedisPrefix,
+		"data_path":         cfg.DataPath != old.DataPath,
+		"thread_index_path": cfg.ThreadIndexPath != old.ThreadIndexPath,
+		"overrides_path":    cfg.OverridesPath != old.OverridesPath,
+		"archive":           !reflect.DeepEqual(cfg.Archive, old.Archive),
+		"jobs":              !reflect.DeepEqual(cfg.Jobs, old.Jobs),
+		"timezone":          cfg.Timezone != old.Timezone,
+	} {
+		if changed {
+			restart = append(restart, name)
+		}
+	}
+	if len(restart) > 0 {
+		log.Printf("config: changes to %s take effect after a restart", strings.Join(restart, ", "))
+	}
+	// startup-only settings keep their running values so the bot stays consistent
+	cfg.DiscordToken, cfg.StoreBackend, cfg.DataPath, cfg.ThreadIndexPath = old.DiscordToken, old.StoreBackend, old.DataPath, old.ThreadIndexPath
+	cfg.DatabaseURL, cfg.OverridesPath, cfg.Archive, cfg.Jobs = old.DatabaseURL, old.OverridesPath, old.Archive, old.Jobs
+	cfg.Timezone, cfg.location = old.Timezone, old.location
+	cfg.Cache, cfg.RedisURL, cfg.RedisPrefix = old.Cache, old.RedisURL, old.RedisPrefix
+
+	// the overrides file may have been edited by hand too; a broken one keeps the running overrides
+	h.overridesMu.Lock()
+	overrides, err := loadOverrides(cfg.OverridesPath)
+	watched := cfg.watchedParents()
+	h.mu.Lock()
+	if err != nil {
+		log.Printf("config: keeping the current runtime overrides: %v", err)
+		overrides = h.overrides
+	}
+	next, err := overrides.apply(cfg, watched)
+	if err != nil && overrides != h.overrides {
+		log.Printf("config: keeping the current runtime overrides: %v", err)
+		overrides = h.overrides
+		next, err = overrides.apply(cfg, watched)
+	}
+	if err != nil {
+		h.mu.Unlock()
+		h.overridesMu.Unlock()
+		return err
+	}
+	cfg = next
+	h.overrides = overrides
+	h.watchedParents = watched
+	h.liveCfg.Store(cfg)
+	h.mu.Unlock()
+	h.overridesMu.Unlock()
+
+	if cfg.SearchCache.ttl != old.SearchCache.ttl || cfg.SearchCache.Size != old.SearchCache.Size {
+		searchCache.configure(cfg.SearchCache.ttl, cfg.SearchCache.Size)
+	}
+	aniListToken.Store(cfg.AniListToken)
+	// search_in_dms decides which slash commands are offered in DMs
+	if cfg.SearchInDMs != old.SearchInDMs && h.dg.State != nil && h.dg.State.User != nil {
+		h.registerCommands(h.dg, h.dg.State.User.ID)
+		if cfg.SearchInDMs && h.dg.Identify.Intents&discordgo.IntentsDirectMessages == 0 {
+			log.Printf("config: search_in_dms enables the slash commands in DMs now; lookups in DM messages need a restart")
+		}
+	}
+	log.Printf("config: reloaded %s (watching %d forum parents)", path, len(watched))
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// permissionNames are the values accepted by allowed_permissions
+var permissionNames = map[string]int64{
+	"ADMINISTRATOR":   discordgo.PermissionAdministrator,
+	"MANAGE_CHANNELS": discordgo.PermissionManageChannels,
+	"MANAGE_ROLES":    discordgo.PermissionManageRoles,
+	"MANAGE_MESSAGES": discordgo.PermissionManageMessages,
+}
+
+// unknownFieldRe matches yaml.v3's error for a setting the target type does not have
+var unknownFieldRe = regexp.MustCompile(`^line (\d+): field (\S+) not found in type (\S+)$`)
+
+// decodeConfigStrict decodes doc, the parsed configuration file b, into cfg and returns one error
+// for every setting Config does not know, naming the closest known setting when it looks like a
+// typo, and for every value of the wrong type. Known settings are decoded either way; err is set
+// when the file cannot be decoded at all.
+func decodeConfigStrict(b []byte, doc *yaml.Node, cfg *Config) (problems []error, err error) {
+	if doc.Kind == 0 {
+		// an empty file
+		return nil, nil
+	}
+	// a node cannot be decoded strictly, so unknown settings are looked for in the text as
+	// written; the values are decoded from doc, which may have ${VAR} references expanded
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	err = dec.Decode(&Config{})
+	var te *yaml.TypeError
+	if err != nil && err != io.EOF && !errors.As(err, &te) {
+		return nil, err
+	}
+	if te != nil {
+		for _, msg := range te.Errors {
+			m := unknownFieldRe.FindStringSubmatch(msg)
+			if m == nil {
+				continue
+			}
+			msg = fmt.Sprintf("line %s: unknown setting %s", m[1], m[2])
+			if s := closestSetting(m[2], configSettings()[m[3]]); s != "" {
+				msg += fmt.Sprintf(" (did you mean %s?)", s)
+			}
+			problems = append(problems, errors.New(msg))
+		}
+	}
+	if err := doc.Decode(cfg); err != nil {
+		te = nil
+		if !errors.As(err, &te) {
+			return nil, err
+		}
+		for _, msg := range te.Errors {
+			problems = append(problems, errors.New(msg))
+		}
+	}
+	return problems, nil
+}
+
+// closestSetting returns the known setting most similar to name, if any is close enough
+func closestSetting(name string, known []string) string {
+	q := trigrams(strings.ReplaceAll(name, "_", ""))
+	best, bestScore := "", 0.5
+	for _, k := range known {
+		if score := jaccard(q, trigrams(strings.ReplaceAll(k, "_", ""))); score > bestScore {
+			best, bestScore = k, score
+		}
+	}
+	return best
+}
+
+var configSettingsOnce struct {
+	sync.Once
+	byType map[string][]string
+}
+
+// configSettings returns the YAML keys of Config and of every struct nested in it, keyed by type
+// name as yaml.v3 reports it ("main.ForumConfig")
+func configSettings() map[string][]string {
+	configSettingsOnce.Do(func() {
+		configSettingsOnce.byType = map[string][]string{}
+		var walk func(t reflect.Type)
+		walk = func(t reflect.Type) {
+			switch t.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map:
+				walk(t.Elem())
+				return
+			case reflect.Struct:
+			default:
+				return
+			}
+			if _, seen := configSettingsOnce.byType[t.String()]; seen {
+				return
+			}
+			var keys []string
+			configSettingsOnce.byType[t.String()] = keys
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+				if !f.IsExported() || name == "" || name == "-" {
+					continue
+				}
+				keys = append(keys, name)
+				walk(f.Type)
+			}
+			sort.Strings(keys)
+			configSettingsOnce.byType[t.String()] = keys
+		}
+		walk(reflect.TypeOf(Config{}))
+	})
+	return configSettingsOnce.byType
+}
+
+// checkFields validates the settings that are lists of IDs and names, returning one error per
+// bad entry so all of them can be fixed at once
+func (cfg *Config) checkFields() []error {
+	var errs []error
+	blank := func(field string, ids []string) {
+		for i, id := range ids {
+			if strings.TrimSpace(id) == "" {
+				errs = append(errs, fmt.Errorf("%s[%d]: empty ID", field, i))
+			}
+		}
+	}
+	perms := func(field string, names []string) {
+		for i, name := range names {
+			if _, ok := permissionNames[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s[%d]: unknown permission %q (use ADMINISTRATOR, MANAGE_CHANNELS, MANAGE_ROLES or MANAGE_MESSAGES)", field, i, name))
+			}
+		}
+	}
+	blank("forum_parent_ids", cfg.ForumParentIDs)
+	blank("allowed_role_ids", cfg.AllowedRoleIDs)
+	blank("search_channels", cfg.SearchChannels)
+	perms("allowed_permissions", cfg.AllowedPermissions)
+	for id := range cfg.Forums {
+		if strings.TrimSpace(id) == "" {
+			errs = append(errs, errors.New("forums: empty forum ID"))
+		}
+	}
+	for id, g := range cfg.Guilds {
+		if strings.TrimSpace(id) == "" {
+			errs = append(errs, errors.New("guilds: empty guild ID"))
+		}
+		blank("guilds."+id+".forum_parent_ids", g.ForumParentIDs)
+		blank("guilds."+id+".allowed_role_ids", g.AllowedRoleIDs)
+		blank("guilds."+id+".search_channels", g.SearchChannels)
+		perms("guilds."+id+".allowed_permissions", g.AllowedPermissions)
+	}
+	return errs
+}
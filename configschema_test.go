@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+func TestDecodeConfigStrict(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		want      []string
+		wantStyle string
+	}{
+		{"empty file", "", nil, ""},
+		{"known settings", "status_style: emoji\nforum_parent_ids: [\"1\"]", nil, "emoji"},
+		{"typo", "status_styel: emoji", []string{"line 1: unknown setting status_styel (did you mean status_style?)"}, ""},
+		{"unknown setting", "status_style: emoji\nflavour: 1", []string{"line 2: unknown setting flavour"}, "emoji"},
+		{"nested typo", "forums:\n  \"1\":\n    auto_close_aftr: 48h", []string{"line 3: unknown setting auto_close_aftr (did you mean auto_close_after?)"}, ""},
+		{"every unknown setting", "statusstyle: emoji\ncommand_prefix: [\"!\"]", []string{
+			"line 1: unknown setting statusstyle (did you mean status_style?)",
+			"line 2: unknown setting command_prefix (did you mean command_prefixes?)",
+		}, ""},
+		{"wrong type", "status_style: emoji\nforum_parent_ids: {a: b}", []string{"line 2: cannot unmarshal !!map into []string"}, "emoji"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			if err := yaml.Unmarshal([]byte(tt.src), &doc); err != nil {
+				t.Fatal(err)
+			}
+			var cfg Config
+			problems, err := decodeConfigStrict([]byte(tt.src), &doc, &cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range problems {
+				got = append(got, p.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+			if cfg.StatusStyle != tt.wantStyle {
+				t.Errorf("status_style = %q, want %q", cfg.StatusStyle, tt.wantStyle)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// errStoreUnavailable is returned by every store operation while the bot runs without storage
+var errStoreUnavailable = errors.New("persistent store is unavailable")
+
+const (
+	storeRetryMin = 5 * time.Second
+	storeRetryMax = 2 * time.Minute
+)
+
+// statefulCommands are the dot and slash commands that cannot work without the store. They are
+// refused with a notice while the bot runs degraded; status commands keep working.
+var statefulCommands = map[string]bool{}
+
+func requireStore(names ...string) {
+	for _, n := range names {
+		statefulCommands[n] = true
+	}
+}
+
+// recoveringStore wraps the real store. When it cannot be opened at startup the bot keeps running
+// stateless and the store is reopened in the background until it succeeds.
+type recoveringStore struct {
+	mu    sync.RWMutex
+	inner Store
+	// onRecover is called once the store becomes available after a failed open
+	onRecover func()
+}
+
+// openRecoveringStore opens the store with open. On failure it logs the error, returns a degraded
+// store immediately and keeps retrying with backoff.
+func openRecoveringStore(open func() (Store, error)) *recoveringStore {
+	rs := &recoveringStore{}
+	inner, err := open()
+	if err == nil {
+		rs.inner = inner
+		return rs
+	}
+	log.Printf("store: failed to open, starting in degraded mode without persistence: %v", err)
+	go func() {
+		wait := storeRetryMin
+		for {
+			time.Sleep(wait)
+			inner, err := open()
+			if err != nil {
+				if wait *= 2; wait > storeRetryMax {
+					wait = storeRetryMax
+				}
+				log.Printf("store: still unavailable, retrying in %s: %v", wait, err)
+				continue
+			}
+			rs.mu.Lock()
+			rs.inner = inner
+			cb := rs.onRecover
+			rs.mu.Unlock()
+			log.Printf("store: available again, leaving degraded mode")
+			if cb != nil {
+				cb()
+			}
+			return
+		}
+	}()
+	return rs
+}
+
+// OnRecover sets the function called when the store becomes available after a failed open
+func (rs *recoveringStore) OnRecover(fn func()) {
+	rs.mu.Lock()
+	rs.onRecover = fn
+	rs.mu.Unlock()
+}
+
+// Available reports whether the store is usable
+func (rs *recoveringStore) Available() bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	return rs.inner != nil
+}
+
+func (rs *recoveringStore) get() (Store, error) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	if rs.inner == nil {
+		return nil, errStoreUnavailable
+	}
+	return rs.inner, nil
+}
+
+func (rs *recoveringStore) Get(bucket, key string, v interface{}) (bool, error) {
+	st, err := rs.get()
+	if err != nil {
+		return false, err
+	}
+	return st.Get(bucket, key, v)
+}
+
+func (rs *recoveringStore) Put(bucket, key string, v interface{}) error {
+	st, err := rs.get()
+	if err != nil {
+		return err
+	}
+	return st.Put(bucket, key, v)
+}
+
+func (rs *recoveringStore) Delete(bucket, key string) error {
+	st, err := rs.get()
+	if err != nil {
+		return err
+	}
+	return st.Delete(bucket, key)
+}
+
+func (rs *recoveringStore) List(bucket string) (map[string]json.RawMessage, error) {
+	st, err := rs.get()
+	if err != nil {
+		return nil, err
+	}
+	return st.List(bucket)
+}
+
+func (rs *recoveringStore) Close() error {
+	st, err := rs.get()
+	if err != nil {
+		return nil
+	}
+	return st.Close()
+}
+
+// storeDegraded reports whether the bot is running without its persistent store
+func (h *handler) storeDegraded() bool {
+	rs, ok := h.store.(*recoveringStore)
+	return ok && !rs.Available()
+}
+
+// degradedNotice is shown when a stateful command is used while the store is unavailable
+const degradedNotice = "⚠️ The bot's storage is currently unavailable, so this command is disabled. Status commands still work; an administrator has been notified in the logs."
+
+// onStoreRecovered persists state collected while degraded and tells the ops channel
+func (h *handler) onStoreRecovered() {
+	// watched forums of earlier versions could not be moved to the overrides file at startup
+	h.overridesMu.Lock()
+	h.mu.Lock()
+	if migrateStoredOverrides(h.store, h.overrides, h.cfg().OverridesPath) {
+		for _, id := range h.overrides.WatchedForums {
+			if _, ok := h.watchedParents[id]; !ok {
+				h.watchedParents[id] = ""
+			}
+		}
+	}
+	h.mu.Unlock()
+	h.overridesMu.Unlock()
+	// jobs scheduled before the restart were not loaded at startup
+	h.sched.load()
+	h.sched.PersistAll()
+	if h.cfg().HeartbeatChannelID != "" {
+		sendMessage(h.dg, h.cfg().HeartbeatChannelID, "✅ Storage is available again; all features are back.")
+	}
+}
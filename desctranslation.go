@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Translation services supported by description_translation
+const (
+	translatorDeepL          = "deepl"
+	translatorLibreTranslate = "libretranslate"
+)
+
+// maxTranslations bounds descTranslations, the cache of translated descriptions
+const maxTranslations = 1000
+
+var descTranslations = struct {
+	sync.Mutex
+	cache map[string]string
+}{cache: map[string]string{}}
+
+// mediaEmbed builds the search result embed of a title: the description in the configured
+// language and, for manga, where to read it
+func (h *handler) mediaEmbed(tr localizer, media *aniListMedia, mediaType string) *discordgo.MessageEmbed {
+	if translated := h.translateDescription(media.Desc); translated != "" {
+		m := *media
+		m.Desc = translated
+		media = &m
+	}
+	emb := media.toEmbed(tr)
+	if mediaType == "MANGA" {
+		h.addWhereToRead(tr, emb, media)
+	}
+	return emb
+}
+
+// translateDescription returns text translated into description_translation.target_language,
+// or "" when translation is off or failed (the original is shown then)
+func (h *handler) translateDescription(text string) string {
+	cfg := h.cfg().DescriptionTranslation
+	if cfg.Service == "" || strings.TrimSpace(text) == "" {
+		return ""
+	}
+	key := cfg.TargetLanguage + "\x00" + text
+	descTranslations.Lock()
+	cached, ok := descTranslations.cache[key]
+	descTranslations.Unlock()
+	if ok {
+		return cached
+	}
+
+	var translated string
+	var err error
+	switch cfg.Service {
+	case translatorDeepL:
+		translated, err = translateDeepL(cfg, text)
+	case translatorLibreTranslate:
+		translated, err = translateLibre(cfg, text)
+	}
+	if err != nil {
+		log.Printf("translate: %s failed: %v", cfg.Service, err)
+		return ""
+	}
+
+	descTranslations.Lock()
+	if len(descTranslations.cache) >= maxTranslations {
+		descTranslations.cache = map[string]string{}
+	}
+	descTranslations.cache[key] = translated
+	descTranslations.Unlock()
+	return translated
+}
+
+// translateDeepL uses the DeepL API; keys of free accounts end in ":fx" and use the free endpoint
+func translateDeepL(cfg TranslationConfig, text string) (string, error) {
+	url := cfg.URL
+	if url == "" {
+		url = "https://api.deepl.com"
+		if strings.HasSuffix(cfg.APIKey, ":fx") {
+			url = "https://api-free.deepl.com"
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"text": []string{text}, "target_lang": strings.ToUpper(cfg.TargetLanguage)})
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	headers := map[string]string{"Content-Type": "application/json", "Authorization": "DeepL-Auth-Key " + cfg.APIKey}
+	if err := requestJSON("POST", strings.TrimRight(url, "/")+"/v2/translate", headers, bytes.NewReader(body), &res); err != nil {
+		return "", err
+	}
+	if len(res.Translations) == 0 {
+		return "", fmt.Errorf("no translation returned")
+	}
+	return res.Translations[0].Text, nil
+}
+
+// translateLibre uses a LibreTranslate instance
+func translateLibre(cfg TranslationConfig, text string) (string, error) {
+	payload := map[string]string{"q": text, "source": "auto", "target": strings.ToLower(cfg.TargetLanguage), "format": "text"}
+	if cfg.APIKey != "" {
+		payload["api_key"] = cfg.APIKey
+	}
+	var res struct {
+		TranslatedText string `json:"translatedText"`
+	}
+	if err := postJSON(strings.TrimRight(cfg.URL, "/")+"/translate", payload, &res); err != nil {
+		return "", err
+	}
+	if res.TranslatedText == "" {
+		return "", fmt.Errorf("no translation returned")
+	}
+	return res.TranslatedText, nil
+}
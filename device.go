@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// androidVersionRe matches "Android 14", "android version: 13.0", "Android13"
+var androidVersionRe = regexp.MustCompile(`(?i)\bandroid\s*(?:version\s*:?\s*)?(\d{1,2})(?:\.\d+)*\b`)
+
+// extractAndroidVersion returns the major Android version reported in text, or ""
+func extractAndroidVersion(text string) string {
+	if m := androidVersionRe.FindStringSubmatch(text); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// detectDevice returns the Android major version and the names of matching device rules
+func detectDevice(fc ForumConfig, text string) (string, []DeviceRule) {
+	var matched []DeviceRule
+	for _, r := range fc.DeviceRules {
+		if r.re != nil && r.re.MatchString(text) {
+			matched = append(matched, r)
+		}
+	}
+	return extractAndroidVersion(text), matched
+}
+
+// applyDeviceInfo tags a new post with its Android version and device family according to the
+// forum's mapping and, if enabled, posts a short note with what was detected
+func (h *handler) applyDeviceInfo(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message, fc ForumConfig) {
+	android, devices := detectDevice(fc, th.Name+"\n"+starter.Content)
+	if android == "" && len(devices) == 0 {
+		return
+	}
+	var tags, labels []string
+	if android != "" {
+		labels = append(labels, "Android "+android)
+		if t, ok := fc.AndroidVersionTags[android]; ok {
+			tags = append(tags, t)
+		}
+	}
+	for _, d := range devices {
+		labels = append(labels, d.Name)
+		if d.Tag != "" {
+			tags = append(tags, d.Tag)
+		}
+	}
+	if len(tags) > 0 {
+		if _, err := editThreadTags(s, th, tags, nil); err != nil {
+			log.Printf("device: failed to tag %s with %v: %v", th.ID, tags, err)
+		}
+	}
+	if fc.AnnotateDevice {
+		sendMessage(s, th.ID, fmt.Sprintf("📱 Detected environment: %s", strings.Join(labels, " · ")))
+	}
+}
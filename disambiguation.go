@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// searchCandidates is how many matches are offered when a search is ambiguous
+	searchCandidates = 5
+	// candidateTTL is how long the select menu of an ambiguous search keeps working
+	candidateTTL = 30 * time.Minute
+)
+
+// candidateSet is an ambiguous search result awaiting the requester's choice
+type candidateSet struct {
+	requesterID string
+	tr          localizer
+	mediaType   string
+	media       []*aniListMedia
+	created     time.Time
+}
+
+var pendingCandidates = struct {
+	sync.Mutex
+	items map[string]*candidateSet
+}{items: map[string]*candidateSet{}}
+
+func init() {
+	registerComponentHandler("pick:", (*handler).handleCandidatePick)
+}
+
+// ambiguous reports whether the best match of a search is uncertain enough to offer the others:
+// there are several matches and none of the best match's titles equals the query
+func ambiguous(query string, results []*aniListMedia) bool {
+	if len(results) < 2 {
+		return false
+	}
+	for _, t := range append([]string{results[0].Title}, results[0].AltTitles...) {
+		if strings.EqualFold(strings.TrimSpace(t), strings.TrimSpace(query)) {
+			return false
+		}
+	}
+	return true
+}
+
+// lowConfidence reports whether a best match falls below search_min_popularity or
+// search_min_score, suggesting a short query hit an obscure title
+func (h *handler) lowConfidence(m *aniListMedia) bool {
+	return h.cfg().SearchMinPopularity > 0 && m.Popularity > 0 && m.Popularity < h.cfg().SearchMinPopularity ||
+		h.cfg().SearchMinScore > 0 && m.Score > 0 && m.Score < h.cfg().SearchMinScore
+}
+
+// sendWithCandidates posts the embed of the best match with a select menu of the other
+// candidates in reply to the search m; only its author can switch the result
+func (h *handler) sendWithCandidates(s *discordgo.Session, tr localizer, m *discordgo.Message, mediaType string, results []*aniListMedia, first *discordgo.MessageEmbed, files []*discordgo.File) (*discordgo.Message, error) {
+	pendingCandidates.Lock()
+	for k, v := range pendingCandidates.items {
+		if time.Since(v.created) > candidateTTL {
+			delete(pendingCandidates.items, k)
+		}
+	}
+	key := strconv.FormatInt(time.Now().UnixNano(), 36)
+	pendingCandidates.items[key] = &candidateSet{requesterID: m.Author.ID, tr: tr, mediaType: mediaType, media: results, created: time.Now()}
+	pendingCandidates.Unlock()
+
+	return s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{first},
+		Components: append(candidateMenu(tr, key, results), trailerButtons(tr, results[0])...),
+		Files:      files,
+	}, m))
+}
+
+func candidateMenu(tr localizer, key string, results []*aniListMedia) []discordgo.MessageComponent {
+	options := make([]discordgo.SelectMenuOption, 0, len(results))
+	for n, m := range results {
+		desc := humanizeEnum(m.Format)
+		if len(m.StartDate) >= 4 {
+			desc = strings.TrimSpace(desc + " " + m.StartDate[:4])
+		}
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       truncateRunes(m.Title, 100),
+			Value:       strconv.Itoa(n),
+			Description: desc,
+			Default:     n == 0,
+		})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.SelectMenu{CustomID: "pick:" + key, Placeholder: tr.T("search.pick"), Options: options},
+	}}}
+}
+
+// handleCandidatePick swaps the result of an ambiguous search for the match the requester picked
+func (h *handler) handleCandidatePick(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	key := strings.TrimPrefix(data.CustomID, "pick:")
+	pendingCandidates.Lock()
+	set, ok := pendingCandidates.items[key]
+	pendingCandidates.Unlock()
+	if !ok || len(data.Values) == 0 {
+		respondEphemeral(s, i, h.channelLocalizer(s, i.ChannelID).T("search.pick_expired"))
+		return
+	}
+	user := interactionUser(i)
+	if user == nil || user.ID != set.requesterID {
+		respondEphemeral(s, i, set.tr.T("search.pick_not_yours"))
+		return
+	}
+	n, err := strconv.Atoi(data.Values[0])
+	if err != nil || n < 0 || n >= len(set.media) {
+		return
+	}
+	pendingCandidates.Lock()
+	delete(pendingCandidates.items, key)
+	pendingCandidates.Unlock()
+	emb := h.mediaEmbed(set.tr, set.media[n], set.mediaType)
+	// the spoilered cover of the previous pick, if any, is replaced as well
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:      []*discordgo.MessageEmbed{emb},
+			Components:  append([]discordgo.MessageComponent{}, trailerButtons(set.tr, set.media[n])...),
+			Files:       h.adultCoverFiles([]*discordgo.MessageEmbed{emb}, set.media[n:n+1]),
+			Attachments: &[]*discordgo.MessageAttachment{},
+		},
+	})
+	if err != nil {
+		log.Printf("search: failed to swap result: %v", err)
+	}
+}
+
+// describeCandidates lists the candidate titles for logs
+func describeCandidates(results []*aniListMedia) string {
+	titles := make([]string, len(results))
+	for n, m := range results {
+		titles[n] = fmt.Sprintf("%q", m.Title)
+	}
+	return strings.Join(titles, ", ")
+}
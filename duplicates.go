@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// duplicateCandidate is an existing thread similar to a new post
+type duplicateCandidate struct {
+	thread indexedThread
+	score  float64
+}
+
+// findSimilarThreads scores the indexed threads of the post's forum against a new post and
+// returns up to limit candidates above the threshold, best first
+func (h *handler) findSimilarThreads(s *discordgo.Session, th *discordgo.Channel, body string, threshold float64, limit int) []duplicateCandidate {
+	h.ensureForumIndexed(s, th.GuildID, th.ParentID)
+	titleTokens := tokenize(stripStatusPrefixes(th.Name))
+	// the body adds context but titles carry most of the signal; weigh the title match higher
+	bodyTokens := tokenize(th.Name + " " + body)
+	var out []duplicateCandidate
+	for _, other := range h.index.lookup([]string{th.ParentID}, bodyTokens, false) {
+		if other.ID == th.ID {
+			continue
+		}
+		otherTokens := other.titleTokens
+		score := 0.7*jaccard(titleTokens, otherTokens) + 0.3*jaccard(bodyTokens, otherTokens)
+		if score >= threshold {
+			out = append(out, duplicateCandidate{thread: other, score: score})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].score > out[j].score })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// suggestDuplicates replies to a new post with links to possibly related reports and a button
+// moderators can use to mark the post as a duplicate
+func (h *handler) suggestDuplicates(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message, fc ForumConfig) {
+	threshold := fc.DuplicateThreshold
+	if threshold <= 0 {
+		threshold = 0.35
+	}
+	candidates := h.findSimilarThreads(s, th, starter.Content, threshold, 3)
+	if len(candidates) == 0 {
+		return
+	}
+	tr := h.localizer(th.GuildID, th.ID, th.ParentID)
+	sb := &strings.Builder{}
+	sb.WriteString(tr.T("duplicates.related") + "\n")
+	for _, c := range candidates {
+		status := ""
+		if cmd := h.cfg().forGuild(th.GuildID).statusFromTitle(c.thread.Title); cmd != "" {
+			status = " " + commandConfig[cmd].Prefix
+		}
+		sb.WriteString(fmt.Sprintf("- <#%s>%s\n", c.thread.ID, status))
+	}
+	sb.WriteString(tr.T("duplicates.check_first"))
+	_, err := s.ChannelMessageSendComplex(th.ID, &discordgo.MessageSend{
+		Content: sb.String(),
+		Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			// handled by the triage panel's button handler, so only moderators can use it
+			discordgo.Button{Label: tr.T("duplicates.mark"), Style: discordgo.SecondaryButton, CustomID: "triage:duplicate"},
+		}}},
+	})
+	if err != nil {
+		log.Printf("duplicates: failed to post suggestions in %s: %v", th.ID, err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const issueLinksBucket = "issue_links"
+
+// issueLink links a thread to the GitHub issue filed for it by `.escalate` or pasted into it
+type issueLink struct {
+	Repo     string    `json:"repo"`
+	Number   int       `json:"number"`
+	URL      string    `json:"url"`
+	LinkedBy string    `json:"linked_by"`
+	LinkedAt time.Time `json:"linked_at"`
+	// Closed is set once the issue sync has announced that the issue was closed
+	Closed bool `json:"closed,omitempty"`
+}
+
+func init() {
+	registerThreadCommand("escalate", (*handler).handleEscalate)
+	requireStore("escalate")
+}
+
+// handleEscalate implements `.escalate [extra notes]`: file an issue in escalation_repo with the
+// thread's title, first post, reported app version and a link back, then post the issue URL
+func (h *handler) handleEscalate(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, args string) {
+	repo := h.cfg().EscalationRepo
+	if repo == "" || h.cfg().GitHubToken == "" {
+		replyMessage(s, m.Message, "Escalation is not configured (needs `escalation_repo` and `github_token`).")
+		return
+	}
+	var prev issueLink
+	if found, err := h.store.Get(issueLinksBucket, ch.ID, &prev); err == nil && found {
+		replyMessage(s, m.Message, fmt.Sprintf("This thread is already linked to %s", prev.URL))
+		return
+	}
+	starter, err := s.ChannelMessage(ch.ID, ch.ID)
+	if err != nil {
+		log.Printf("escalate: failed to fetch starter message of %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, "❌ Could not read the first post of this thread.")
+		return
+	}
+
+	version := "unknown"
+	if vs := extractVersions(ch.Name + "\n" + starter.Content); len(vs) > 0 {
+		version = vs[0]
+	}
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s", ch.GuildID, ch.ID)
+	body := &strings.Builder{}
+	fmt.Fprintf(body, "Reported on Discord: %s\n\n", link)
+	fmt.Fprintf(body, "**App version:** %s\n", version)
+	if av := extractAndroidVersion(starter.Content); av != "" {
+		fmt.Fprintf(body, "**Android:** %s\n", av)
+	}
+	body.WriteString("\n### Report\n\n")
+	body.WriteString(starter.Content)
+	for _, a := range starter.Attachments {
+		fmt.Fprintf(body, "\n\n[%s](%s)", a.Filename, a.URL)
+	}
+	if args != "" {
+		fmt.Fprintf(body, "\n\n### Notes from the moderator\n\n%s", args)
+	}
+
+	req := map[string]interface{}{"title": stripStatusPrefixes(ch.Name), "body": body.String()}
+	if len(h.cfg().EscalationLabels) > 0 {
+		req["labels"] = h.cfg().EscalationLabels
+	}
+	var issue struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	resp, err := githubRequest(context.Background(), h.cfg().GitHubToken, "POST", "/repos/"+repo+"/issues", req, &issue)
+	if err == nil && resp.StatusCode == 404 {
+		err = fmt.Errorf("repository %s not found or token lacks access", repo)
+	}
+	if err != nil {
+		log.Printf("escalate: failed to create issue for %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, "❌ Failed to create the GitHub issue. Check the logs.")
+		return
+	}
+
+	rec := issueLink{Repo: repo, Number: issue.Number, URL: issue.HTMLURL, LinkedBy: m.Author.ID, LinkedAt: time.Now()}
+	if err := h.store.Put(issueLinksBucket, ch.ID, rec); err != nil {
+		log.Printf("escalate: failed to record issue for %s: %v", ch.ID, err)
+	}
+	log.Printf("escalate: thread %s filed as %s#%d by %s", ch.ID, repo, issue.Number, m.Author.ID)
+	replyMessage(s, m.Message, fmt.Sprintf("📌 Escalated to GitHub: %s", issue.HTMLURL))
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// findMatch is a thread matching a `.find` query
+type findMatch struct {
+	thread indexedThread
+	score  float64
+}
+
+// watchedForumIDs returns the watched forum parents of a guild, or all of its forums when none are configured
+func (h *handler) watchedForumIDs(s *discordgo.Session, guildID string) []string {
+	if ids := h.watchedParentIDs(guildID); len(ids) > 0 {
+		return ids
+	}
+	var out []string
+	channels, err := s.GuildChannels(guildID)
+	if err != nil {
+		log.Printf("failed to list channels of guild %s: %v", guildID, err)
+		return nil
+	}
+	for _, ch := range channels {
+		if ch.Type == discordgo.ChannelTypeGuildForum {
+			out = append(out, ch.ID)
+		}
+	}
+	return out
+}
+
+// scoreFind returns the share of query tokens found in the candidate tokens (prefix matches count)
+func scoreFind(query, candidate []string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	hits := 0
+	for _, q := range query {
+		for _, c := range candidate {
+			if strings.HasPrefix(c, q) {
+				hits++
+				break
+			}
+		}
+	}
+	return float64(hits) / float64(len(query))
+}
+
+// starterContent returns the first message of a thread, using the thread index when possible
+func (h *handler) starterContent(s *discordgo.Session, threadID string) (string, bool) {
+	if content, ok := h.index.body(threadID); ok {
+		return content, true
+	}
+	msg, err := s.ChannelMessage(threadID, threadID)
+	if err != nil {
+		return "", false
+	}
+	h.cacheStarter(threadID, msg.Content)
+	return msg.Content, true
+}
+
+func (h *handler) cacheStarter(threadID, content string) {
+	h.index.setBody(threadID, content)
+}
+
+// handleFind implements `.find <query>`: search thread titles (and optionally first posts) across
+// the watched forums and reply with a paginated list of matches and their tags
+func (h *handler) handleFind(s *discordgo.Session, m *discordgo.MessageCreate, query string) {
+	if m.GuildID == "" {
+		return
+	}
+	qTokens := tokenize(query)
+	if len(qTokens) == 0 {
+		replyMessage(s, m.Message, "usage: "+h.cfg().forGuild(m.GuildID).command("find")+" <words from the thread title>")
+		return
+	}
+
+	var matches []findMatch
+	tagNames := map[string]string{}
+	forumIDs := h.watchedForumIDs(s, m.GuildID)
+	for _, forumID := range forumIDs {
+		h.ensureForumIndexed(s, m.GuildID, forumID)
+		if tags, err := fetchForumTags(s, forumID); err == nil {
+			for _, t := range tags {
+				tagNames[t.ID] = t.Name
+			}
+		}
+	}
+	for _, th := range h.index.lookup(forumIDs, qTokens, true) {
+		score := scoreFind(qTokens, th.titleTokens)
+		if score < 1 && h.cfg().FindSearchBodies {
+			if bs := 0.8 * scoreFind(qTokens, th.bodyTokens); bs > score {
+				score = bs
+			}
+		}
+		// require at least half of the query words
+		if score >= 0.5 {
+			matches = append(matches, findMatch{thread: th, score: score})
+		}
+	}
+	if len(matches) == 0 {
+		replyMessage(s, m.Message, fmt.Sprintf("No threads found for %q.", query))
+		return
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	lines := make([]string, 0, len(matches))
+	for _, fm := range matches {
+		var tags []string
+		for _, id := range fm.thread.Tags {
+			if name, ok := tagNames[id]; ok {
+				tags = append(tags, name)
+			}
+		}
+		line := fmt.Sprintf("<#%s>", fm.thread.ID)
+		if len(tags) > 0 {
+			line += " — " + strings.Join(tags, ", ")
+		}
+		lines = append(lines, line)
+	}
+	title := fmt.Sprintf("Threads matching %q (%d)", query, len(matches))
+	if err := sendPaged(s, m.ChannelID, embedPages(title, lines, 10)); err != nil {
+		log.Printf("find: failed to send results: %v", err)
+	}
+}
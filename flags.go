@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Log levels of --log-level. Lines are not tagged with a level; debug lines are those starting
+// with "debug:" and error lines those reporting a failure, as counted by the heartbeat.
+const (
+	logDebug = "debug"
+	logInfo  = "info" // everything but debug lines (default)
+	logError = "error"
+)
+
+// cliOptions holds the command line switches
+type cliOptions struct {
+	configPath string
+	dryRun     bool
+	validate   bool
+	live       bool
+	logLevel   string
+}
+
+// dryRun is set by --dry-run: the bot connects and handles events, but nothing is changed on
+// Discord or GitHub and no state, index or overrides file is written
+var dryRun bool
+
+// parseFlags reads the command line switches
+func parseFlags(args []string) (cliOptions, error) {
+	var opts cliOptions
+	fs := flag.NewFlagSet("go-kotatsu-bot", flag.ContinueOnError)
+	fs.StringVar(&opts.configPath, "config", "config.yaml", "configuration file, read at startup and on reloads")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "connect and handle events without changing anything on Discord or GitHub or writing state")
+	fs.BoolVar(&opts.validate, "validate", false, "check the configuration file and exit (non-zero when it has problems)")
+	fs.BoolVar(&opts.live, "live", false, "with --validate, also check with discord_token that the servers, channels and roles it references exist")
+	fs.StringVar(&opts.logLevel, "log-level", logInfo, "log verbosity: debug, info or error")
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	switch opts.logLevel {
+	case logDebug, logInfo, logError:
+	default:
+		return opts, fmt.Errorf("-log-level: unknown level %q (use %s, %s or %s)", opts.logLevel, logDebug, logInfo, logError)
+	}
+	return opts, nil
+}
+
+// levelLogWriter drops the log lines below level on their way to w
+type levelLogWriter struct {
+	w     io.Writer
+	level string
+}
+
+func (l levelLogWriter) Write(p []byte) (int, error) {
+	line := string(p)
+	switch {
+	case l.level == logInfo && strings.Contains(line, " debug: "):
+		return len(p), nil
+	case l.level == logError && !isFailureLine(line):
+		return len(p), nil
+	}
+	return l.w.Write(p)
+}
+
+// dryRunHosts are the APIs whose state the bot changes; other APIs are only queried
+var dryRunHosts = map[string]bool{"discord.com": true, "api.github.com": true, "github.com": true}
+
+// dryRunTransport refuses requests that would change something on dryRunHosts. The caller gets
+// an error, so the action is logged as failed and nothing else happens.
+type dryRunTransport struct {
+	base http.RoundTripper
+}
+
+func (t dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if dryRunHosts[req.URL.Hostname()] {
+			return nil, fmt.Errorf("dry run: %s %s not sent", req.Method, req.URL.Path)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// setupFromFlags applies the logging and dry-run switches
+func setupFromFlags(opts cliOptions) {
+	log.SetOutput(installOpsCounters(levelLogWriter{w: os.Stderr, level: opts.logLevel}))
+	if opts.dryRun {
+		dryRun = true
+		http.DefaultTransport = dryRunTransport{base: http.DefaultTransport}
+		log.Printf("dry run: changes to Discord and GitHub are not sent and no state is saved")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const forumPoliciesBucket = "forum_policies"
+
+// forumPolicyVersion is one saved revision of a forum's post guidelines and default reaction
+type forumPolicyVersion struct {
+	Version    int       `json:"version"`
+	Guidelines string    `json:"guidelines"`
+	EmojiID    string    `json:"emoji_id,omitempty"`
+	EmojiName  string    `json:"emoji_name,omitempty"`
+	ChangedBy  string    `json:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at"`
+	Note       string    `json:"note,omitempty"`
+}
+
+// forumPolicyHistory is the stored revision list of one forum, oldest first
+type forumPolicyHistory struct {
+	Versions []forumPolicyVersion `json:"versions"`
+}
+
+var customEmojiRe = regexp.MustCompile(`^<a?:([A-Za-z0-9_]+):(\d+)>$`)
+
+func init() {
+	registerThreadCommand("guidelines", (*handler).handleGuidelines)
+	registerThreadCommand("default-reaction", (*handler).handleDefaultReaction)
+	requireStore("guidelines", "default-reaction")
+}
+
+// handleGuidelines implements `.guidelines [set <text>|history|revert <version>]` for the current thread's forum
+func (h *handler) handleGuidelines(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, args string) {
+	sub, rest := args, ""
+	if i := strings.IndexAny(args, " \n"); i >= 0 {
+		sub, rest = args[:i], strings.TrimSpace(args[i+1:])
+	}
+	switch strings.ToLower(sub) {
+	case "":
+		forum, err := s.Channel(ch.ParentID)
+		if err != nil {
+			log.Printf("guidelines: failed to fetch forum %s: %v", ch.ParentID, err)
+			return
+		}
+		if forum.Topic == "" {
+			replyMessage(s, m.Message, "This forum has no post guidelines.")
+			return
+		}
+		replyMessage(s, m.Message, "Current post guidelines:\n"+forum.Topic)
+	case "set":
+		if rest == "" {
+			replyMessage(s, m.Message, "usage: "+h.cfg().forGuild(m.GuildID).command("guidelines")+" set <text>")
+			return
+		}
+		h.updateForumPolicy(s, m, ch.ParentID, func(v *forumPolicyVersion) { v.Guidelines = rest; v.Note = "guidelines" })
+	case "history":
+		hist, err := h.loadForumPolicy(ch.ParentID)
+		if err != nil {
+			log.Printf("guidelines: failed to load history: %v", err)
+			return
+		}
+		if len(hist.Versions) == 0 {
+			replyMessage(s, m.Message, "No guideline changes have been made through the bot yet.")
+			return
+		}
+		sb := &strings.Builder{}
+		sb.WriteString("Forum policy history:\n")
+		for _, v := range hist.Versions {
+			sb.WriteString(fmt.Sprintf("- v%d <t:%d:f> by <@%s> (%s), reaction %s\n", v.Version, v.ChangedAt.Unix(), v.ChangedBy, v.Note, formatForumReaction(v)))
+		}
+		replyMessage(s, m.Message, sb.String())
+	case "revert":
+		n, err := strconv.Atoi(strings.TrimPrefix(rest, "v"))
+		if err != nil {
+			replyMessage(s, m.Message, "usage: "+h.cfg().forGuild(m.GuildID).command("guidelines")+" revert <version>")
+			return
+		}
+		hist, err := h.loadForumPolicy(ch.ParentID)
+		if err != nil {
+			log.Printf("guidelines: failed to load history: %v", err)
+			return
+		}
+		var target *forumPolicyVersion
+		for i := range hist.Versions {
+			if hist.Versions[i].Version == n {
+				target = &hist.Versions[i]
+			}
+		}
+		if target == nil {
+			replyMessage(s, m.Message, fmt.Sprintf("Version %d not found. Use `%s history` to list versions.", n, h.cfg().forGuild(m.GuildID).command("guidelines")))
+			return
+		}
+		old := *target
+		h.updateForumPolicy(s, m, ch.ParentID, func(v *forumPolicyVersion) {
+			v.Guidelines, v.EmojiID, v.EmojiName = old.Guidelines, old.EmojiID, old.EmojiName
+			v.Note = fmt.Sprintf("revert to v%d", n)
+		})
+	default:
+		replyMessage(s, m.Message, "usage: "+h.cfg().forGuild(m.GuildID).command("guidelines")+" [set <text>|history|revert <version>]")
+	}
+}
+
+// handleDefaultReaction implements `.default-reaction <emoji|none>` for the current thread's forum
+func (h *handler) handleDefaultReaction(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, args string) {
+	if args == "" {
+		replyMessage(s, m.Message, "usage: "+h.cfg().forGuild(m.GuildID).command("default-reaction")+" <emoji|none>")
+		return
+	}
+	h.updateForumPolicy(s, m, ch.ParentID, func(v *forumPolicyVersion) {
+		v.EmojiID, v.EmojiName = "", ""
+		if match := customEmojiRe.FindStringSubmatch(args); match != nil {
+			v.EmojiID = match[2]
+		} else if !strings.EqualFold(args, "none") {
+			v.EmojiName = args
+		}
+		v.Note = "default reaction"
+	})
+}
+
+// updateForumPolicy applies a change on top of the forum's current settings, pushes it to Discord
+// and records it as a new version
+func (h *handler) updateForumPolicy(s *discordgo.Session, m *discordgo.MessageCreate, forumID string, change func(v *forumPolicyVersion)) {
+	forum, err := s.Channel(forumID)
+	if err != nil {
+		log.Printf("guidelines: failed to fetch forum %s: %v", forumID, err)
+		return
+	}
+	hist, err := h.loadForumPolicy(forumID)
+	if err != nil {
+		log.Printf("guidelines: failed to load history: %v", err)
+		return
+	}
+	// start from the live settings so edits made in the Discord UI are not lost
+	next := forumPolicyVersion{Guidelines: forum.Topic}
+	if forum.DefaultReactionEmoji.EmojiID != "" || forum.DefaultReactionEmoji.EmojiName != "" {
+		next.EmojiID = forum.DefaultReactionEmoji.EmojiID
+		next.EmojiName = forum.DefaultReactionEmoji.EmojiName
+	}
+	change(&next)
+	next.Version = len(hist.Versions) + 1
+	next.ChangedBy = m.Author.ID
+	next.ChangedAt = time.Now()
+
+	// a raw PATCH lets us send null to clear the default reaction, which ChannelEdit omits
+	body := map[string]interface{}{"topic": next.Guidelines, "default_reaction_emoji": nil}
+	if next.EmojiID != "" || next.EmojiName != "" {
+		body["default_reaction_emoji"] = discordgo.ForumDefaultReaction{EmojiID: next.EmojiID, EmojiName: next.EmojiName}
+	}
+	endpoint := discordgo.EndpointChannel(forumID)
+	if _, err := s.RequestWithBucketID("PATCH", endpoint, body, endpoint); err != nil {
+		log.Printf("guidelines: failed to edit forum %s: %v", forumID, err)
+		replyMessage(s, m.Message, "❌ Failed to update the forum. The bot needs Manage Channels on the forum.")
+		return
+	}
+
+	hist.Versions = append(hist.Versions, next)
+	if h.store != nil {
+		if err := h.store.Put(forumPoliciesBucket, forumID, hist); err != nil {
+			log.Printf("guidelines: failed to save version %d for %s: %v", next.Version, forumID, err)
+		}
+	}
+	replyMessage(s, m.Message, fmt.Sprintf("Updated forum policy (v%d): %s, default reaction %s.", next.Version, next.Note, formatForumReaction(next)))
+}
+
+func (h *handler) loadForumPolicy(forumID string) (*forumPolicyHistory, error) {
+	hist := &forumPolicyHistory{}
+	if h.store == nil {
+		return hist, nil
+	}
+	if _, err := h.store.Get(forumPoliciesBucket, forumID, hist); err != nil {
+		return nil, err
+	}
+	return hist, nil
+}
+
+func formatForumReaction(v forumPolicyVersion) string {
+	switch {
+	case v.EmojiID != "":
+		return fmt.Sprintf("<:emoji:%s>", v.EmojiID)
+	case v.EmojiName != "":
+		return v.EmojiName
+	default:
+		return "none"
+	}
+}
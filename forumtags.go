@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxAppliedTags is Discord's limit on tags applied to a single forum post
+const maxAppliedTags = 5
+
+// forumTag is a tag available in a forum channel
+type forumTag struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// fetchForumTags reads the available tags of a forum via raw REST, checking both the top-level
+// available_tags and forum_metadata.available_tags shapes of the payload.
+func fetchForumTags(s *discordgo.Session, forumID string) ([]forumTag, error) {
+	var tags []forumTag
+	if sharedCache != nil && sharedCache.get("tags:"+forumID, &tags) {
+		return tags, nil
+	}
+	endpoint := discordgo.EndpointChannel(forumID)
+	raw, err := s.RequestWithBucketID("GET", endpoint, nil, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		AvailableTags []forumTag `json:"available_tags"`
+		ForumMetadata *struct {
+			AvailableTags []forumTag `json:"available_tags"`
+		} `json:"forum_metadata"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	tags = data.AvailableTags
+	if len(tags) == 0 && data.ForumMetadata != nil {
+		tags = data.ForumMetadata.AvailableTags
+	}
+	if sharedCache != nil {
+		sharedCache.set("tags:"+forumID, tags, forumTagsTTL)
+	}
+	return tags, nil
+}
+
+// onForumUpdate drops the cached tags of a forum when its settings change
+func (h *handler) onForumUpdate(s *discordgo.Session, c *discordgo.ChannelUpdate) {
+	if sharedCache != nil && c.Type == discordgo.ChannelTypeGuildForum {
+		sharedCache.del("tags:" + c.ID)
+	}
+}
+
+// fetchAppliedTags reads the tag IDs currently applied to a thread
+func fetchAppliedTags(s *discordgo.Session, threadID string) ([]string, error) {
+	endpoint := discordgo.EndpointChannel(threadID)
+	raw, err := s.RequestWithBucketID("GET", endpoint, nil, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		AppliedTags []string `json:"applied_tags"`
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data.AppliedTags, nil
+}
+
+// findForumTag returns the tag whose name matches case-insensitively
+func findForumTag(tags []forumTag, name string) (forumTag, bool) {
+	for _, t := range tags {
+		if strings.EqualFold(t.Name, name) {
+			return t, true
+		}
+	}
+	return forumTag{}, false
+}
+
+// editThreadTags adds and removes tags (by name) on a thread, keeping all other applied tags.
+// Names the forum does not define are ignored; additions stop at Discord's five-tag limit.
+// It returns the names that were newly added.
+func editThreadTags(s *discordgo.Session, th *discordgo.Channel, add, remove []string) ([]string, error) {
+	available, err := fetchForumTags(s, th.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := fetchAppliedTags(s, th.ID)
+	if err != nil {
+		return nil, err
+	}
+	removeIDs := map[string]bool{}
+	for _, name := range remove {
+		if t, ok := findForumTag(available, name); ok {
+			removeIDs[t.ID] = true
+		}
+	}
+	next := make([]string, 0, len(applied)+len(add))
+	present := map[string]bool{}
+	for _, id := range applied {
+		if !removeIDs[id] {
+			next = append(next, id)
+			present[id] = true
+		}
+	}
+	var added []string
+	for _, name := range add {
+		t, ok := findForumTag(available, name)
+		if !ok || present[t.ID] || len(next) >= maxAppliedTags {
+			continue
+		}
+		next = append(next, t.ID)
+		present[t.ID] = true
+		added = append(added, t.Name)
+	}
+	if len(added) == 0 && len(next) == len(applied) {
+		return nil, nil
+	}
+	if _, err := s.ChannelEdit(th.ID, &discordgo.ChannelEdit{AppliedTags: &next}); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// listForumThreads returns the active threads of a forum followed by up to archivedLimit of its
+// most recently archived public threads
+func listForumThreads(s *discordgo.Session, guildID, forumID string, archivedLimit int) ([]*discordgo.Channel, error) {
+	var out []*discordgo.Channel
+	active, err := s.GuildThreadsActive(guildID)
+	if err != nil {
+		return nil, err
+	}
+	for _, th := range active.Threads {
+		if th.ParentID == forumID {
+			out = append(out, th)
+		}
+	}
+	remaining := archivedLimit
+	var before *time.Time
+	for remaining > 0 {
+		limit := remaining
+		if limit > 100 {
+			limit = 100
+		}
+		list, err := s.ThreadsArchived(forumID, before, limit)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, list.Threads...)
+		remaining -= len(list.Threads)
+		if !list.HasMore || len(list.Threads) == 0 {
+			break
+		}
+		last := list.Threads[len(list.Threads)-1]
+		if last.ThreadMetadata == nil {
+			break
+		}
+		ts := last.ThreadMetadata.ArchiveTimestamp
+		before = &ts
+	}
+	return out, nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const githubLinksBucket = "github_links"
+
+// githubLink records which GitHub account a Discord member authenticated as
+type githubLink struct {
+	DiscordUserID string    `json:"discord_user_id"`
+	GuildID       string    `json:"guild_id"`
+	Login         string    `json:"login"`
+	LinkedAt      time.Time `json:"linked_at"`
+	Contributor   bool      `json:"contributor"`
+	CheckedAt     time.Time `json:"checked_at"`
+}
+
+// githubLinkKey keys a link by server and member, since the role is granted per server. Links made
+// before that are keyed by the member alone until the next role sync.
+func githubLinkKey(guildID, userID string) string {
+	return guildID + "/" + userID
+}
+
+// hasContributorRole reports whether contributor_role_id is set at the top level or for a server
+func (cfg *Config) hasContributorRole() bool {
+	if cfg.ContributorRoleID != "" {
+		return true
+	}
+	for _, g := range cfg.Guilds {
+		if g.ContributorRoleID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// githubDeviceCode is the response of GitHub's device authorization endpoint
+type githubDeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// handleLinkGitHub starts the GitHub device flow for the author of a `.link-github` message.
+// The code is sent by DM and the role is granted once the user has authorized the app.
+func (h *handler) handleLinkGitHub(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if h.cfg().GitHubClientID == "" || m.GuildID == "" || h.cfg().forGuild(m.GuildID).ContributorRoleID == "" {
+		return
+	}
+	dm, err := s.UserChannelCreate(m.Author.ID)
+	if err != nil {
+		log.Printf("github link: failed to open DM with %s: %v", m.Author.ID, err)
+		return
+	}
+	code, err := githubRequestDeviceCode(h.cfg().GitHubClientID)
+	if err != nil {
+		log.Printf("github link: device code request failed: %v", err)
+		replyMessage(s, m.Message, "GitHub linking is currently unavailable, please try again later.")
+		return
+	}
+	msg := fmt.Sprintf("To link your GitHub account, open %s and enter the code **%s**. The code expires <t:%d:R>.",
+		code.VerificationURI, code.UserCode, time.Now().Add(time.Duration(code.ExpiresIn)*time.Second).Unix())
+	if _, err := s.ChannelMessageSend(dm.ID, msg); err != nil {
+		log.Printf("github link: failed to DM %s: %v", m.Author.ID, err)
+		replyMessage(s, m.Message, "I couldn't DM you. Please allow direct messages from server members and try again.")
+		return
+	}
+	replyMessage(s, m.Message, "Check your DMs to finish linking your GitHub account.")
+
+	go func() {
+		token, err := githubPollDeviceToken(h.cfg().GitHubClientID, code)
+		if err != nil {
+			log.Printf("github link: authorization for %s failed: %v", m.Author.ID, err)
+			if _, e := s.ChannelMessageSend(dm.ID, "GitHub linking did not complete: "+err.Error()); e != nil {
+				log.Printf("failed to send github link error: %v", e)
+			}
+			return
+		}
+		var user struct {
+			Login string `json:"login"`
+		}
+		if _, err := githubRequest(context.Background(), token, "GET", "/user", nil, &user); err != nil || user.Login == "" {
+			log.Printf("github link: failed to read GitHub user for %s: %v", m.Author.ID, err)
+			if _, e := s.ChannelMessageSend(dm.ID, "Could not read your GitHub profile, please try again."); e != nil {
+				log.Printf("failed to send github link error: %v", e)
+			}
+			return
+		}
+		// the user's token is only needed to prove account ownership and is not stored
+		link := &githubLink{DiscordUserID: m.Author.ID, GuildID: m.GuildID, Login: user.Login, LinkedAt: time.Now()}
+		if err := h.refreshGitHubLink(context.Background(), s, link); err != nil {
+			log.Printf("github link: status check for %s failed: %v", user.Login, err)
+		}
+		reply := fmt.Sprintf("Linked GitHub account **%s**.", user.Login)
+		if link.Contributor {
+			reply += " You have been given the contributor role."
+		} else {
+			reply += " No contributions were found yet; the role is granted automatically once you contribute."
+		}
+		if _, err := s.ChannelMessageSend(dm.ID, reply); err != nil {
+			log.Printf("failed to send github link confirmation: %v", err)
+		}
+	}()
+}
+
+// handleUnlinkGitHub removes the author's GitHub link and the contributor role
+func (h *handler) handleUnlinkGitHub(s *discordgo.Session, m *discordgo.MessageCreate) {
+	roleID := h.cfg().forGuild(m.GuildID).ContributorRoleID
+	if roleID == "" || h.store == nil {
+		return
+	}
+	var link githubLink
+	key := githubLinkKey(m.GuildID, m.Author.ID)
+	found, err := h.store.Get(githubLinksBucket, key, &link)
+	if err == nil && !found {
+		key = m.Author.ID
+		found, err = h.store.Get(githubLinksBucket, key, &link)
+		found = found && link.GuildID == m.GuildID
+	}
+	if err != nil {
+		log.Printf("github link: failed to read link for %s: %v", m.Author.ID, err)
+		return
+	}
+	if !found {
+		replyMessage(s, m.Message, "You have no linked GitHub account.")
+		return
+	}
+	if err := h.store.Delete(githubLinksBucket, key); err != nil {
+		log.Printf("github link: failed to delete link for %s: %v", m.Author.ID, err)
+	}
+	if err := s.GuildMemberRoleRemove(link.GuildID, m.Author.ID, roleID); err != nil {
+		log.Printf("github link: failed to remove role from %s: %v", m.Author.ID, err)
+	}
+	replyMessage(s, m.Message, fmt.Sprintf("Unlinked GitHub account **%s**.", link.Login))
+}
+
+// syncGitHubRoles is the periodic job that re-checks every linked account and grants or removes the role
+func (h *handler) syncGitHubRoles(ctx context.Context, job jobRecord) error {
+	if h.store == nil {
+		return nil
+	}
+	raw, err := h.store.List(githubLinksBucket)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for id, r := range raw {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var link githubLink
+		if err := json.Unmarshal(r, &link); err != nil {
+			log.Printf("github link: unreadable link %s: %v", id, err)
+			continue
+		}
+		if err := h.refreshGitHubLink(ctx, h.dg, &link); err != nil {
+			log.Printf("github link: refresh for %s failed: %v", link.Login, err)
+			failed++
+			continue
+		}
+		// the refresh stored the link under its per-server key
+		if id != githubLinkKey(link.GuildID, link.DiscordUserID) {
+			if err := h.store.Delete(githubLinksBucket, id); err != nil {
+				log.Printf("github link: failed to remove old link record %s: %v", id, err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d links could not be refreshed", failed, len(raw))
+	}
+	return nil
+}
+
+// refreshGitHubLink checks the contribution status of a link, updates the member's role and persists the result
+func (h *handler) refreshGitHubLink(ctx context.Context, s *discordgo.Session, link *githubLink) error {
+	contributor, err := h.githubIsContributor(ctx, link.Login)
+	if err != nil {
+		return err
+	}
+	roleID := h.cfg().forGuild(link.GuildID).ContributorRoleID
+	switch {
+	case roleID == "":
+		// contributor_role_id was removed for this server since the link was made
+	case contributor:
+		err = s.GuildMemberRoleAdd(link.GuildID, link.DiscordUserID, roleID)
+	case link.Contributor:
+		err = s.GuildMemberRoleRemove(link.GuildID, link.DiscordUserID, roleID)
+	}
+	if err != nil {
+		return err
+	}
+	link.Contributor = contributor
+	link.CheckedAt = time.Now()
+	if h.store == nil {
+		return nil
+	}
+	return h.store.Put(githubLinksBucket, githubLinkKey(link.GuildID, link.DiscordUserID), link)
+}
+
+// githubIsContributor reports whether login is a member of the configured org or a contributor to the configured repo
+func (h *handler) githubIsContributor(ctx context.Context, login string) (bool, error) {
+	if org := h.cfg().GitHubOrg; org != "" {
+		resp, err := githubRequest(ctx, h.cfg().GitHubToken, "GET", "/orgs/"+url.PathEscape(org)+"/members/"+url.PathEscape(login), nil, nil)
+		if err != nil {
+			return false, err
+		}
+		if resp.StatusCode == http.StatusNoContent {
+			return true, nil
+		}
+	}
+	if repo := h.cfg().GitHubRepo; repo != "" {
+		// contributors are sorted by contribution count; a few pages cover everyone who matters
+		for page := 1; page <= 5; page++ {
+			var contributors []struct {
+				Login string `json:"login"`
+			}
+			if _, err := githubRequest(ctx, h.cfg().GitHubToken, "GET", fmt.Sprintf("/repos/%s/contributors?per_page=100&page=%d", repo, page), nil, &contributors); err != nil {
+				return false, err
+			}
+			for _, c := range contributors {
+				if strings.EqualFold(c.Login, login) {
+					return true, nil
+				}
+			}
+			if len(contributors) < 100 {
+				break
+			}
+		}
+	}
+	return false, nil
+}
+
+// githubRequestDeviceCode starts the OAuth device flow
+func githubRequestDeviceCode(clientID string) (*githubDeviceCode, error) {
+	var code githubDeviceCode
+	if err := githubOAuthPost("https://github.com/login/device/code", url.Values{"client_id": {clientID}, "scope": {"read:user"}}, &code); err != nil {
+		return nil, err
+	}
+	if code.DeviceCode == "" {
+		return nil, errors.New("github returned no device code")
+	}
+	return &code, nil
+}
+
+// githubPollDeviceToken polls until the user authorizes the device code, it expires, or access is denied
+func githubPollDeviceToken(clientID string, code *githubDeviceCode) (string, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		var res struct {
+			AccessToken string `json:"access_token"`
+			Error       string `json:"error"`
+		}
+		err := githubOAuthPost("https://github.com/login/oauth/access_token", url.Values{
+			"client_id":   {clientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		}, &res)
+		if err != nil {
+			return "", err
+		}
+		switch res.Error {
+		case "":
+			return res.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		case "expired_token":
+			return "", errors.New("the code expired")
+		case "access_denied":
+			return "", errors.New("authorization was denied")
+		default:
+			return "", fmt.Errorf("github returned %s", res.Error)
+		}
+	}
+	return "", errors.New("the code expired")
+}
+
+func githubOAuthPost(endpoint string, form url.Values, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("github oauth returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// GuildConfig holds the settings of one server (guilds.<guild id>), for a bot serving several
+// servers with different setups, e.g. a community server and a test server. Settings left out
+// keep their top-level values; an empty list clears the top-level one.
+type GuildConfig struct {
+	// Forum parents watched in this server, in addition to the top-level forum_parent_ids
+	ForumParentIDs     []string `yaml:"forum_parent_ids"`
+	AllowedRoleIDs     []string `yaml:"allowed_role_ids"`
+	AllowedPermissions []string `yaml:"allowed_permissions"`
+	SearchEnabled      *bool    `yaml:"search_enabled"`
+	SearchChannels     []string `yaml:"search_channels"`
+	SearchProviders    []string `yaml:"search_providers"`
+	SearchCombined     *bool    `yaml:"search_combined"`
+	// "silent", "explain" or "hint" (see unwatched_command_reply)
+	UnwatchedCommandReply string `yaml:"unwatched_command_reply"`
+	// Title style of the status commands in this server (see status_style); status_prefixes
+	// entries are added to the top-level ones
+	StatusStyle    string            `yaml:"status_style"`
+	StatusPrefixes map[string]string `yaml:"status_prefixes"`
+	// Text command prefixes of this server, replacing command_prefixes
+	CommandPrefixes []string `yaml:"command_prefixes"`
+	// Channel of this server that receives its thread transcripts
+	TranscriptChannelID string `yaml:"transcript_channel_id"`
+	// Role granted to linked GitHub contributors in this server; roles belong to a single server,
+	// so each server needs its own
+	ContributorRoleID string `yaml:"contributor_role_id"`
+	// Per-forum settings (welcome messages, triage panels, auto tags...) of this server's forums.
+	// A forum cannot also be listed in the top-level forums.
+	Forums map[string]ForumConfig `yaml:"forums"`
+	// Reply templates of this server, replacing the top-level ones with the same key
+	Templates map[string]string `yaml:"templates"`
+}
+
+// mergeGuildForums adds the forums of every guild section to cfg.Forums, where forum settings are
+// looked up by forum ID
+func (cfg *Config) mergeGuildForums() error {
+	for guildID, g := range cfg.Guilds {
+		if len(g.Forums) > 0 && cfg.Forums == nil {
+			cfg.Forums = map[string]ForumConfig{}
+		}
+		for id, fc := range g.Forums {
+			if _, ok := cfg.Forums[id]; ok {
+				return fmt.Errorf("guilds.%s.forums.%s: the forum is also configured elsewhere", guildID, id)
+			}
+			cfg.Forums[id] = fc
+		}
+	}
+	return nil
+}
+
+// compileGuilds validates the guild sections and builds the configuration in effect in each of
+// those servers. It runs last in compile so the copies start from the compiled top level.
+func (cfg *Config) compileGuilds() error {
+	cfg.guildConfigs = make(map[string]*Config, len(cfg.Guilds))
+	for id, g := range cfg.Guilds {
+		c := *cfg
+		c.guildConfigs = nil
+		if g.AllowedRoleIDs != nil {
+			c.AllowedRoleIDs = g.AllowedRoleIDs
+		}
+		if g.AllowedPermissions != nil {
+			c.AllowedPermissions = g.AllowedPermissions
+		}
+		if g.SearchEnabled != nil {
+			c.SearchEnabled = g.SearchEnabled
+		}
+		if g.SearchChannels != nil {
+			c.SearchChannels = g.SearchChannels
+		}
+		if len(g.SearchProviders) > 0 {
+			resolveProviderAliases(g.SearchProviders)
+			if err := checkTrackers("guilds."+id+".search_providers", g.SearchProviders); err != nil {
+				return err
+			}
+			c.SearchProviders = g.SearchProviders
+		}
+		if g.SearchCombined != nil {
+			c.SearchCombined = *g.SearchCombined
+		}
+		if g.TranscriptChannelID != "" {
+			c.TranscriptChannelID = g.TranscriptChannelID
+		}
+		if g.ContributorRoleID != "" {
+			c.ContributorRoleID = g.ContributorRoleID
+		}
+		switch g.UnwatchedCommandReply {
+		case "":
+		case unwatchedSilent, unwatchedExplain, unwatchedHint:
+			c.UnwatchedCommandReply = g.UnwatchedCommandReply
+		default:
+			return fmt.Errorf("guilds.%s.unwatched_command_reply: unknown value %q (use %q, %q or %q)", id, g.UnwatchedCommandReply, unwatchedSilent, unwatchedExplain, unwatchedHint)
+		}
+		if err := checkStatusStyle("guilds."+id+".", g.StatusStyle, g.StatusPrefixes); err != nil {
+			return err
+		}
+		if len(g.CommandPrefixes) > 0 {
+			if err := checkCommandPrefixes("guilds."+id+".command_prefixes", g.CommandPrefixes); err != nil {
+				return err
+			}
+			c.CommandPrefixes = g.CommandPrefixes
+		}
+		if g.StatusStyle != "" {
+			c.StatusStyle = g.StatusStyle
+		}
+		if len(g.StatusPrefixes) > 0 {
+			c.StatusPrefixes = make(map[string]string, len(cfg.StatusPrefixes)+len(g.StatusPrefixes))
+			for cmd, p := range cfg.StatusPrefixes {
+				c.StatusPrefixes[cmd] = p
+			}
+			for cmd, p := range g.StatusPrefixes {
+				c.StatusPrefixes[cmd] = p
+			}
+		}
+		if len(g.Templates) > 0 {
+			templates, err := compileTemplates("guilds."+id+".templates", g.Templates)
+			if err != nil {
+				return err
+			}
+			c.templates = make(map[string]*template.Template, len(cfg.templates)+len(templates))
+			for key, tmpl := range cfg.templates {
+				c.templates[key] = tmpl
+			}
+			for key, tmpl := range templates {
+				c.templates[key] = tmpl
+			}
+		}
+		cfg.guildConfigs[id] = &c
+	}
+	return nil
+}
+
+// forGuild returns the configuration in effect in a server: the top level with the server's
+// guilds entry applied
+func (cfg *Config) forGuild(guildID string) *Config {
+	if cfg == nil {
+		return nil
+	}
+	if c, ok := cfg.guildConfigs[guildID]; ok {
+		return c
+	}
+	return cfg
+}
+
+// allForumParentIDs returns forum_parent_ids together with those of every guild section
+func (cfg *Config) allForumParentIDs() []string {
+	var ids []string
+	for _, id := range cfg.ForumParentIDs {
+		ids = append(ids, strings.TrimSpace(id))
+	}
+	for _, g := range cfg.Guilds {
+		for _, id := range g.ForumParentIDs {
+			ids = append(ids, strings.TrimSpace(id))
+		}
+	}
+	return ids
+}
+
+// watchedParents maps the forums of forum_parent_ids and of the guild sections to their guild.
+// The guild of a top-level forum is not known from the config and left empty until looked up.
+func (cfg *Config) watchedParents() map[string]string {
+	watched := map[string]string{}
+	for _, id := range cfg.ForumParentIDs {
+		watched[strings.TrimSpace(id)] = ""
+	}
+	for guildID, g := range cfg.Guilds {
+		for _, id := range g.ForumParentIDs {
+			watched[strings.TrimSpace(id)] = guildID
+		}
+	}
+	return watched
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const guildsBucket = "guilds"
+
+// guildJoinGrace separates guilds joined while the bot was running from guilds it was already in
+// before the first start with a guild record (those are recorded without onboarding)
+const guildJoinGrace = 10 * time.Minute
+
+// guildRecord is the per-guild state created when the bot joins a server
+type guildRecord struct {
+	Name      string    `json:"name"`
+	JoinedAt  time.Time `json:"joined_at"`
+	InviterID string    `json:"inviter_id,omitempty"`
+	// OnboardedVia is where the onboarding message went: "dm", "system_channel" or empty
+	OnboardedVia string    `json:"onboarded_via,omitempty"`
+	OnboardedAt  time.Time `json:"onboarded_at,omitempty"`
+}
+
+func init() {
+	manageServer := int64(discordgo.PermissionManageServer)
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:                     "setup",
+		Description:              "Show the bot's setup for this server or start watching a forum",
+		DefaultMemberPermissions: &manageServer,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "forum",
+				Description:  "Forum whose posts the bot should manage",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildForum},
+			},
+		},
+	}, (*handler).handleSetupCommand)
+}
+
+// onGuildCreate records new guilds and sends the onboarding message to whoever added the bot, or
+// to the server's system channel when the inviter cannot be determined or DMed
+func (h *handler) onGuildCreate(s *discordgo.Session, g *discordgo.GuildCreate) {
+	if h.store == nil || g.Guild == nil || g.Unavailable {
+		return
+	}
+	var rec guildRecord
+	if found, err := h.store.Get(guildsBucket, g.ID, &rec); err != nil || found {
+		return
+	}
+	rec = guildRecord{Name: g.Name, JoinedAt: g.JoinedAt}
+	if time.Since(g.JoinedAt) < guildJoinGrace {
+		rec.InviterID = findInviter(s, g.ID)
+		rec.OnboardedVia = h.sendOnboarding(s, g.Guild, rec.InviterID)
+		if rec.OnboardedVia != "" {
+			rec.OnboardedAt = time.Now()
+		}
+		log.Printf("joined guild %s (%s), inviter=%q, onboarding via %q", g.ID, g.Name, rec.InviterID, rec.OnboardedVia)
+	}
+	if err := h.store.Put(guildsBucket, g.ID, rec); err != nil {
+		log.Printf("failed to save guild record for %s: %v", g.ID, err)
+	}
+}
+
+// findInviter returns who added the bot to a guild according to the audit log (needs View Audit Log)
+func findInviter(s *discordgo.Session, guildID string) string {
+	entries, err := s.GuildAuditLog(guildID, "", "", int(discordgo.AuditLogActionBotAdd), 10)
+	if err != nil {
+		log.Printf("onboarding: cannot read audit log of %s: %v", guildID, err)
+		return ""
+	}
+	for _, e := range entries.AuditLogEntries {
+		if e.TargetID == s.State.User.ID {
+			return e.UserID
+		}
+	}
+	return ""
+}
+
+// sendOnboarding posts the onboarding message and reports where it was delivered
+func (h *handler) sendOnboarding(s *discordgo.Session, g *discordgo.Guild, inviterID string) string {
+	text := fmt.Sprintf("👋 Thanks for adding me to **%s**!\n"+
+		"I manage support forum posts: status commands like `%s`, triage panels, duplicate suggestions and more.\n"+
+		"To get started, run `/setup forum:#your-forum` in the server to choose which forum I should manage, "+
+		"then `/setup` to review the current setup. Moderators with Manage Messages or Manage Channels can use the commands in its posts.", g.Name, h.cfg().forGuild(g.ID).command("solved"))
+	if inviterID != "" {
+		if dm, err := s.UserChannelCreate(inviterID); err == nil {
+			if _, err := s.ChannelMessageSend(dm.ID, text); err == nil {
+				return "dm"
+			}
+		}
+	}
+	if g.SystemChannelID != "" {
+		_, err := s.ChannelMessageSend(g.SystemChannelID, text)
+		if err == nil {
+			return "system_channel"
+		}
+		log.Printf("onboarding: failed to post in system channel of %s: %v", g.ID, err)
+	}
+	return ""
+}
+
+// handleSetupCommand implements /setup [forum:<forum>]
+func (h *handler) handleSetupCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.GuildID == "" || i.Member == nil {
+		respondEphemeral(s, i, "Run /setup in a server.")
+		return
+	}
+	if i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) == 0 {
+		respondEphemeral(s, i, h.interactionReply(s, i, "permission.setup"))
+		return
+	}
+	if o, ok := slashOptions(i)["forum"]; ok {
+		forum := o.ChannelValue(s)
+		if forum == nil || forum.Type != discordgo.ChannelTypeGuildForum {
+			respondEphemeral(s, i, "Pick a forum channel.")
+			return
+		}
+		if err := h.addWatchedParent(forum.ID, i.GuildID); err != nil {
+			log.Printf("setup: failed to persist watched forum %s: %v", forum.ID, err)
+		}
+		log.Printf("setup: forum %s added to the watch list by %s", forum.ID, i.Member.User.ID)
+		respondEphemeral(s, i, fmt.Sprintf("Now managing posts in <#%s>. Commands in other forums are ignored unless they are added too.", forum.ID))
+		return
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("**Bot setup for this server**\n")
+	forums := h.watchedParentIDs(i.GuildID)
+	if len(forums) == 0 {
+		sb.WriteString("Watched forums: all forums (none configured)\n")
+	} else {
+		ids := make([]string, 0, len(forums))
+		for _, id := range forums {
+			ids = append(ids, "<#"+id+">")
+		}
+		sb.WriteString("Watched forums: " + strings.Join(ids, ", ") + "\n")
+	}
+	if h.storeDegraded() {
+		sb.WriteString("⚠️ Storage is unavailable: changes made now are not saved and stateful features are disabled\n")
+	}
+	sb.WriteString("\nNext steps:\n")
+	sb.WriteString("- `/setup forum:#forum` to add a forum the bot should manage\n")
+	sb.WriteString("- Create forum tags named like the status commands (`.Solved`, `.Devs aware`, ...) so statuses are tagged as well as prefixed\n")
+	sb.WriteString("- Give the bot Manage Threads, Manage Messages and Manage Channels in those forums\n")
+	sb.WriteString("- Per-forum automation (welcome messages, triage panels, auto tags) is configured under `forums:` in config.yaml")
+	respondEphemeral(s, i, sb.String())
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// opsStats collects the counters reported by the heartbeat. They are reset after every beat.
+type opsStats struct {
+	mu        sync.Mutex
+	since     time.Time
+	logLines  int
+	logErrors int
+	hosts     map[string]*hostStats
+}
+
+// hostStats counts outgoing HTTP requests to one API host
+type hostStats struct {
+	requests    int
+	failures    int // transport errors and 5xx responses
+	rateLimited int // 429 responses
+	// last quota reported by X-RateLimit-Remaining / X-RateLimit-Limit, when the API sends them
+	remaining, limit string
+}
+
+var stats = &opsStats{since: time.Now(), hosts: map[string]*hostStats{}}
+
+// countingTransport wraps the default HTTP transport so every API client (discordgo, AniList,
+// GitHub, ...) is counted without changes to the callers
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	hs := stats.hosts[req.URL.Host]
+	if hs == nil {
+		hs = &hostStats{}
+		stats.hosts[req.URL.Host] = hs
+	}
+	hs.requests++
+	switch {
+	case err != nil || resp.StatusCode >= 500:
+		hs.failures++
+	case resp.StatusCode == http.StatusTooManyRequests:
+		hs.rateLimited++
+	}
+	if err == nil {
+		if r := resp.Header.Get("X-RateLimit-Remaining"); r != "" {
+			hs.remaining, hs.limit = r, resp.Header.Get("X-RateLimit-Limit")
+		}
+	}
+	return resp, err
+}
+
+// countingLogWriter counts log lines, and those reporting a failure, on their way to w
+type countingLogWriter struct {
+	w io.Writer
+}
+
+func (c countingLogWriter) Write(p []byte) (int, error) {
+	stats.mu.Lock()
+	stats.logLines++
+	if isFailureLine(string(p)) {
+		stats.logErrors++
+	}
+	stats.mu.Unlock()
+	return c.w.Write(p)
+}
+
+// isFailureLine reports whether a log line reports a failure
+func isFailureLine(line string) bool {
+	line = strings.ToLower(line)
+	return strings.Contains(line, "fail") || strings.Contains(line, "error")
+}
+
+// installOpsCounters hooks the counters into the default HTTP transport and the standard logger
+func installOpsCounters(logOut io.Writer) io.Writer {
+	http.DefaultTransport = countingTransport{base: http.DefaultTransport}
+	return countingLogWriter{w: logOut}
+}
+
+// postHeartbeat is the `heartbeat` job: it posts an embed summarizing the bot's state since the
+// previous beat to heartbeat_channel_id and resets the counters
+func (h *handler) postHeartbeat(ctx context.Context, job jobRecord) error {
+	stats.mu.Lock()
+	since, logLines, logErrors, hosts := stats.since, stats.logLines, stats.logErrors, stats.hosts
+	stats.since, stats.logLines, stats.logErrors, stats.hosts = time.Now(), 0, 0, map[string]*hostStats{}
+	stats.mu.Unlock()
+
+	jobs, running := h.sched.List()
+	overdue, paused, failing, inFlight := 0, 0, 0, 0
+	for _, j := range jobs {
+		switch {
+		case j.Paused:
+			paused++
+		case !j.NextRun.IsZero() && time.Since(j.NextRun) > time.Minute:
+			overdue++
+		}
+		if j.LastError != "" {
+			failing++
+		}
+		inFlight += running[j.ID]
+	}
+
+	h.mu.Lock()
+	down := 0
+	for _, sh := range h.serviceHealth {
+		if sh.down {
+			down++
+		}
+	}
+	h.mu.Unlock()
+	pages.mu.Lock()
+	pagedMessages := len(pages.items)
+	pages.mu.Unlock()
+
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+	api := &strings.Builder{}
+	for _, host := range names {
+		hs := hosts[host]
+		fmt.Fprintf(api, "`%s` %d req", host, hs.requests)
+		if hs.failures > 0 {
+			fmt.Fprintf(api, ", %d failed", hs.failures)
+		}
+		if hs.rateLimited > 0 {
+			fmt.Fprintf(api, ", %d rate limited", hs.rateLimited)
+		}
+		if hs.remaining != "" {
+			fmt.Fprintf(api, ", quota %s/%s left", hs.remaining, hs.limit)
+		}
+		api.WriteString("\n")
+	}
+	if api.Len() == 0 {
+		api.WriteString("no requests")
+	}
+
+	color := 0x2ecc71
+	if logErrors > 0 || overdue > 0 || down > 0 {
+		color = 0xf1c40f
+	}
+	storage := "ok"
+	if h.storeDegraded() {
+		storage, color = "unavailable (degraded mode)", 0xe74c3c
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       "Heartbeat",
+		Description: fmt.Sprintf("Since <t:%d:t> · gateway latency %s", since.Unix(), h.dg.HeartbeatLatency().Round(time.Millisecond)),
+		Color:       color,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Jobs", Value: fmt.Sprintf("%d scheduled, %d running, %d overdue, %d paused, %d last run failed", len(jobs), inFlight, overdue, paused, failing), Inline: false},
+			{Name: "Log", Value: fmt.Sprintf("%d lines, %d errors", logLines, logErrors), Inline: true},
+			{Name: "Caches", Value: fmt.Sprintf("%d indexed threads, %d paged messages, %s", h.index.size(), pagedMessages, searchCacheSummary()), Inline: true},
+			{Name: "Upstream services", Value: fmt.Sprintf("%d down", down), Inline: true},
+			{Name: "Storage", Value: storage, Inline: true},
+			{Name: "Search providers", Value: providerHealthSummary(), Inline: false},
+			{Name: "API usage", Value: api.String(), Inline: false},
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	_, err := h.dg.ChannelMessageSendEmbed(h.cfg().HeartbeatChannelID, embed)
+	return err
+}
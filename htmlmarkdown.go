@@ -0,0 +1,190 @@
+package main
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlTagRe matches an HTML tag: closing slash, name and attributes
+var htmlTagRe = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9]*)([^>]*)>`)
+
+// htmlHrefRe extracts the link target of an <a> tag
+var htmlHrefRe = regexp.MustCompile(`(?i)href\s*=\s*["']([^"']*)["']`)
+
+// htmlSpaceRe matches the whitespace runs HTML renders as a single space
+var htmlSpaceRe = regexp.MustCompile(`\s+`)
+
+// markdownSpoilerRe matches Discord spoiler markup
+var markdownSpoilerRe = regexp.MustCompile(`(?s)\|\|.*?\|\|`)
+
+// htmlInlineMarks maps inline HTML elements to the Discord markdown wrapping them
+var htmlInlineMarks = map[string]string{
+	"i": "*", "em": "*", "cite": "*",
+	"b": "**", "strong": "**",
+	"u": "__",
+	"s": "~~", "del": "~~", "strike": "~~",
+	"h1": "**", "h2": "**", "h3": "**", "h4": "**", "h5": "**",
+}
+
+// htmlToMarkdown converts the HTML of AniList descriptions to Discord markdown: emphasis, links,
+// line breaks and spoiler spans keep their meaning, other tags are dropped and whitespace is
+// collapsed the way a browser would, with at most one blank line between paragraphs.
+func htmlToMarkdown(s string) string {
+	if s == "" {
+		return s
+	}
+	w := &markdownWriter{}
+	// for each open <a> and <span>: the markup written when it closes
+	var closers []string
+	pos := 0
+	for _, loc := range htmlTagRe.FindAllStringSubmatchIndex(s, -1) {
+		w.text(s[pos:loc[0]])
+		pos = loc[1]
+		closing := loc[3] > loc[2]
+		name := strings.ToLower(s[loc[4]:loc[5]])
+		attrs := s[loc[6]:loc[7]]
+		switch name {
+		case "br":
+			w.lineBreak()
+		case "p", "div", "blockquote", "ul", "ol":
+			w.endLine(2)
+		case "li":
+			if !closing {
+				w.endLine(1)
+				w.open("- ")
+			}
+		case "a":
+			if closing {
+				if n := len(closers); n > 0 {
+					w.close("[", closers[n-1])
+					closers = closers[:n-1]
+				}
+				continue
+			}
+			closer := ""
+			if m := htmlHrefRe.FindStringSubmatch(attrs); m != nil && strings.HasPrefix(m[1], "http") {
+				w.open("[")
+				closer = "](" + html.UnescapeString(m[1]) + ")"
+			}
+			closers = append(closers, closer)
+		case "span":
+			if closing {
+				if n := len(closers); n > 0 {
+					w.close("||", closers[n-1])
+					closers = closers[:n-1]
+				}
+				continue
+			}
+			closer := ""
+			if strings.Contains(attrs, "markdown_spoiler") {
+				w.open("||")
+				closer = "||"
+			}
+			closers = append(closers, closer)
+		default:
+			mark, ok := htmlInlineMarks[name]
+			if !ok {
+				continue
+			}
+			heading := strings.HasPrefix(name, "h")
+			if closing {
+				w.close(mark, mark)
+				if heading {
+					w.endLine(1)
+				}
+			} else {
+				if heading {
+					w.endLine(1)
+				}
+				w.open(mark)
+			}
+		}
+	}
+	w.text(s[pos:])
+	return strings.TrimSpace(string(w.out))
+}
+
+// markdownWriter assembles markdown while moving emphasis markers off the surrounding spaces,
+// since Discord does not render "* text *"
+type markdownWriter struct {
+	out []byte
+	// opening markup not yet followed by text
+	pending string
+}
+
+// text writes character data, collapsing whitespace and unescaping entities
+func (w *markdownWriter) text(t string) {
+	t = html.UnescapeString(htmlSpaceRe.ReplaceAllString(t, " "))
+	if w.atLineStart() || len(w.out) > 0 && w.out[len(w.out)-1] == ' ' {
+		t = strings.TrimLeft(t, " ")
+	}
+	if t == "" {
+		return
+	}
+	if w.pending != "" {
+		if strings.HasPrefix(t, " ") {
+			w.out = append(w.out, ' ')
+			t = t[1:]
+		}
+		if t == "" {
+			return
+		}
+		w.out = append(w.out, w.pending...)
+		w.pending = ""
+	}
+	w.out = append(w.out, t...)
+}
+
+// open queues the opening markup of an element until its first text is written
+func (w *markdownWriter) open(mark string) {
+	w.pending += mark
+}
+
+// close writes the closing markup of an element opened with open
+func (w *markdownWriter) close(open, mark string) {
+	if mark == "" {
+		return
+	}
+	// an element without text leaves no markup
+	if strings.HasSuffix(w.pending, open) {
+		w.pending = strings.TrimSuffix(w.pending, open)
+		return
+	}
+	trailing := len(w.out) > 0 && w.out[len(w.out)-1] == ' '
+	if trailing {
+		w.out = w.out[:len(w.out)-1]
+	}
+	w.out = append(w.out, mark...)
+	if trailing {
+		w.out = append(w.out, ' ')
+	}
+}
+
+// endLine makes sure the current line is followed by n line breaks (n = 2: a blank line)
+func (w *markdownWriter) endLine(n int) {
+	for have := w.trailingBreaks(); have >= 0 && have < n; have++ {
+		w.out = append(w.out, '\n')
+	}
+}
+
+// lineBreak adds a line break (<br>); consecutive ones leave at most one blank line
+func (w *markdownWriter) lineBreak() {
+	if have := w.trailingBreaks(); have >= 0 && have < 2 {
+		w.out = append(w.out, '\n')
+	}
+}
+
+// trailingBreaks drops trailing spaces and counts the line breaks ending the output, or returns
+// -1 while it is empty
+func (w *markdownWriter) trailingBreaks() int {
+	w.out = []byte(strings.TrimRight(string(w.out), " "))
+	if len(w.out) == 0 {
+		return -1
+	}
+	return len(w.out) - len(strings.TrimRight(string(w.out), "\n"))
+}
+
+func (w *markdownWriter) atLineStart() bool {
+	return len(w.out) == 0 || w.out[len(w.out)-1] == '\n'
+}
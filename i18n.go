@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// defaultLocale is the language of the built-in messages
+const defaultLocale = "en"
+
+// messageCatalog maps message keys to the fmt format strings of one language
+type messageCatalog map[string]string
+
+// defaultMessages are the built-in English messages. Catalogs of other languages may translate
+// any subset; missing keys fall back to these.
+//
+// The catalog covers what members see: the status command replies, permission errors, the
+// search results and notices, report template reminders, duplicate suggestions, solve votes and
+// the triage panel. The other replies of the administration commands (archive,
+// escalation, watch list, transcripts, /config) are only shown to moderators and stay English.
+var defaultMessages = messageCatalog{
+	// thread status commands
+	"command.no_permission":        "you don't have permission to run that command.",
+	"command.guild_only":           "This command only works in servers.",
+	"command.no_permission_tags":   "you don't have permission to list tags",
+	"command.updated_thread":       "Updated thread: %s",
+	"command.tag_missing":          "Tag %s not found in the forum. Please create it first.",
+	"command.timeout":              "command timed out (Discord API not responding)",
+	"command.rate_limited":         "⏱️ Discord rate limit reached. The bot is being throttled. Please wait a moment and try again.",
+	"command.bot_permissions":      "❌ Permission denied. The bot lacks the required permissions (Manage Threads, Manage Messages).",
+	"command.thread_not_found":     "⚠️ Thread or forum not found. The post may have been deleted.",
+	"command.discord_unavailable":  "🔧 Discord API is experiencing issues. Please try again in a moment.",
+	"command.update_failed":        "❌ Failed to update thread (Error %d). Check bot permissions or try again.",
+	"command.update_failed_nocode": "❌ Failed to update thread (unknown error). Please check logs or try again.",
+
+	// permission errors of the other commands and buttons
+	"permission.triage":       "you don't have permission to triage this thread",
+	"permission.jobs":         "you don't have permission to manage jobs",
+	"permission.search_stats": "you don't have permission to view search statistics",
+	"permission.watch":        "Only server administrators can change which forums the bot watches.",
+	"permission.setup":        "You need the Manage Server permission to change the bot's setup.",
+	"permission.config":       "You need the Manage Server permission to export or import settings.",
+
+	// search results
+	"search.format":              "Format",
+	"search.status":              "Status",
+	"search.episodes":            "Episodes",
+	"search.chapters":            "Chapters",
+	"search.chapters_volumes":    "%d (%d volumes)",
+	"search.volumes":             "Volumes",
+	"search.score":               "Score",
+	"search.popularity":          "Popularity",
+	"search.users":               "%d users",
+	"search.season":              "Season",
+	"search.start_date":          "Start date",
+	"search.links":               "Official links",
+	"search.source":              "Data from %s",
+	"search.trailer":             "Watch trailer",
+	"search.trailer_of":          "Trailer: %s",
+	"search.pick":                "Not the right title? Pick another match",
+	"search.pick_expired":        "This search has expired, please search again.",
+	"search.pick_not_yours":      "Only the person who searched can pick another match.",
+	"search.did_you_mean":        "No results for %q. Did you mean %s?",
+	"search.or":                  " or ",
+	"search.repeated":            "**%s** was looked up here <t:%d:R>: %s",
+	"search.limit_channel":       "Lots of lookups in this channel right now, please try again in a minute.",
+	"search.limit_user":          "You've reached today's limit of %d lookups; it resets at <t:%d:t>.",
+	"search.low_confidence":      "⚠️ *Low-confidence match, this may not be the title you meant.*",
+	"search.also_anime":          "Also an anime",
+	"search.also_manga":          "Also a manga",
+	"search.where_to_read":       "Where to read",
+	"search.licensed":            "Licensed in English: %s",
+	"search.unlicensed":          "Not licensed in English",
+	"search.publisher":           "Original publisher: %s",
+	"search.no_results_on":       "No results for %q on %s.",
+	"search.title_or_id":         "Give a title or an AniList ID to look up.",
+	"search.opted_out":           "Your messages will no longer trigger lookups. /anime and /manga still work; use `/searchoptout resume:true` to undo.",
+	"search.opted_in":            "Your messages will trigger lookups again.",
+	"prefs.save_failed":          "Could not save your preference, please try again later.",
+	"prefs.tracker_saved":        "/anime and /manga will now link to %s by default.",
+	"prefs.title_language_saved": "Lookups will now show %s titles where known.",
+	"prefs.title_language_reset": "Lookups will show titles in the server's language (%s) again.",
+
+	// report templates, duplicate suggestions, solve votes and the triage panel
+	"report.missing":         "<@%s> your report is missing some information we need to help you:\n- %s\nPlease reply in this thread with the details.",
+	"report.still_missing":   "Thanks! Still missing:\n- %s",
+	"report.complete":        "Thanks, your report now has everything we need.",
+	"duplicates.related":     "🔎 Possibly related existing reports:",
+	"duplicates.check_first": "If one of these answers your question, please check it first.",
+	"duplicates.mark":        "Mark as duplicate",
+	"vote.solved_by_op":      "%s Marked as solved by the original poster. Fix: %s",
+	"vote.solved_by_vote":    "%s Marked as solved by community vote. Fix: %s",
+	"triage.title":           "Triage",
+	"triage.open":            "Open",
+	"triage.status":          "Status: **%s**\nUpdated <t:%d:R>",
+	"triage.status_by":       "Status: **%s**\nUpdated <t:%d:R> by <@%s>",
+	"triage.lock":            "Close & lock",
+}
+
+// localizer formats messages in one language
+type localizer struct {
+	catalog   messageCatalog
+	templates map[string]*template.Template
+}
+
+// T formats the message key with args, in English when the catalog does not translate it
+func (l localizer) T(key string, args ...interface{}) string {
+	format, ok := l.catalog[key]
+	if !ok {
+		if format, ok = defaultMessages[key]; !ok {
+			format = key
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// localizer returns the localizer of a channel: the first of channelIDs (e.g. a thread, then its
+// forum) with a channel_locales entry, else the guild's guild_locales entry, else locale
+func (h *handler) localizer(guildID string, channelIDs ...string) localizer {
+	cfg := h.cfg()
+	if cfg == nil {
+		return localizer{}
+	}
+	locale := cfg.Locale
+	if l, ok := cfg.GuildLocales[guildID]; ok && guildID != "" {
+		locale = l
+	}
+	for _, id := range channelIDs {
+		if l, ok := cfg.ChannelLocales[id]; ok && id != "" {
+			locale = l
+			break
+		}
+	}
+	return localizer{catalog: cfg.catalogs[locale], templates: cfg.forGuild(guildID).templates}
+}
+
+// channelLocalizer returns the localizer of a channel looked up by ID, see localizer
+func (h *handler) channelLocalizer(s *discordgo.Session, channelID string) localizer {
+	ch, err := s.State.Channel(channelID)
+	if err != nil {
+		ch, err = s.Channel(channelID)
+	}
+	if err != nil {
+		return h.localizer("", channelID)
+	}
+	return h.localizer(ch.GuildID, ch.ID, ch.ParentID)
+}
+
+// loadCatalogs reads the message catalogs in dir, one YAML map of message keys per language
+// named after its locale (de.yaml, pt-BR.yaml). Unknown keys are rejected so typos surface at
+// startup.
+func loadCatalogs(dir string) (map[string]messageCatalog, error) {
+	catalogs := map[string]messageCatalog{defaultLocale: defaultMessages}
+	if dir == "" {
+		return catalogs, nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var cat messageCatalog
+		if err := yaml.Unmarshal(b, &cat); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		var unknown []string
+		for key := range cat {
+			if _, ok := defaultMessages[key]; !ok {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return nil, fmt.Errorf("%s: unknown message keys %s", path, strings.Join(unknown, ", "))
+		}
+		// a translation with other placeholders would render e.g. "%!d(MISSING)"
+		for key, format := range cat {
+			if got, want := formatVerbs(format), formatVerbs(defaultMessages[key]); got != want {
+				return nil, fmt.Errorf("%s: %s: the placeholders %s differ from those of the English message (%s)", path, key, verbList(got), verbList(want))
+			}
+		}
+		catalogs[strings.TrimSuffix(filepath.Base(path), ".yaml")] = cat
+	}
+	return catalogs, nil
+}
+
+// formatVerbRe matches a fmt verb with its optional argument index, flags, width and precision
+var formatVerbRe = regexp.MustCompile(`%(?:\[(\d+)\])?[-+# 0]*\d*(?:\.\d*)?([a-zA-Z%])`)
+
+// formatVerbs returns the verbs of a format string in argument order, e.g. "sd" for "%s: %d", so
+// translations may reorder arguments with explicit indexes ("%[2]d %[1]s")
+func formatVerbs(format string) string {
+	var verbs []byte
+	next := 0
+	for _, m := range formatVerbRe.FindAllStringSubmatch(format, -1) {
+		if m[2] == "%" {
+			continue
+		}
+		if m[1] != "" {
+			n, _ := strconv.Atoi(m[1])
+			next = n - 1
+		}
+		for len(verbs) <= next {
+			verbs = append(verbs, '?')
+		}
+		verbs[next] = m[2][0]
+		next++
+	}
+	return string(verbs)
+}
+
+// verbList formats the verbs returned by formatVerbs for an error message, e.g. "%s %d"
+func verbList(verbs string) string {
+	if verbs == "" {
+		return "none"
+	}
+	parts := make([]string, len(verbs))
+	for i := range verbs {
+		parts[i] = "%" + verbs[i:i+1]
+	}
+	return strings.Join(parts, " ")
+}
+
+// checkLocales loads the catalogs of locales_dir and verifies every configured locale has one
+func (cfg *Config) checkLocales() error {
+	if cfg.LocalesDir != "" {
+		if _, err := os.Stat(cfg.LocalesDir); err != nil {
+			return fmt.Errorf("locales_dir: %v", err)
+		}
+	}
+	catalogs, err := loadCatalogs(cfg.LocalesDir)
+	if err != nil {
+		return fmt.Errorf("locales_dir: %v", err)
+	}
+	cfg.catalogs = catalogs
+	if cfg.Locale == "" {
+		cfg.Locale = defaultLocale
+	}
+	check := func(field, locale string) error {
+		if _, ok := catalogs[locale]; !ok {
+			return fmt.Errorf("%s: no catalog for locale %q in locales_dir", field, locale)
+		}
+		return nil
+	}
+	if err := check("locale", cfg.Locale); err != nil {
+		return err
+	}
+	for id, l := range cfg.GuildLocales {
+		if err := check("guild_locales["+id+"]", l); err != nil {
+			return err
+		}
+	}
+	for id, l := range cfg.ChannelLocales {
+		if err := check("channel_locales["+id+"]", l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
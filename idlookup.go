@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+)
+
+// idQueryRe matches the {id:12345} / <id:12345> lookup syntax, which fetches an AniList entry by
+// ID instead of searching
+var idQueryRe = regexp.MustCompile(`(?i)^\s*id\s*:\s*(\d{1,9})\s*$`)
+
+// parseIDQuery returns the AniList ID of an id:<number> query
+func parseIDQuery(name string) (int, bool) {
+	m := idQueryRe.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	id, err := strconv.Atoi(m[1])
+	return id, err == nil && id > 0
+}
+
+// mediaTypeOfFormat tells anime from manga by AniList format, for ID lookups of either type
+func mediaTypeOfFormat(format string) string {
+	switch format {
+	case "MANGA", "NOVEL", "ONE_SHOT":
+		return "MANGA"
+	}
+	return "ANIME"
+}
+
+// lookupByID fetches an AniList entry by ID. mediaTypeAny accepts either type; the type of the
+// entry is returned with it. Blocked titles are not returned.
+func (h *handler) lookupByID(id int, mediaType string, allowAdult bool) (*aniListMedia, string, error) {
+	queryType := mediaType
+	if mediaType == mediaTypeAny {
+		queryType = ""
+	}
+	media, err := fetchAniListByID(id, queryType, allowAdult)
+	recordProviderResult(trackerAniList, err, media != nil)
+	if err != nil || media == nil {
+		return nil, mediaType, err
+	}
+	media.Provider = trackerAniList
+	if h.blocked(media) {
+		log.Printf("search: AniList ID %d is blocked", id)
+		return nil, mediaType, nil
+	}
+	if mediaType == mediaTypeAny {
+		mediaType = mediaTypeOfFormat(media.Format)
+	}
+	return media, mediaType, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// slashCommand pairs an application command definition with its handler
+type slashCommand struct {
+	def *discordgo.ApplicationCommand
+	run func(h *handler, s *discordgo.Session, i *discordgo.InteractionCreate)
+}
+
+// slashCommands holds every registered application command keyed by name. Features add
+// themselves from an init function via registerSlashCommand.
+var slashCommands = map[string]slashCommand{}
+
+func registerSlashCommand(def *discordgo.ApplicationCommand, run func(h *handler, s *discordgo.Session, i *discordgo.InteractionCreate)) {
+	slashCommands[def.Name] = slashCommand{def: def, run: run}
+}
+
+// dmCommands are the slash commands offered in direct messages when search_in_dms is on; every
+// other command only works in servers
+var dmCommands = map[string]bool{}
+
+// allowInDMs marks slash commands that work in direct messages with the bot
+func allowInDMs(names ...string) {
+	for _, n := range names {
+		dmCommands[n] = true
+	}
+}
+
+// componentHandlers maps a custom ID prefix of buttons/select menus to its handler
+var componentHandlers = map[string]func(h *handler, s *discordgo.Session, i *discordgo.InteractionCreate){}
+
+func registerComponentHandler(prefix string, run func(h *handler, s *discordgo.Session, i *discordgo.InteractionCreate)) {
+	componentHandlers[prefix] = run
+}
+
+// autocompleteHandlers maps a slash command name to the handler suggesting values for its options
+var autocompleteHandlers = map[string]func(h *handler, s *discordgo.Session, i *discordgo.InteractionCreate){}
+
+func registerAutocomplete(command string, run func(h *handler, s *discordgo.Session, i *discordgo.InteractionCreate)) {
+	autocompleteHandlers[command] = run
+}
+
+// onReady registers the application commands once the gateway session is established
+func (h *handler) onReady(s *discordgo.Session, r *discordgo.Ready) {
+	h.registerCommands(s, r.User.ID)
+}
+
+// registerCommands publishes the application command table, which depends on the configuration
+// (e.g. which commands are offered in DMs)
+func (h *handler) registerCommands(s *discordgo.Session, appID string) {
+	defs := make([]*discordgo.ApplicationCommand, 0, len(slashCommands))
+	for name, c := range slashCommands {
+		def := *c.def
+		inDMs := h.cfg().SearchInDMs && dmCommands[name]
+		def.DMPermission = &inDMs
+		defs = append(defs, &def)
+	}
+	if _, err := s.ApplicationCommandBulkOverwrite(appID, "", defs); err != nil {
+		log.Printf("failed to register slash commands: %v", err)
+		return
+	}
+	log.Printf("registered %d slash commands", len(defs))
+}
+
+// onInteractionCreate dispatches slash commands and message components to their handlers
+func (h *handler) onInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		name := i.ApplicationCommandData().Name
+		c, ok := slashCommands[name]
+		if !ok {
+			return
+		}
+		if i.GuildID == "" && !(h.cfg().SearchInDMs && dmCommands[name]) {
+			respondEphemeral(s, i, h.localizer("", i.ChannelID).R("command.guild_only", replyData{User: interactionUser(i).Mention()}))
+			return
+		}
+		if statefulCommands[name] && h.storeDegraded() {
+			respondEphemeral(s, i, degradedNotice)
+			return
+		}
+		c.run(h, s, i)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		if run, ok := autocompleteHandlers[i.ApplicationCommandData().Name]; ok {
+			run(h, s, i)
+		}
+	case discordgo.InteractionMessageComponent:
+		id := i.MessageComponentData().CustomID
+		for prefix, run := range componentHandlers {
+			if strings.HasPrefix(id, prefix) {
+				run(h, s, i)
+				return
+			}
+		}
+	}
+}
+
+// interactionUser returns the user who triggered an interaction in a guild or DM
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// interactionCanManage applies the same moderator check as the dot commands to an interaction
+func (h *handler) interactionCanManage(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	if i.Member == nil || i.Member.User == nil {
+		return false
+	}
+	ch, err := s.Channel(i.ChannelID)
+	if err != nil {
+		log.Printf("failed to fetch channel for interaction: %v", err)
+		return false
+	}
+	has, err := h.userCanManagePosts(s, i.Member.User.ID, ch)
+	if err != nil {
+		log.Printf("permission check failed: %v", err)
+		return false
+	}
+	return has
+}
+
+// respondEphemeral answers an interaction with a message only the invoking user can see
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("failed to respond to interaction: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// issueRefRe matches "#1234" references; channel mentions (<#id>) are excluded by the leading boundary
+var issueRefRe = regexp.MustCompile(`(?:^|[\s(])#(\d{1,6})\b`)
+
+// maxIssueRefs bounds how many references of one message are expanded
+const maxIssueRefs = 3
+
+// githubIssue is the subset of the GitHub issue object the bot shows
+type githubIssue struct {
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	State       string    `json:"state"`
+	HTMLURL     string    `json:"html_url"`
+	PullRequest *struct{} `json:"pull_request"`
+	StateReason string    `json:"state_reason"`
+}
+
+// line formats an issue as a one-line summary with a state marker
+func (gi githubIssue) line() string {
+	marker := "🟢"
+	if gi.State == "closed" {
+		marker = "🟣"
+		if gi.StateReason == "not_planned" {
+			marker = "⚪"
+		}
+	}
+	kind := "Issue"
+	if gi.PullRequest != nil {
+		kind = "PR"
+	}
+	return fmt.Sprintf("%s [%s #%d](%s) %s (%s)", marker, kind, gi.Number, gi.HTMLURL, gi.Title, gi.State)
+}
+
+// issueRepo returns the repository searched by `.issue`: escalation_repo, else github_repo
+func (h *handler) issueRepo() string {
+	if h.cfg().EscalationRepo != "" {
+		return h.cfg().EscalationRepo
+	}
+	return h.cfg().GitHubRepo
+}
+
+// handleIssueSearch implements `.issue <query>`: search the repository's issues and reply with
+// the matches, or show a single issue when the query is a number
+func (h *handler) handleIssueSearch(s *discordgo.Session, m *discordgo.MessageCreate, query string) {
+	repo := h.issueRepo()
+	if repo == "" {
+		return
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		replyMessage(s, m.Message, fmt.Sprintf("usage: %[1]s <words from the bug title> or %[1]s <number>", h.cfg().forGuild(m.GuildID).command("issue")))
+		return
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(query, "#")); err == nil {
+		gi, err := fetchIssue(h.cfg().GitHubToken, repo, n)
+		if err != nil || gi == nil {
+			replyMessage(s, m.Message, fmt.Sprintf("No issue #%d in %s.", n, repo))
+			return
+		}
+		h.sendIssueEmbed(s, m, []githubIssue{*gi})
+		return
+	}
+
+	var result struct {
+		TotalCount int           `json:"total_count"`
+		Items      []githubIssue `json:"items"`
+	}
+	q := url.QueryEscape(query + " repo:" + repo + " is:issue")
+	if _, err := githubRequest(context.Background(), h.cfg().GitHubToken, "GET", "/search/issues?per_page=30&q="+q, nil, &result); err != nil {
+		log.Printf("issue: search for %q failed: %v", query, err)
+		replyMessage(s, m.Message, "❌ GitHub search failed, try again later.")
+		return
+	}
+	if len(result.Items) == 0 {
+		replyMessage(s, m.Message, fmt.Sprintf("No issues in %s match %q.", repo, query))
+		return
+	}
+	lines := make([]string, 0, len(result.Items))
+	for _, gi := range result.Items {
+		lines = append(lines, gi.line())
+	}
+	title := fmt.Sprintf("%s issues matching %q (%d)", repo, query, result.TotalCount)
+	if err := sendPaged(s, m.ChannelID, embedPages(title, lines, 10)); err != nil {
+		log.Printf("issue: failed to send results: %v", err)
+	}
+}
+
+// fetchIssue returns one issue or pull request, or nil when it does not exist
+func fetchIssue(token, repo string, number int) (*githubIssue, error) {
+	var gi githubIssue
+	resp, err := githubRequest(context.Background(), token, "GET", fmt.Sprintf("/repos/%s/issues/%d", repo, number), nil, &gi)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == 404 {
+		return nil, nil
+	}
+	return &gi, nil
+}
+
+// expandIssueReferences replies with the title and state of "#1234" references in messages posted
+// in watched forum threads when issue_references is enabled
+func (h *handler) expandIssueReferences(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel) {
+	repo := h.issueRepo()
+	if !h.cfg().IssueReferences || repo == "" || !isThreadChannel(ch) || !h.inWatchedForum(ch) {
+		return
+	}
+	matches := issueRefRe.FindAllStringSubmatch(m.Content, -1)
+	if len(matches) == 0 {
+		return
+	}
+	var found []githubIssue
+	seen := map[int]bool{}
+	for _, match := range matches {
+		n, _ := strconv.Atoi(match[1])
+		if seen[n] || len(seen) >= maxIssueRefs {
+			continue
+		}
+		seen[n] = true
+		gi, err := fetchIssue(h.cfg().GitHubToken, repo, n)
+		if err != nil {
+			log.Printf("issue: failed to fetch %s#%d: %v", repo, n, err)
+			continue
+		}
+		if gi != nil {
+			found = append(found, *gi)
+		}
+	}
+	if len(found) > 0 {
+		h.sendIssueEmbed(s, m, found)
+	}
+}
+
+func (h *handler) sendIssueEmbed(s *discordgo.Session, m *discordgo.MessageCreate, issues []githubIssue) {
+	lines := make([]string, 0, len(issues))
+	for _, gi := range issues {
+		lines = append(lines, gi.line())
+	}
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{{Description: strings.Join(lines, "\n"), Color: 0x24292e}},
+	}, m.Message))
+	if err != nil {
+		log.Printf("issue: failed to send issue summary: %v", err)
+	}
+}
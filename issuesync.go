@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// issueURLRe matches links to GitHub issues
+var issueURLRe = regexp.MustCompile(`https://github\.com/([\w.-]+/[\w.-]+)/issues/(\d+)`)
+
+// recordIssueLink links a watched thread to an issue of the configured repository when someone
+// pastes its URL, so the issue sync can follow it. The first linked issue of a thread wins.
+func (h *handler) recordIssueLink(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel) {
+	repo := h.issueRepo()
+	if !h.cfg().IssueSync.Enabled || repo == "" || !isThreadChannel(ch) || !h.inWatchedForum(ch) {
+		return
+	}
+	match := issueURLRe.FindStringSubmatch(m.Content)
+	if match == nil || !strings.EqualFold(match[1], repo) {
+		return
+	}
+	var existing issueLink
+	if found, err := h.store.Get(issueLinksBucket, ch.ID, &existing); err != nil || found {
+		return
+	}
+	n, _ := strconv.Atoi(match[2])
+	link := issueLink{Repo: repo, Number: n, URL: match[0], LinkedBy: m.Author.ID, LinkedAt: time.Now()}
+	if err := h.store.Put(issueLinksBucket, ch.ID, link); err != nil {
+		log.Printf("issue sync: failed to link %s to %s: %v", ch.ID, link.URL, err)
+		return
+	}
+	if err := s.MessageReactionAdd(m.ChannelID, m.ID, "🔗"); err != nil {
+		log.Printf("issue sync: failed to acknowledge link in %s: %v", ch.ID, err)
+	}
+}
+
+// syncIssueStatus is the `github-issue-sync` job: it checks the issues linked to threads and,
+// once one is closed, posts a notice in the thread and applies the configured status
+func (h *handler) syncIssueStatus(ctx context.Context, job jobRecord) error {
+	links, err := h.store.List(issueLinksBucket)
+	if err != nil {
+		return err
+	}
+	for threadID := range links {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var link issueLink
+		if found, err := h.store.Get(issueLinksBucket, threadID, &link); err != nil || !found || link.Closed {
+			continue
+		}
+		gi, err := fetchIssue(h.cfg().GitHubToken, link.Repo, link.Number)
+		if err != nil {
+			log.Printf("issue sync: failed to fetch %s#%d: %v", link.Repo, link.Number, err)
+			continue
+		}
+		if gi == nil {
+			// the issue was deleted or transferred; stop following it
+			if err := h.store.Delete(issueLinksBucket, threadID); err != nil {
+				log.Printf("issue sync: failed to forget link of %s: %v", threadID, err)
+			}
+			continue
+		}
+		if gi.State != "closed" {
+			continue
+		}
+		h.announceIssueClosed(threadID, *gi)
+		link.Closed = true
+		if err := h.store.Put(issueLinksBucket, threadID, link); err != nil {
+			log.Printf("issue sync: failed to update link of %s: %v", threadID, err)
+		}
+	}
+	return nil
+}
+
+// announceIssueClosed posts the closing notice and applies the status configured for the reason
+func (h *handler) announceIssueClosed(threadID string, gi githubIssue) {
+	s := h.dg
+	reason := "completed"
+	status := h.cfg().IssueSync.CompletedStatus
+	if gi.StateReason == "not_planned" {
+		reason, status = "closed as not planned", h.cfg().IssueSync.NotPlannedStatus
+	}
+	sendMessage(s, threadID, fmt.Sprintf("🔔 The linked GitHub issue was %s: %s", reason, gi.HTMLURL))
+	if status == "" {
+		return
+	}
+	ch, err := s.Channel(threadID)
+	if err != nil {
+		log.Printf("issue sync: failed to fetch thread %s: %v", threadID, err)
+		return
+	}
+	if _, ok := h.applyStatus(s, ch, status); ok {
+		h.refreshTriagePanel(s, threadID, status, s.State.User.ID)
+		log.Printf("issue sync: marked %s as %s after %s was closed", threadID, status, gi.HTMLURL)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	jobOption := &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "job",
+		Description: "Job ID as shown by /jobs list",
+		Required:    true,
+	}
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "jobs",
+		Description: "Inspect and control scheduled bot jobs",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "list", Description: "List scheduled jobs"},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "run", Description: "Run a job now", Options: []*discordgo.ApplicationCommandOption{jobOption}},
+			{Type: discordgo.ApplicationCommandOptionSubCommand, Name: "pause", Description: "Pause or resume a job", Options: []*discordgo.ApplicationCommandOption{
+				jobOption,
+				{Type: discordgo.ApplicationCommandOptionBoolean, Name: "resume", Description: "Resume instead of pausing"},
+			}},
+		},
+	}, (*handler).handleJobsCommand)
+}
+
+// registerJobs registers the recurring jobs of every enabled feature with the scheduler
+func (h *handler) registerJobs() {
+	recurring := func(id, schedule string, fn jobFunc) {
+		h.sched.Handle(id, fn)
+		if err := h.sched.Recurring(id, id, schedule, h.cfg()); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+	}
+
+	recurring("thread-index-sync", "@every 6h", h.syncThreadIndex)
+	recurring("thread-index-save", "@every 1m", h.saveThreadIndex)
+	recurring("airing-notify", "@every 5m", h.notifyAiring)
+	recurring("search-stats-save", "@every 1m", h.saveSearchStats)
+	recurring("processed-messages-prune", "@every 15m", h.pruneProcessedMessages)
+	h.sched.Handle(autoCloseJobKind, h.runAutoClose)
+	if h.cfg().HeartbeatChannelID != "" {
+		recurring("heartbeat", "@hourly", h.postHeartbeat)
+	}
+	if h.cfg().IssueSync.Enabled && h.issueRepo() != "" {
+		recurring("github-issue-sync", "@every 15m", h.syncIssueStatus)
+	}
+	if h.cfg().Releases.ChannelID != "" && h.cfg().Releases.Repo != "" {
+		recurring("release-watch", "@every 10m", h.watchReleases)
+	}
+	if h.cfg().Nightly.ChannelID != "" && h.cfg().Nightly.Workflow != "" && h.cfg().Nightly.Repo != "" {
+		recurring("nightly-watch", "@every 15m", h.watchNightly)
+	}
+	if h.cfg().Parsers.Enabled {
+		recurring("parser-scan", "@every 3h", h.scanParsers)
+	}
+	if h.cfg().GitHubClientID != "" && h.cfg().hasContributorRole() {
+		recurring("github-role-sync", "@every 6h", h.syncGitHubRoles)
+	}
+	if len(h.cfg().StatusMonitor.Services) > 0 {
+		recurring("status-probe", "@every 2m", h.probeServices)
+	}
+	if len(h.cfg().Retention) > 0 {
+		recurring("retention-sweep", "@hourly", h.sweepRetention)
+	}
+	if h.cfg().Archive.Backend == "local" && h.cfg().Archive.RetentionDays > 0 {
+		recurring("archive-prune", "@daily", h.pruneLocalArchives)
+	}
+}
+
+// handleJobsCommand implements /jobs list|run|pause (moderators only)
+func (h *handler) handleJobsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !h.interactionCanManage(s, i) {
+		respondEphemeral(s, i, h.interactionReply(s, i, "permission.jobs"))
+		return
+	}
+	if h.sched == nil {
+		respondEphemeral(s, i, "the scheduler is not running")
+		return
+	}
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	sub := data.Options[0]
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range sub.Options {
+		opts[o.Name] = o
+	}
+
+	switch sub.Name {
+	case "list":
+		jobs, running := h.sched.List()
+		if len(jobs) == 0 {
+			respondEphemeral(s, i, "No jobs are scheduled.")
+			return
+		}
+		sb := &strings.Builder{}
+		// one-shot timers (auto-close, ...) can be many, so they are summed up per kind
+		pending := map[string][]jobRecord{}
+		for _, j := range jobs {
+			if j.Schedule == "" {
+				pending[j.Kind] = append(pending[j.Kind], j)
+				continue
+			}
+			state := "active"
+			if j.Paused {
+				state = "paused"
+			}
+			if running[j.ID] > 0 {
+				state = fmt.Sprintf("running (%d)", running[j.ID])
+			}
+			schedule := j.Schedule
+			if schedule == "" {
+				schedule = "once"
+			}
+			sb.WriteString(fmt.Sprintf("- `%s` [%s] %s", j.ID, schedule, state))
+			if !j.NextRun.IsZero() {
+				sb.WriteString(fmt.Sprintf(", next <t:%d:R>", j.NextRun.Unix()))
+			}
+			if !j.LastRun.IsZero() {
+				sb.WriteString(fmt.Sprintf(", last <t:%d:R>", j.LastRun.Unix()))
+			}
+			if j.LastError != "" {
+				sb.WriteString(fmt.Sprintf(", last error: %s", j.LastError))
+			}
+			sb.WriteString("\n")
+		}
+		kinds := make([]string, 0, len(pending))
+		for kind := range pending {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			next := time.Time{}
+			for _, j := range pending[kind] {
+				if !j.NextRun.IsZero() && (next.IsZero() || j.NextRun.Before(next)) {
+					next = j.NextRun
+				}
+			}
+			sb.WriteString(fmt.Sprintf("- `%s` %d pending", kind, len(pending[kind])))
+			if !next.IsZero() {
+				sb.WriteString(fmt.Sprintf(", next <t:%d:R>", next.Unix()))
+			}
+			sb.WriteString("\n")
+		}
+		respondEphemeral(s, i, sb.String())
+	case "run":
+		id := opts["job"].StringValue()
+		if err := h.sched.RunNow(id); err != nil {
+			respondEphemeral(s, i, err.Error())
+			return
+		}
+		respondEphemeral(s, i, fmt.Sprintf("Started job `%s` at <t:%d:T>.", id, time.Now().Unix()))
+	case "pause":
+		id := opts["job"].StringValue()
+		resume := false
+		if o, ok := opts["resume"]; ok {
+			resume = o.BoolValue()
+		}
+		if err := h.sched.SetPaused(id, !resume); err != nil {
+			respondEphemeral(s, i, err.Error())
+			return
+		}
+		if resume {
+			respondEphemeral(s, i, fmt.Sprintf("Resumed job `%s`.", id))
+		} else {
+			respondEphemeral(s, i, fmt.Sprintf("Paused job `%s`.", id))
+		}
+	}
+}
@@ -1,28 +1,41 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 func main() {
-	cfg, err := LoadConfig("config.yaml")
+	opts, err := parseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	setupFromFlags(opts)
+	if opts.validate {
+		os.Exit(validateConfig(opts.configPath, opts.live))
+	}
+
+	cfg, err := LoadConfig(opts.configPath)
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 	token := cfg.DiscordToken
 	if token == "" {
-		log.Fatal("Discord token required via config.yaml or DISCORD_TOKEN env var")
-	}
-	watchedMap := map[string]bool{}
-	for _, id := range cfg.ForumParentIDs {
-		watchedMap[strings.TrimSpace(id)] = true
+		log.Fatalf("Discord token required via %s, DISCORD_TOKEN or DISCORD_TOKEN_FILE env var", opts.configPath)
 	}
+	watchedMap := cfg.watchedParents()
 
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
@@ -33,11 +46,98 @@ func main() {
 	dg.ShouldRetryOnRateLimit = true
 
 	// ensure gateway intents include message content so the bot can read command messages
-	dg.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsGuildMessageReactions | discordgo.IntentsMessageContent
+	dg.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsGuildMessageReactions | discordgo.IntentsMessageContent
+	if cfg.SearchInDMs {
+		dg.Identify.Intents |= discordgo.IntentsDirectMessages
+	}
+
+	// a missing or broken state file must not keep the bot from handling status commands
+	store := openRecoveringStore(func() (Store, error) {
+		switch cfg.StoreBackend {
+		case storeSQLite:
+			st, err := openSQLiteStore(cfg.DataPath)
+			if err != nil {
+				return nil, fmt.Errorf("database %s: %v", cfg.DataPath, err)
+			}
+			return st, nil
+		case storeBolt:
+			st, err := openBoltStore(cfg.DataPath)
+			if err != nil {
+				return nil, fmt.Errorf("state file %s: %v", cfg.DataPath, err)
+			}
+			return st, nil
+		case storePostgres:
+			// the connection string is not logged, it usually holds the password
+			st, err := openPostgresStore(cfg.DatabaseURL, cfg.DataPath)
+			if err != nil {
+				return nil, fmt.Errorf("postgres database: %v", err)
+			}
+			return st, nil
+		}
+		fs, err := openFileStore(cfg.DataPath)
+		if err != nil {
+			return nil, fmt.Errorf("state file %s: %v", cfg.DataPath, err)
+		}
+		return fs, nil
+	})
+	defer store.Close()
+	overrides, err := loadOverrides(cfg.OverridesPath)
+	if err != nil {
+		log.Fatalf("failed to load runtime overrides: %v", err)
+	}
+	migrateStoredOverrides(store, overrides, cfg.OverridesPath)
+	withOverrides, err := overrides.apply(cfg, watchedMap)
+	if err != nil {
+		log.Fatalf("failed to load runtime overrides from %s: %v", cfg.OverridesPath, err)
+	}
+	cfg = withOverrides
+	searchCache.configure(cfg.SearchCache.ttl, cfg.SearchCache.Size)
+	if cfg.Cache == cacheRedis {
+		// without Redis each replica keeps its own hot state, which only loosens limits and cooldowns
+		if c, err := openRedisCache(cfg.RedisURL, cfg.RedisPrefix); err != nil {
+			log.Printf("cache: Redis unavailable, keeping hot state in memory: %v", err)
+		} else {
+			sharedCache = c
+			defer c.Close()
+		}
+	}
+	aniListToken.Store(cfg.AniListToken)
+	sched := newScheduler(store, cfg.scheduleLocation())
+
+	archive, err := newArchiveSink(cfg.Archive)
+	if err != nil {
+		log.Fatalf("invalid archive config: %v", err)
+	}
+	if dryRun && archive != nil {
+		log.Printf("dry run: archive storage is disabled")
+		archive = nil
+	}
+
+	index, err := openThreadIndex(cfg.ThreadIndexPath)
+	if err != nil {
+		log.Fatalf("failed to open thread index %s: %v", cfg.ThreadIndexPath, err)
+	}
+
+	h := &handler{dg: dg, watchedParents: watchedMap, overrides: overrides, token: token, store: store, sched: sched, archive: archive, index: index, voteSolving: map[string]bool{}, serviceHealth: map[string]*serviceHealth{}}
 
-	h := &handler{dg: dg, watchedParents: watchedMap, token: token, cfg: cfg}
+	h.liveCfg.Store(cfg)
+
+	store.OnRecover(h.onStoreRecovered)
+
+	h.registerJobs()
 
 	dg.AddHandler(h.onMessageCreate)
+	dg.AddHandler(h.onMessageReactionAdd)
+	dg.AddHandler(h.onSearchReaction)
+	dg.AddHandler(h.onThreadCreate)
+	dg.AddHandler(h.onThreadUpdate)
+	dg.AddHandler(h.onThreadIndexUpdate)
+	dg.AddHandler(h.onThreadIndexDelete)
+	dg.AddHandler(h.onForumUpdate)
+	dg.AddHandler(h.onStarterMessageUpdate)
+	dg.AddHandler(h.onReady)
+	dg.AddHandler(h.onGuildCreate)
+	dg.AddHandler(h.onInteractionCreate)
 
 	if err := dg.Open(); err != nil {
 		log.Fatalf("error opening connection: %v", err)
@@ -62,18 +162,53 @@ func main() {
 		}
 	}
 
+	if h.storeDegraded() && cfg.HeartbeatChannelID != "" {
+		sendMessage(dg, cfg.HeartbeatChannelID, "⚠️ Started without persistent storage (see logs). Status commands work; stateful features are disabled until the store is available again.")
+	}
+
+	sched.Start()
+	defer sched.Stop()
+	// build the thread index right away on first start instead of waiting for the first sync
+	if index.size() == 0 {
+		if err := sched.RunNow("thread-index-sync"); err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+	}
+
+	stopWatch := h.watchConfig(opts.configPath)
+	defer stopWatch()
+
 	log.Printf("Bot is now running. Watching %d forum parents. Press CTRL-C to exit.", len(watchedMap))
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
 	log.Println("Shutting down")
+	if err := index.save(); err != nil {
+		log.Printf("failed to save thread index: %v", err)
+	}
 }
 
 // handler holds runtime state
 type handler struct {
-	dg             *discordgo.Session
-	watchedParents map[string]bool
-	token          string
-	cfg            *Config
+	dg *discordgo.Session
+	// watched forum parents mapped to their guild ("" until looked up); a guild without any
+	// watches all of its forums
+	watchedParents map[string]string
+	// settings changed with admin commands, kept in overrides_path (guarded by mu)
+	overrides   *runtimeOverrides
+	overridesMu sync.Mutex
+	token       string
+	// live configuration, swapped as a whole when config.yaml is reloaded; read through cfg()
+	liveCfg atomic.Pointer[Config]
+	store   Store
+	sched   *scheduler
+	archive archiveSink
+	index   *threadIndex
+
+	mu sync.Mutex
+	// voteSolving tracks threads currently being marked solved by a reaction vote
+	voteSolving map[string]bool
+	// serviceHealth holds the status monitor's probe results keyed by service name
+	serviceHealth map[string]*serviceHealth
 }
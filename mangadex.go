@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// mangadexManga is the subset of a MangaDex manga object (with the cover_art relationship) used for embeds
+type mangadexManga struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Title         map[string]string   `json:"title"`
+		AltTitles     []map[string]string `json:"altTitles"`
+		Description   map[string]string   `json:"description"`
+		Status        string              `json:"status"`
+		Year          int                 `json:"year"`
+		ContentRating string              `json:"contentRating"`
+		Tags          []struct {
+			Attributes struct {
+				Name  map[string]string `json:"name"`
+				Group string            `json:"group"`
+			} `json:"attributes"`
+		} `json:"tags"`
+	} `json:"attributes"`
+	Relationships []struct {
+		Type       string `json:"type"`
+		Attributes struct {
+			FileName string `json:"fileName"`
+		} `json:"attributes"`
+	} `json:"relationships"`
+}
+
+// toMedia converts the title; the cover is left out of adult titles unless allowAdult
+func (d mangadexManga) toMedia(allowAdult bool) *aniListMedia {
+	a := d.Attributes
+	m := &aniListMedia{
+		SiteURL: "https://mangadex.org/title/" + d.ID,
+		Title:   mangadexText(a.Title),
+		Desc:    mangadexText(a.Description),
+		Status:  strings.ToUpper(a.Status),
+	}
+	for _, l := range append([]map[string]string{a.Title}, a.AltTitles...) {
+		if m.Titles.English == "" {
+			m.Titles.English = l["en"]
+		}
+		if m.Titles.Romaji == "" {
+			m.Titles.Romaji = l["ja-ro"]
+		}
+		if m.Titles.Native == "" {
+			m.Titles.Native = l["ja"]
+		}
+	}
+	if m.Title == "" {
+		for _, alt := range a.AltTitles {
+			if m.Title = mangadexText(alt); m.Title != "" {
+				break
+			}
+		}
+	}
+	if a.Year > 0 {
+		m.StartDate = fmt.Sprint(a.Year)
+	}
+	for _, t := range a.Tags {
+		if t.Attributes.Group == "genre" || t.Attributes.Group == "theme" {
+			m.Genres = append(m.Genres, mangadexText(t.Attributes.Name))
+		}
+	}
+	adult := a.ContentRating == "erotica" || a.ContentRating == "pornographic"
+	m.Adult = adult
+	for _, r := range d.Relationships {
+		if r.Type == "cover_art" && r.Attributes.FileName != "" && (allowAdult || !adult) {
+			m.CoverURL = fmt.Sprintf("https://uploads.mangadex.org/covers/%s/%s.256.jpg", d.ID, r.Attributes.FileName)
+		}
+	}
+	return m
+}
+
+// mangadexText picks the English version of a localized MangaDex string, else any version
+func mangadexText(l map[string]string) string {
+	if v := l["en"]; v != "" {
+		return v
+	}
+	for _, v := range l {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// mangadexProvider looks manga up on MangaDex; it has no anime
+type mangadexProvider struct{}
+
+func (mangadexProvider) Name() string { return trackerMangaDex }
+
+func (mangadexProvider) Search(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	if mediaType == "ANIME" {
+		return nil, nil
+	}
+	q := url.Values{"title": {name}, "limit": {"1"}, "includes[]": {"cover_art"}, "order[relevance]": {"desc"}}
+	q["contentRating[]"] = []string{"safe", "suggestive"}
+	if allowAdult {
+		q["contentRating[]"] = append(q["contentRating[]"], "erotica", "pornographic")
+	}
+	var res struct {
+		Data []mangadexManga `json:"data"`
+	}
+	if err := getJSON("https://api.mangadex.org/manga?"+q.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, nil
+	}
+	return res.Data[0].toMedia(allowAdult), nil
+}
+
+// GetByID loads a title by its UUID
+func (mangadexProvider) GetByID(id, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	var res struct {
+		Data mangadexManga `json:"data"`
+	}
+	if err := getJSON("https://api.mangadex.org/manga/"+url.PathEscape(id)+"?includes[]=cover_art", nil, &res); err != nil {
+		return nil, err
+	}
+	return res.Data.toMedia(allowAdult), nil
+}
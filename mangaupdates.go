@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// mangaUpdatesSeries is the subset of a MangaUpdates series record used for "where to read"
+type mangaUpdatesSeries struct {
+	SeriesID   int64  `json:"series_id"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Licensed   bool   `json:"licensed"`
+	Publishers []struct {
+		Name  string `json:"publisher_name"`
+		Type  string `json:"type"`
+		Notes string `json:"notes"`
+	} `json:"publishers"`
+}
+
+// fetchMangaUpdates finds the MangaUpdates series best matching title and loads its record
+func fetchMangaUpdates(title string) (*mangaUpdatesSeries, error) {
+	var search struct {
+		Results []struct {
+			Record struct {
+				SeriesID int64 `json:"series_id"`
+			} `json:"record"`
+		} `json:"results"`
+	}
+	if err := postJSON("https://api.mangaupdates.com/v1/series/search", map[string]interface{}{"search": title, "perpage": 1}, &search); err != nil {
+		return nil, err
+	}
+	if len(search.Results) == 0 {
+		return nil, nil
+	}
+	var series mangaUpdatesSeries
+	if err := getJSON(fmt.Sprintf("https://api.mangaupdates.com/v1/series/%d", search.Results[0].Record.SeriesID), nil, &series); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// whereToReadField lists the official publishers of a series, English licensors first
+func (mu *mangaUpdatesSeries) whereToReadField(tr localizer) *discordgo.MessageEmbedField {
+	var english, original []string
+	for _, p := range mu.Publishers {
+		name := p.Name
+		if p.Notes != "" {
+			name += " (" + p.Notes + ")"
+		}
+		if p.Type == "English" {
+			english = append(english, name)
+		} else {
+			original = append(original, name)
+		}
+	}
+	var lines []string
+	if len(english) > 0 {
+		lines = append(lines, tr.T("search.licensed", strings.Join(english, ", ")))
+	} else if !mu.Licensed {
+		lines = append(lines, tr.T("search.unlicensed"))
+	}
+	if len(original) > 0 {
+		lines = append(lines, tr.T("search.publisher", strings.Join(original, ", ")))
+	}
+	lines = append(lines, fmt.Sprintf("[MangaUpdates](%s)", mu.URL))
+	value := truncateRunes(strings.Join(lines, "\n"), 1024)
+	return &discordgo.MessageEmbedField{Name: tr.T("search.where_to_read"), Value: value}
+}
+
+// addWhereToRead appends official reading sources from MangaUpdates to a manga embed when
+// where_to_read is enabled. Lookup failures leave the embed unchanged.
+func (h *handler) addWhereToRead(tr localizer, emb *discordgo.MessageEmbed, media *aniListMedia) {
+	if !h.cfg().WhereToRead || media == nil {
+		return
+	}
+	series, err := fetchMangaUpdates(media.Title)
+	if err != nil {
+		log.Printf("search: MangaUpdates error for %q: %v", media.Title, err)
+		return
+	}
+	if series != nil {
+		emb.Fields = append(emb.Fields, series.whereToReadField(tr))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxMediaLinks bounds the tracker links expanded from a single message
+const maxMediaLinks = 3
+
+// mediaLinkPattern recognises the page URLs of a provider. The first submatch is the media type
+// ("anime", "animes", anything else means manga) and the second the ID passed to GetByID.
+type mediaLinkPattern struct {
+	re       *regexp.Regexp
+	provider string
+	// reply with the small card instead of the full search embed
+	compact bool
+}
+
+var mediaLinkPatterns = []mediaLinkPattern{
+	{re: regexp.MustCompile(`https?://(?:www\.)?anilist\.co/(anime|manga)/(\d+)`), provider: trackerAniList},
+	{re: regexp.MustCompile(`https?://(?:www\.)?myanimelist\.net/(anime|manga)/(\d+)`), provider: trackerMAL},
+	{re: regexp.MustCompile(`https?://(?:www\.)?kitsu\.(?:io|app)/(anime|manga)/([a-z0-9-]+)`), provider: trackerKitsu},
+	{re: regexp.MustCompile(`https?://(?:www\.)?shikimori\.(?:one|me)/(animes|mangas|ranobe)/[a-z]*(\d+)`), provider: trackerShikimori},
+	{re: regexp.MustCompile(`https?://(?:www\.)?mangadex\.org/(title)/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`), provider: trackerMangaDex, compact: true},
+}
+
+// expandMediaLinks replies to tracker links in a message with an embed of the linked title
+func (h *handler) expandMediaLinks(s *discordgo.Session, m *discordgo.MessageCreate, allowAdult bool) {
+	var embeds []*discordgo.MessageEmbed
+	var found []*aniListMedia
+	seen := map[string]bool{}
+	tr := h.channelLocalizer(s, m.ChannelID)
+	for _, p := range mediaLinkPatterns {
+		for _, match := range p.re.FindAllStringSubmatch(m.Content, -1) {
+			if seen[match[0]] || len(embeds) >= maxMediaLinks {
+				continue
+			}
+			seen[match[0]] = true
+			if len(seen) == 1 {
+				defer showTyping(s, m.ChannelID)()
+			}
+			mediaType := "MANGA"
+			if strings.HasPrefix(match[1], "anime") {
+				mediaType = "ANIME"
+			}
+			media, err := mediaProviders[p.provider].GetByID(match[2], mediaType, allowAdult)
+			recordProviderResult(p.provider, err, media != nil)
+			if err != nil {
+				log.Printf("search: %s error for %s: %v", p.provider, match[0], err)
+			}
+			if media == nil {
+				continue
+			}
+			media.Provider = p.provider
+			if h.blocked(media) {
+				continue
+			}
+			media = h.withTitleLanguage(m.Author, media)[0]
+			found = append(found, media)
+			if p.compact {
+				embeds = append(embeds, media.compactEmbed())
+			} else {
+				embeds = append(embeds, h.mediaEmbed(tr, media, ""))
+			}
+		}
+	}
+	if len(embeds) == 0 {
+		return
+	}
+	msg := &discordgo.MessageSend{Embeds: embeds, Components: trailerButtons(tr, found...), Files: h.adultCoverFiles(embeds, found)}
+	if _, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(msg, m.Message)); err != nil {
+		log.Printf("search: failed to send link expansion: %v", err)
+		return
+	}
+	h.suppressSourcePreviews(s, featureSearch, m.Message)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Discord rejects messages and embeds above these lengths (in characters) with a 400 error
+const (
+	maxMessageLength          = 2000
+	maxEmbedTitleLength       = 256
+	maxEmbedDescriptionLength = 4096
+)
+
+// splitContent splits text into chunks of at most limit characters, breaking between lines where
+// possible and inside overlong lines otherwise. A code block cut in two is closed at the end of
+// one chunk and reopened at the start of the next.
+func splitContent(text string, limit int) []string {
+	if utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+	// room for the fence closing and reopening a code block
+	const fence = "```"
+	limit -= 2 * (len(fence) + 1)
+	var chunks []string
+	var cur strings.Builder
+	curLen := 0
+	inCode := false
+	flush := func() {
+		chunk := strings.TrimRight(cur.String(), "\n")
+		if inCode {
+			chunk += "\n" + fence
+		}
+		// a chunk holding nothing but fences is an empty code block
+		if strings.TrimSpace(strings.ReplaceAll(chunk, fence, "")) != "" {
+			chunks = append(chunks, chunk)
+		}
+		cur.Reset()
+		curLen = 0
+		if inCode {
+			cur.WriteString(fence + "\n")
+			curLen = len(fence) + 1
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		for {
+			n := utf8.RuneCountInString(line)
+			if curLen+n+1 <= limit {
+				break
+			}
+			if curLen > 0 && n+1 <= limit {
+				flush()
+				break
+			}
+			// the line alone does not fit: cut it at the room left
+			room := limit - curLen
+			if room <= 0 {
+				flush()
+				continue
+			}
+			r := []rune(line)
+			cur.WriteString(string(r[:room]))
+			line = string(r[room:])
+			flush()
+		}
+		cur.WriteString(line)
+		cur.WriteString("\n")
+		curLen += utf8.RuneCountInString(line) + 1
+		if strings.Count(line, fence)%2 == 1 {
+			inCode = !inCode
+		}
+	}
+	inCode = false
+	flush()
+	return chunks
+}
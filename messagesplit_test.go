@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"fits", "hello", 20, []string{"hello"}},
+		{"exactly the limit", "aaaaa\nbbbbb", 11, []string{"aaaaa\nbbbbb"}},
+		{"between lines", "aaaaa\nbbbbb\nccccc", 14, []string{"aaaaa", "bbbbb", "ccccc"}},
+		{"overlong line", "aaaaaaaaaaaaaaa", 14, []string{"aaaaaa", "aaaaaa", "aaa"}},
+		{"multi-byte characters", "ééééééééééééééé", 14, []string{"éééééé", "éééééé", "ééé"}},
+		{"blank lines between chunks dropped", "aaaaa\n\n\n\n\n\n\n\nbbbbb", 16, []string{"aaaaa", "bbbbb"}},
+		{"code block closed and reopened", "```\naaaa\nbbbb\ncccc\n```", 20, []string{"```\naaaa\n```", "```\nbbbb\n```", "```\ncccc\n```"}},
+		{"no empty code blocks", "```\naaaa\nbbbb\n```", 14, []string{"```\naaaa\n```", "```\nbbbb\n```"}},
+		{"text after a code block", "```\naaaa\n```\nbbbbbbbbbb", 20, []string{"```\naaaa\n```", "bbbbbbbbbb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitContent(tt.text, tt.limit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitContent(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+			for _, chunk := range got {
+				if n := utf8.RuneCountInString(chunk); n > tt.limit {
+					t.Errorf("chunk %q is %d characters, over the limit of %d", chunk, n, tt.limit)
+				}
+			}
+		})
+	}
+}
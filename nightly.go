@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const nightlyBucket = "nightly"
+
+// maxNightlyCommits bounds the commits listed in one nightly notification
+const maxNightlyCommits = 15
+
+// nightlyState remembers the commit of the last announced nightly build
+type nightlyState struct {
+	HeadSHA string    `json:"head_sha"`
+	RunID   int64     `json:"run_id"`
+	BuiltAt time.Time `json:"built_at"`
+}
+
+// workflowRun is the subset of a GitHub Actions run used for nightly notifications
+type workflowRun struct {
+	ID         int64     `json:"id"`
+	RunNumber  int       `json:"run_number"`
+	HeadSHA    string    `json:"head_sha"`
+	HeadBranch string    `json:"head_branch"`
+	HTMLURL    string    `json:"html_url"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// watchNightly is the `nightly-watch` job: it announces each new successful run of the nightly
+// workflow with the commits it contains. The first run only records the current build.
+func (h *handler) watchNightly(ctx context.Context, job jobRecord) error {
+	nc := h.cfg().Nightly
+	q := url.Values{"status": {"success"}, "per_page": {"1"}}
+	if nc.Branch != "" {
+		q.Set("branch", nc.Branch)
+	}
+	var runs struct {
+		WorkflowRuns []workflowRun `json:"workflow_runs"`
+	}
+	path := fmt.Sprintf("/repos/%s/actions/workflows/%s/runs?%s", nc.Repo, url.PathEscape(nc.Workflow), q.Encode())
+	if _, err := githubRequest(ctx, h.cfg().GitHubToken, "GET", path, nil, &runs); err != nil {
+		return err
+	}
+	if len(runs.WorkflowRuns) == 0 {
+		return nil
+	}
+	run := runs.WorkflowRuns[0]
+	var state nightlyState
+	found, err := h.store.Get(nightlyBucket, nc.Repo, &state)
+	if err != nil {
+		return err
+	}
+	if found && (state.RunID == run.ID || state.HeadSHA == run.HeadSHA) {
+		return nil
+	}
+	if found {
+		if err := h.announceNightly(ctx, run, state.HeadSHA); err != nil {
+			return err
+		}
+	} else {
+		log.Printf("nightly: now watching %s %s, latest build #%d", nc.Repo, nc.Workflow, run.RunNumber)
+	}
+	return h.store.Put(nightlyBucket, nc.Repo, nightlyState{HeadSHA: run.HeadSHA, RunID: run.ID, BuiltAt: run.UpdatedAt})
+}
+
+// announceNightly posts the new build with the commits since the previously announced one
+func (h *handler) announceNightly(ctx context.Context, run workflowRun, prevSHA string) error {
+	nc := h.cfg().Nightly
+	var cmp struct {
+		HTMLURL string `json:"html_url"`
+		Commits []struct {
+			SHA    string `json:"sha"`
+			Commit struct {
+				Message string `json:"message"`
+			} `json:"commit"`
+		} `json:"commits"`
+	}
+	if _, err := githubRequest(ctx, h.cfg().GitHubToken, "GET", fmt.Sprintf("/repos/%s/compare/%s...%s", nc.Repo, prevSHA, run.HeadSHA), nil, &cmp); err != nil {
+		log.Printf("nightly: failed to compare %s...%s: %v", shortSHA(prevSHA), shortSHA(run.HeadSHA), err)
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "🌙 New nightly build **#%d** (`%s..%s`) is available: <%s>\n", run.RunNumber, shortSHA(prevSHA), shortSHA(run.HeadSHA), run.HTMLURL)
+	// the compare API lists commits oldest first; show the newest
+	commits := cmp.Commits
+	for i := len(commits) - 1; i >= 0 && len(commits)-1-i < maxNightlyCommits; i-- {
+		title := strings.SplitN(commits[i].Commit.Message, "\n", 2)[0]
+		fmt.Fprintf(sb, "- `%s` %s\n", shortSHA(commits[i].SHA), title)
+	}
+	if len(commits) > maxNightlyCommits {
+		fmt.Fprintf(sb, "…and %d more: <%s>\n", len(commits)-maxNightlyCommits, cmp.HTMLURL)
+	}
+	sb.WriteString("Please retest open reports against this build.")
+	text := truncateRunes(sb.String(), 1950)
+	if _, err := h.dg.ChannelMessageSend(nc.ChannelID, text); err != nil {
+		return fmt.Errorf("announce nightly #%d: %v", run.RunNumber, err)
+	}
+	log.Printf("nightly: announced build #%d of %s", run.RunNumber, nc.Repo)
+	return nil
+}
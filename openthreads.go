@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	registerThreadCommand("open", (*handler).handleOpenThreads)
+	registerThreadCommand("unsolved", (*handler).handleOpenThreads)
+}
+
+// handleOpenThreads implements `.open [tag]` (alias `.unsolved`): list the forum's active threads
+// that have no status yet, oldest first, optionally only those carrying the given tag
+func (h *handler) handleOpenThreads(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, args string) {
+	tags, err := fetchForumTags(s, ch.ParentID)
+	if err != nil {
+		log.Printf("open: failed to fetch tags of forum %s: %v", ch.ParentID, err)
+		return
+	}
+	tagNames := map[string]string{}
+	for _, t := range tags {
+		tagNames[t.ID] = t.Name
+	}
+	statusTags := map[string]bool{}
+	for cmd := range commandConfig {
+		if t, ok := findForumTag(tags, h.cfg().statusTag(ch.ParentID, cmd)); ok {
+			statusTags[t.ID] = true
+		}
+	}
+	filterID := ""
+	if args != "" {
+		t, ok := findForumTag(tags, args)
+		if !ok {
+			replyMessage(s, m.Message, fmt.Sprintf("This forum has no tag named %q.", args))
+			return
+		}
+		filterID = t.ID
+	}
+
+	active, err := s.GuildThreadsActive(ch.GuildID)
+	if err != nil {
+		log.Printf("open: failed to list active threads: %v", err)
+		return
+	}
+	var open []*discordgo.Channel
+	for _, th := range active.Threads {
+		if th.ParentID != ch.ParentID || h.cfg().forGuild(ch.GuildID).statusFromTitle(th.Name) != "" {
+			continue
+		}
+		resolved, matches := false, filterID == ""
+		for _, id := range th.AppliedTags {
+			resolved = resolved || statusTags[id]
+			matches = matches || id == filterID
+		}
+		if !resolved && matches {
+			open = append(open, th)
+		}
+	}
+	if len(open) == 0 {
+		replyMessage(s, m.Message, "No open threads without a status. 🎉")
+		return
+	}
+	created := func(th *discordgo.Channel) time.Time {
+		t, _ := discordgo.SnowflakeTimestamp(th.ID)
+		return t
+	}
+	sort.Slice(open, func(i, j int) bool { return created(open[i]).Before(created(open[j])) })
+
+	lines := make([]string, 0, len(open))
+	for _, th := range open {
+		line := fmt.Sprintf("<#%s> — opened <t:%d:R>", th.ID, created(th).Unix())
+		var names []string
+		for _, id := range th.AppliedTags {
+			if name, ok := tagNames[id]; ok {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			line += " — " + strings.Join(names, ", ")
+		}
+		lines = append(lines, line)
+	}
+	title := fmt.Sprintf("Open threads without a status (%d)", len(open))
+	if args != "" {
+		title = fmt.Sprintf("Open threads tagged %s without a status (%d)", tagNames[filterID], len(open))
+	}
+	if err := sendPaged(s, m.ChannelID, embedPages(title, lines, 10)); err != nil {
+		log.Printf("open: failed to send list: %v", err)
+	}
+}
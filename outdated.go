@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// latestReleaseTTL is how long the latest release is cached between GitHub requests
+const latestReleaseTTL = 10 * time.Minute
+
+var latestReleaseCache struct {
+	sync.Mutex
+	repo    string
+	release githubRelease
+	fetched time.Time
+}
+
+// latestRelease returns the newest stable release of repo, cached for latestReleaseTTL
+func latestRelease(token, repo string) (githubRelease, error) {
+	latestReleaseCache.Lock()
+	defer latestReleaseCache.Unlock()
+	if latestReleaseCache.repo == repo && time.Since(latestReleaseCache.fetched) < latestReleaseTTL {
+		return latestReleaseCache.release, nil
+	}
+	var r githubRelease
+	resp, err := githubRequest(context.Background(), token, "GET", "/repos/"+repo+"/releases/latest", nil, &r)
+	if err != nil {
+		return r, err
+	}
+	if resp.StatusCode == 404 {
+		return r, fmt.Errorf("%s has no releases", repo)
+	}
+	latestReleaseCache.repo, latestReleaseCache.release, latestReleaseCache.fetched = repo, r, time.Now()
+	return r, nil
+}
+
+// versionVerdict compares a reported version with the latest release and returns the reply and
+// whether the version is outdated
+func versionVerdict(version string, latest githubRelease) (string, bool) {
+	lv := releaseVersionRe.FindStringSubmatch(latest.TagName)
+	if lv == nil {
+		return "", false
+	}
+	switch c := compareVersions(version, lv[1]); {
+	case c < 0:
+		return fmt.Sprintf("⬆️ Version **%s** is outdated: the latest release is **%s** (<t:%d:R>). Please update and check whether the problem still happens before it is triaged: %s",
+			version, latest.TagName, latest.PublishedAt.Unix(), latest.HTMLURL), true
+	case c == 0:
+		return fmt.Sprintf("✅ **%s** is the latest release.", version), false
+	default:
+		return fmt.Sprintf("🧪 **%s** is newer than the latest stable release (**%s**), so it is probably a nightly or development build.", version, latest.TagName), false
+	}
+}
+
+// handleVersion implements `.version <x.y.z>`
+func (h *handler) handleVersion(s *discordgo.Session, m *discordgo.MessageCreate, args string) {
+	repo := h.cfg().Releases.Repo
+	versions := extractVersions("v" + args)
+	if repo == "" || len(versions) == 0 {
+		replyMessage(s, m.Message, "usage: "+h.cfg().forGuild(m.GuildID).command("version")+" <x.y.z>")
+		return
+	}
+	latest, err := latestRelease(h.cfg().GitHubToken, repo)
+	if err != nil {
+		log.Printf("version: failed to fetch latest release of %s: %v", repo, err)
+		replyMessage(s, m.Message, "❌ Could not fetch the latest release, try again later.")
+		return
+	}
+	if reply, _ := versionVerdict(versions[0], latest); reply != "" {
+		replyMessage(s, m.Message, reply)
+	}
+}
+
+// noteOutdatedVersion asks the author of a new post to update first when the version they report
+// is older than the latest release. Nightly builds are left alone.
+func (h *handler) noteOutdatedVersion(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message) {
+	text := th.Name + "\n" + starter.Content
+	versions := extractVersions(text)
+	if len(versions) == 0 || nightlyRe.MatchString(text) || h.cfg().Releases.Repo == "" {
+		return
+	}
+	latest, err := latestRelease(h.cfg().GitHubToken, h.cfg().Releases.Repo)
+	if err != nil {
+		log.Printf("version: failed to fetch latest release of %s: %v", h.cfg().Releases.Repo, err)
+		return
+	}
+	if reply, outdated := versionVerdict(versions[0], latest); outdated {
+		sendMessage(s, th.ID, fmt.Sprintf("<@%s> %s", th.OwnerID, reply))
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runtimeOverrides holds the settings changed with admin commands instead of config.yaml. They
+// are written to overrides_path on every change and applied on top of config.yaml at startup and
+// on every reload, so they survive restarts.
+type runtimeOverrides struct {
+	// Forums watched through .watch or the "Watch" button, in addition to forum_parent_ids
+	WatchedForums []string `yaml:"watched_forums,omitempty"`
+	// Forum settings imported with /config import; they replace the forum's entry in config.yaml
+	Forums map[string]ForumConfig `yaml:"forums,omitempty"`
+}
+
+const overridesHeader = `# Settings changed with admin commands (.watch, .unwatch, /config import), applied on top of
+# config.yaml. The bot rewrites this file on every change; edits made by hand are picked up on
+# the next configuration reload.
+`
+
+// loadOverrides reads the overrides file; a missing file means no overrides
+func loadOverrides(path string) (*runtimeOverrides, error) {
+	o := &runtimeOverrides{}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, o); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return o, nil
+}
+
+// writeOverrides writes o to the overrides file at path
+func writeOverrides(path string, o *runtimeOverrides) error {
+	b, err := yaml.Marshal(o)
+	if err != nil {
+		return err
+	}
+	return writeOverridesFile(path, b)
+}
+
+// writeOverridesFile replaces the overrides file through a temp file so a crash never leaves a
+// partial one
+func writeOverridesFile(path string, b []byte) error {
+	if dryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append([]byte(overridesHeader), b...), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// apply returns cfg with the overridden forum settings added and compiled, and adds the runtime
+// watched forums to watched. cfg is not changed; when the overridden settings are invalid the
+// error is returned and watched is left alone.
+func (o *runtimeOverrides) apply(cfg *Config, watched map[string]string) (*Config, error) {
+	next := cfg
+	if len(o.Forums) > 0 {
+		c := *cfg
+		c.Forums = make(map[string]ForumConfig, len(cfg.Forums)+len(o.Forums))
+		for id, fc := range cfg.Forums {
+			c.Forums[id] = fc
+		}
+		for id, fc := range o.Forums {
+			c.Forums[id] = fc
+		}
+		if err := c.compile(); err != nil {
+			return nil, fmt.Errorf("overridden forum settings are invalid: %v", err)
+		}
+		next = &c
+	}
+	for _, id := range o.WatchedForums {
+		if _, ok := watched[id]; !ok {
+			watched[id] = ""
+		}
+	}
+	return next, nil
+}
+
+// watch adds a forum to the runtime watch list; it reports whether the list changed
+func (o *runtimeOverrides) watch(forumID string) bool {
+	for _, id := range o.WatchedForums {
+		if id == forumID {
+			return false
+		}
+	}
+	o.WatchedForums = append(o.WatchedForums, forumID)
+	return true
+}
+
+// unwatch removes a forum from the runtime watch list
+func (o *runtimeOverrides) unwatch(forumID string) {
+	kept := o.WatchedForums[:0]
+	for _, id := range o.WatchedForums {
+		if id != forumID {
+			kept = append(kept, id)
+		}
+	}
+	o.WatchedForums = kept
+}
+
+// updateOverrides changes the overrides with fn, which runs under h.mu so it can update the
+// state the overrides apply to as well, and writes them to overrides_path
+func (h *handler) updateOverrides(fn func(o *runtimeOverrides)) error {
+	h.overridesMu.Lock()
+	defer h.overridesMu.Unlock()
+	h.mu.Lock()
+	fn(h.overrides)
+	b, err := yaml.Marshal(h.overrides)
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return writeOverridesFile(h.cfg().OverridesPath, b)
+}
+
+// migrateStoredOverrides moves the watched forums and imported forum settings that earlier
+// versions kept in the state store into o and the overrides file at path. Store entries are only
+// removed once the file is written. It reports whether anything was moved.
+func migrateStoredOverrides(store Store, o *runtimeOverrides, path string) bool {
+	if store == nil {
+		return false
+	}
+	watched, err := store.List(watchedForumsBucket)
+	if err != nil {
+		log.Printf("config: failed to read watched forums from the state store: %v", err)
+		return false
+	}
+	forums, err := store.List(forumConfigsBucket)
+	if err != nil {
+		log.Printf("config: failed to read imported forum settings from the state store: %v", err)
+		return false
+	}
+	if len(watched) == 0 && len(forums) == 0 {
+		return false
+	}
+	for id := range watched {
+		o.watch(id)
+	}
+	for id, raw := range forums {
+		var fc ForumConfig
+		if err := json.Unmarshal(raw, &fc); err != nil {
+			log.Printf("config: dropping unreadable imported settings of forum %s: %v", id, err)
+			continue
+		}
+		if _, ok := o.Forums[id]; ok {
+			continue
+		}
+		if o.Forums == nil {
+			o.Forums = map[string]ForumConfig{}
+		}
+		o.Forums[id] = fc
+	}
+	if err := writeOverrides(path, o); err != nil {
+		log.Printf("config: failed to write %s: %v", path, err)
+		return true
+	}
+	for id := range watched {
+		if err := store.Delete(watchedForumsBucket, id); err != nil {
+			log.Printf("config: failed to remove migrated watched forum %s: %v", id, err)
+		}
+	}
+	for id := range forums {
+		if err := store.Delete(forumConfigsBucket, id); err != nil {
+			log.Printf("config: failed to remove migrated settings of forum %s: %v", id, err)
+		}
+	}
+	log.Printf("config: moved %d watched forum(s) and %d forum setting(s) from the state store to %s", len(watched), len(forums), path)
+	return true
+}
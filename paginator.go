@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// pagedMessageTTL is how long the page buttons of a paginated message keep working
+const pagedMessageTTL = 30 * time.Minute
+
+// pagedMessage is a multi-page embed whose pages are switched with buttons
+type pagedMessage struct {
+	pages   []*discordgo.MessageEmbed
+	created time.Time
+}
+
+// pageStore keeps paginated messages in memory until they expire
+type pageStore struct {
+	mu    sync.Mutex
+	items map[string]*pagedMessage
+}
+
+var pages = &pageStore{items: map[string]*pagedMessage{}}
+
+func init() {
+	registerComponentHandler("page:", (*handler).handlePageButton)
+}
+
+// add stores the pages under a new key and drops expired entries
+func (ps *pageStore) add(embeds []*discordgo.MessageEmbed) string {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	for k, v := range ps.items {
+		if time.Since(v.created) > pagedMessageTTL {
+			delete(ps.items, k)
+		}
+	}
+	key := strconv.FormatInt(time.Now().UnixNano(), 36)
+	ps.items[key] = &pagedMessage{pages: embeds, created: time.Now()}
+	return key
+}
+
+func (ps *pageStore) get(key string) (*pagedMessage, bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	p, ok := ps.items[key]
+	return p, ok
+}
+
+// pageControls returns the previous/next buttons for page n of a paginated message
+func pageControls(key string, n, total int) []discordgo.MessageComponent {
+	if total <= 1 {
+		return nil
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: "◀", Style: discordgo.SecondaryButton, CustomID: fmt.Sprintf("page:%s:%d", key, n-1), Disabled: n == 0},
+		discordgo.Button{Label: fmt.Sprintf("%d/%d", n+1, total), Style: discordgo.SecondaryButton, CustomID: fmt.Sprintf("page:%s:current", key), Disabled: true},
+		discordgo.Button{Label: "▶", Style: discordgo.SecondaryButton, CustomID: fmt.Sprintf("page:%s:%d", key, n+1), Disabled: n >= total-1},
+	}}}
+}
+
+// sendPaged posts the first page of embeds with page buttons when there is more than one page
+func sendPaged(s *discordgo.Session, channelID string, embeds []*discordgo.MessageEmbed) error {
+	if len(embeds) == 0 {
+		return nil
+	}
+	key := pages.add(embeds)
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embeds[0]},
+		Components: pageControls(key, 0, len(embeds)),
+	})
+	return err
+}
+
+// replyPaged answers m with the first page of embeds and page buttons, see asReply
+func replyPaged(s *discordgo.Session, m *discordgo.Message, embeds []*discordgo.MessageEmbed) error {
+	if len(embeds) == 0 {
+		return nil
+	}
+	key := pages.add(embeds)
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embeds[0]},
+		Components: pageControls(key, 0, len(embeds)),
+	}, m))
+	return err
+}
+
+// respondPaged answers an interaction with the first page of embeds and page buttons
+func respondPaged(s *discordgo.Session, i *discordgo.InteractionCreate, embeds []*discordgo.MessageEmbed, ephemeral bool) error {
+	if len(embeds) == 0 {
+		return nil
+	}
+	key := pages.add(embeds)
+	data := &discordgo.InteractionResponseData{
+		Embeds:     []*discordgo.MessageEmbed{embeds[0]},
+		Components: pageControls(key, 0, len(embeds)),
+	}
+	if ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseChannelMessageWithSource, Data: data})
+}
+
+// editPaged fills a deferred interaction response with the first page of embeds and page buttons
+func editPaged(s *discordgo.Session, i *discordgo.InteractionCreate, embeds []*discordgo.MessageEmbed) error {
+	if len(embeds) == 0 {
+		return nil
+	}
+	key := pages.add(embeds)
+	components := pageControls(key, 0, len(embeds))
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Embeds:     &[]*discordgo.MessageEmbed{embeds[0]},
+		Components: &components,
+	})
+	return err
+}
+
+// handlePageButton switches a paginated message to the requested page
+func (h *handler) handlePageButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	parts := strings.Split(i.MessageComponentData().CustomID, ":")
+	if len(parts) != 3 {
+		return
+	}
+	n, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return
+	}
+	p, ok := pages.get(parts[1])
+	if !ok || n < 0 || n >= len(p.pages) {
+		respondEphemeral(s, i, "These results have expired, please run the command again.")
+		return
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{p.pages[n]},
+			Components: pageControls(parts[1], n, len(p.pages)),
+		},
+	})
+	if err != nil {
+		log.Printf("failed to switch page: %v", err)
+	}
+}
+
+// embedPages splits lines into embeds of at most perPage lines each, titled "<title>". A page
+// also ends early when its description would grow past Discord's embed limit.
+func embedPages(title string, lines []string, perPage int) []*discordgo.MessageEmbed {
+	var out []*discordgo.MessageEmbed
+	var page []string
+	size := 0
+	// titles carry user input such as the .find query
+	title = truncateRunes(title, maxEmbedTitleLength)
+	for _, line := range lines {
+		line = truncateRunes(line, maxEmbedDescriptionLength)
+		n := utf8.RuneCountInString(line) + 1
+		if len(page) > 0 && (len(page) >= perPage || size+n > maxEmbedDescriptionLength) {
+			out = append(out, &discordgo.MessageEmbed{Title: title, Description: strings.Join(page, "\n"), Color: 0x2f3136})
+			page, size = nil, 0
+		}
+		page = append(page, line)
+		size += n
+	}
+	if len(page) > 0 {
+		out = append(out, &discordgo.MessageEmbed{Title: title, Description: strings.Join(page, "\n"), Color: 0x2f3136})
+	}
+	for i, e := range out {
+		if len(out) > 1 {
+			e.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Page %d of %d", i+1, len(out))}
+		}
+	}
+	return out
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	parsersBucket    = "parsers"
+	parserCatalogKey = "catalog"
+	// parserSitePath is where kotatsu-parsers keeps one Kotlin file per source (or source family)
+	parserSitePath = "/parsers/site/"
+	// parserFetchWorkers bounds concurrent raw file downloads during a scan
+	parserFetchWorkers = 8
+	// minParserMentionLen ignores very short source titles when scanning posts for mentions
+	minParserMentionLen = 4
+)
+
+var (
+	// parserAnnotationRe matches @MangaSourceParser("NAME", "Title", "locale", ContentType.TYPE)
+	parserAnnotationRe = regexp.MustCompile(`@MangaSourceParser\(\s*"(\w+)"\s*,\s*"([^"]*)"(?:\s*,\s*"(\w*)")?(?:\s*,\s*(?:type\s*=\s*)?ContentType\.(\w+))?`)
+	// parserBrokenRe matches the @Broken annotation with its optional reason
+	parserBrokenRe = regexp.MustCompile(`@Broken(?:\(\s*"([^"]*)"\s*\))?`)
+)
+
+// parserSource is one source of the kotatsu-parsers catalog
+type parserSource struct {
+	Name         string `json:"name"`
+	Title        string `json:"title"`
+	Locale       string `json:"locale,omitempty"`
+	ContentType  string `json:"content_type"`
+	Broken       bool   `json:"broken,omitempty"`
+	BrokenReason string `json:"broken_reason,omitempty"`
+	Path         string `json:"path"`
+}
+
+// NSFW reports whether the source serves adult content
+func (ps parserSource) NSFW() bool {
+	return ps.ContentType == "HENTAI"
+}
+
+// parserCatalog is the stored result of the last scan
+type parserCatalog struct {
+	Sources map[string]parserSource `json:"sources"`
+	// FileSHAs holds the blob SHA of every scanned file so unchanged files are not downloaded again
+	FileSHAs  map[string]string `json:"file_shas"`
+	Commit    string            `json:"commit"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// loadParserCatalog returns the stored catalog; it is empty before the first scan
+func (h *handler) loadParserCatalog() (parserCatalog, error) {
+	var cat parserCatalog
+	_, err := h.store.Get(parsersBucket, parserCatalogKey, &cat)
+	return cat, err
+}
+
+// parseParserFile extracts the sources declared in one Kotlin file
+func parseParserFile(path, src string) []parserSource {
+	broken, reason := false, ""
+	if m := parserBrokenRe.FindStringSubmatch(src); m != nil {
+		broken, reason = true, m[1]
+	}
+	var out []parserSource
+	for _, m := range parserAnnotationRe.FindAllStringSubmatch(src, -1) {
+		ct := m[4]
+		if ct == "" {
+			ct = "MANGA"
+		}
+		out = append(out, parserSource{Name: m[1], Title: m[2], Locale: m[3], ContentType: ct, Broken: broken, BrokenReason: reason, Path: path})
+	}
+	return out
+}
+
+// fetchText downloads a text file
+func fetchText(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", botUserAgent)
+	resp, err := (&http.Client{Timeout: 20 * time.Second}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+// scanParsers is the `parser-scan` job: it lists the parser sources of the repository through the
+// git tree API, downloads the files that changed since the previous scan and alerts the dev
+// channel about sources that became broken or were fixed
+func (h *handler) scanParsers(ctx context.Context, job jobRecord) error {
+	pc := h.cfg().Parsers
+	var tree struct {
+		SHA  string `json:"sha"`
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+			SHA  string `json:"sha"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	resp, err := githubRequest(ctx, h.cfg().GitHubToken, "GET", fmt.Sprintf("/repos/%s/git/trees/%s?recursive=1", pc.Repo, pc.Branch), nil, &tree)
+	if err != nil {
+		return err
+	}
+	// a wrong repo or branch answers 404 (or a redirect for moved repositories) without an error;
+	// an empty or partial tree must not replace the stored catalog
+	switch {
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("tree of %s@%s: github returned status %d (check parsers.repo and parsers.branch)", pc.Repo, pc.Branch, resp.StatusCode)
+	case tree.SHA == "":
+		return fmt.Errorf("tree of %s@%s: github returned no commit", pc.Repo, pc.Branch)
+	case tree.Truncated:
+		return fmt.Errorf("tree of %s@%s: github truncated the listing", pc.Repo, pc.Branch)
+	}
+	old, err := h.loadParserCatalog()
+	if err != nil {
+		return err
+	}
+	if old.Commit == tree.SHA {
+		return nil
+	}
+
+	next := parserCatalog{Sources: map[string]parserSource{}, FileSHAs: map[string]string{}, Commit: tree.SHA, UpdatedAt: time.Now()}
+	byPath := map[string][]parserSource{}
+	for _, src := range old.Sources {
+		byPath[src.Path] = append(byPath[src.Path], src)
+	}
+	var changed []string
+	for _, e := range tree.Tree {
+		if e.Type != "blob" || !strings.HasSuffix(e.Path, ".kt") || !strings.Contains(e.Path, parserSitePath) {
+			continue
+		}
+		next.FileSHAs[e.Path] = e.SHA
+		if old.FileSHAs[e.Path] == e.SHA {
+			for _, src := range byPath[e.Path] {
+				next.Sources[src.Name] = src
+			}
+			continue
+		}
+		changed = append(changed, e.Path)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	paths := make(chan string)
+	for w := 0; w < parserFetchWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				src, err := fetchText(ctx, fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s", pc.Repo, tree.SHA, path))
+				mu.Lock()
+				if err != nil {
+					log.Printf("parsers: failed to fetch %s: %v", path, err)
+					// keep the previous result and retry on the next scan
+					for _, old := range byPath[path] {
+						next.Sources[old.Name] = old
+					}
+					delete(next.FileSHAs, path)
+				} else {
+					for _, ps := range parseParserFile(path, src) {
+						next.Sources[ps.Name] = ps
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range changed {
+		if ctx.Err() != nil {
+			break
+		}
+		paths <- path
+	}
+	close(paths)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err := h.store.Put(parsersBucket, parserCatalogKey, next); err != nil {
+		return err
+	}
+	log.Printf("parsers: catalog updated to %s (%d sources, %d files fetched)", shortSHA(tree.SHA), len(next.Sources), len(changed))
+
+	// the first scan only builds the catalog
+	if len(old.Sources) > 0 && pc.AlertChannelID != "" {
+		h.alertParserChanges(old, next)
+	}
+	return nil
+}
+
+// alertParserChanges posts the sources whose broken state flipped between two catalogs
+func (h *handler) alertParserChanges(old, next parserCatalog) {
+	var broke, fixed []string
+	for name, src := range next.Sources {
+		prev, ok := old.Sources[name]
+		switch {
+		case src.Broken && (!ok || !prev.Broken):
+			line := fmt.Sprintf("- **%s** (`%s`, %s)", src.Title, name, src.Locale)
+			if src.BrokenReason != "" {
+				line += ": " + src.BrokenReason
+			}
+			broke = append(broke, line)
+		case !src.Broken && ok && prev.Broken:
+			fixed = append(fixed, fmt.Sprintf("- **%s** (`%s`)", src.Title, name))
+		}
+	}
+	if len(broke) == 0 && len(fixed) == 0 {
+		return
+	}
+	sort.Strings(broke)
+	sort.Strings(fixed)
+	sb := &strings.Builder{}
+	if len(broke) > 0 {
+		fmt.Fprintf(sb, "🔴 Sources marked broken in %s:\n%s\n", h.cfg().Parsers.Repo, strings.Join(broke, "\n"))
+	}
+	if len(fixed) > 0 {
+		fmt.Fprintf(sb, "🟢 Sources working again:\n%s\n", strings.Join(fixed, "\n"))
+	}
+	text := truncateRunes(sb.String(), 1900)
+	sendMessage(h.dg, h.cfg().Parsers.AlertChannelID, text)
+}
+
+// noteBrokenSources replies in a new post that mentions a source currently marked broken
+func (h *handler) noteBrokenSources(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message) {
+	cat, err := h.loadParserCatalog()
+	if err != nil || len(cat.Sources) == 0 {
+		return
+	}
+	text := " " + strings.ToLower(th.Name+"\n"+starter.Content) + " "
+	var notes []string
+	for _, src := range cat.Sources {
+		if !src.Broken || len(src.Title) < minParserMentionLen {
+			continue
+		}
+		if mentionsWord(text, strings.ToLower(src.Title)) {
+			note := fmt.Sprintf("⚠️ **%s** is currently marked as broken in the parsers", src.Title)
+			if src.BrokenReason != "" {
+				note += " (" + src.BrokenReason + ")"
+			}
+			notes = append(notes, note+". It will work again once a fix is released; there is no need to report it again.")
+		}
+	}
+	if len(notes) > 0 {
+		sort.Strings(notes)
+		sendMessage(s, th.ID, strings.Join(notes, "\n"))
+	}
+}
+
+// mentionsWord reports whether word appears in text delimited by non-alphanumeric characters
+func mentionsWord(text, word string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		if !isWordByte(text, start-1) && !isWordByte(text, end) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c >= 0x80
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// processedMessagesBucket remembers the command messages that were handled, keyed by message ID
+const processedMessagesBucket = "processed_messages"
+
+// processedMessageTTL is how long a handled command message is remembered. Gateway replays after
+// a reconnect and duplicate deliveries arrive within seconds to minutes of the original.
+const processedMessageTTL = 15 * time.Minute
+
+// processedMessages mirrors the bucket in memory, so duplicates are caught without the store and
+// while it is unavailable
+var processedMessages = struct {
+	sync.Mutex
+	seen map[string]time.Time
+}{seen: map[string]time.Time{}}
+
+// claimCommandMessage records that the command message id is being handled and reports false
+// when it was handled already, so a replayed `.solved` does not edit the thread and reply twice.
+// With cache: redis replicas claim messages from each other as well.
+func (h *handler) claimCommandMessage(id string) bool {
+	if sharedCache != nil {
+		return sharedCache.claim("processed:"+id, processedMessageTTL)
+	}
+	now := time.Now()
+	processedMessages.Lock()
+	if t, ok := processedMessages.seen[id]; ok && now.Sub(t) < processedMessageTTL {
+		processedMessages.Unlock()
+		return false
+	}
+	// claimed in memory before the store is asked, so a duplicate arriving meanwhile is caught
+	processedMessages.seen[id] = now
+	processedMessages.Unlock()
+
+	// a restart loses the memory, not the bucket
+	var handled time.Time
+	if ok, err := h.store.Get(processedMessagesBucket, id, &handled); err == nil && ok && now.Sub(handled) < processedMessageTTL {
+		processedMessages.Lock()
+		processedMessages.seen[id] = handled
+		processedMessages.Unlock()
+		return false
+	}
+	if err := h.store.Put(processedMessagesBucket, id, now); err != nil && err != errStoreUnavailable {
+		log.Printf("commands: failed to record message %s as handled: %v", id, err)
+	}
+	return true
+}
+
+// pruneProcessedMessages forgets handled command messages older than processedMessageTTL
+func (h *handler) pruneProcessedMessages(ctx context.Context, job jobRecord) error {
+	now := time.Now()
+	processedMessages.Lock()
+	for id, t := range processedMessages.seen {
+		if now.Sub(t) >= processedMessageTTL {
+			delete(processedMessages.seen, id)
+		}
+	}
+	processedMessages.Unlock()
+
+	stored, err := h.store.List(processedMessagesBucket)
+	if err != nil {
+		return err
+	}
+	for id, raw := range stored {
+		var handled time.Time
+		if json.Unmarshal(raw, &handled) == nil && now.Sub(handled) < processedMessageTTL {
+			continue
+		}
+		if err := h.store.Delete(processedMessagesBucket, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MediaProvider is a source of anime and manga metadata. mediaType is "ANIME" or "MANGA" and
+// adult titles are only returned when allowAdult; a nil media with a nil error means no match.
+type MediaProvider interface {
+	// Name is the key used in search_providers and by /tracker
+	Name() string
+	Search(name, mediaType string, allowAdult bool) (*aniListMedia, error)
+	// GetByID loads the entry with the provider's own ID, as found in its page URLs
+	GetByID(id, mediaType string, allowAdult bool) (*aniListMedia, error)
+}
+
+// candidateSearcher is implemented by providers that can return several matches for one search
+type candidateSearcher interface {
+	SearchCandidates(name, mediaType string, allowAdult bool, limit int) ([]*aniListMedia, error)
+}
+
+// mediaProviders holds every available provider by name
+var mediaProviders = map[string]MediaProvider{}
+
+func init() {
+	for _, p := range []MediaProvider{aniListProvider{}, jikanProvider{}, kitsuProvider{}, shikimoriProvider{}, mangadexProvider{}} {
+		mediaProviders[p.Name()] = p
+	}
+}
+
+// providerLabels are the display names of the providers
+var providerLabels = map[string]string{
+	trackerAniList:   "AniList",
+	trackerMAL:       "MyAnimeList",
+	trackerKitsu:     "Kitsu",
+	trackerShikimori: "Shikimori",
+	trackerMangaDex:  "MangaDex",
+}
+
+// providerLabel returns the display name of a provider
+func providerLabel(name string) string {
+	if l, ok := providerLabels[name]; ok {
+		return l
+	}
+	return name
+}
+
+// providerAliases are alternative names accepted in the config
+var providerAliases = map[string]string{"jikan": trackerMAL, "myanimelist": trackerMAL}
+
+// Providers failing providerFailureLimit requests in a row are skipped by searches for a cooldown
+// that doubles with every further failure, from providerMinCooldown up to providerMaxCooldown
+const (
+	providerFailureLimit = 3
+	providerMinCooldown  = time.Minute
+	providerMaxCooldown  = 15 * time.Minute
+)
+
+// providerState is the health of one provider
+type providerState struct {
+	failures  int
+	skipUntil time.Time
+	served    int
+	lastError string
+}
+
+var providerHealth = struct {
+	sync.Mutex
+	states map[string]*providerState
+}{states: map[string]*providerState{}}
+
+// recordProviderResult updates the health of a provider after a request; served reports
+// whether the provider's result was used
+func recordProviderResult(name string, err error, served bool) {
+	providerHealth.Lock()
+	defer providerHealth.Unlock()
+	st := providerHealth.states[name]
+	if st == nil {
+		st = &providerState{}
+		providerHealth.states[name] = st
+	}
+	if served {
+		st.served++
+	}
+	if err == nil {
+		st.failures, st.skipUntil, st.lastError = 0, time.Time{}, ""
+		return
+	}
+	st.failures++
+	st.lastError = err.Error()
+	if st.failures >= providerFailureLimit {
+		cooldown := providerMinCooldown << (st.failures - providerFailureLimit)
+		if cooldown > providerMaxCooldown || cooldown <= 0 {
+			cooldown = providerMaxCooldown
+		}
+		st.skipUntil = time.Now().Add(cooldown)
+	}
+}
+
+// providerHealthy reports whether searches should currently use the provider
+func providerHealthy(name string) bool {
+	providerHealth.Lock()
+	defer providerHealth.Unlock()
+	st := providerHealth.states[name]
+	return st == nil || time.Now().After(st.skipUntil)
+}
+
+// providerHealthSummary describes every provider used since startup, for the heartbeat
+func providerHealthSummary() string {
+	providerHealth.Lock()
+	defer providerHealth.Unlock()
+	names := make([]string, 0, len(providerHealth.states))
+	for name := range providerHealth.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	for _, name := range names {
+		st := providerHealth.states[name]
+		line := fmt.Sprintf("%s: %d served", name, st.served)
+		if time.Now().Before(st.skipUntil) {
+			line += fmt.Sprintf(", skipped until <t:%d:t> (%s)", st.skipUntil.Unix(), st.lastError)
+		} else if st.failures > 0 {
+			line += fmt.Sprintf(", %d failures in a row", st.failures)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "no lookups"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mediaProviderNames lists the provider names, for config validation
+func mediaProviderNames() []string {
+	names := make([]string, 0, len(mediaProviders))
+	for name := range mediaProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// recentSearch is a result posted by a single-title search
+type recentSearch struct {
+	title     string
+	messageID string
+	posted    time.Time
+}
+
+// recentSearches remembers the results posted per channel, keyed by the normalized query and by
+// the media found, so a repeated lookup within search_repeat_window links back instead of posting
+// the same embed again
+var recentSearches = struct {
+	sync.Mutex
+	items map[string]recentSearch
+}{items: map[string]recentSearch{}}
+
+func recentQueryKey(channelID, mediaType, query string) string {
+	return channelID + "|" + mediaType + "|q:" + strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+func recentMediaKey(channelID string, m *aniListMedia) string {
+	return fmt.Sprintf("%s|%s|%s:%d", channelID, m.SiteURL, m.Provider, m.ID)
+}
+
+// recentResult returns the result posted for one of the keys within the window, if any
+func (h *handler) recentResult(keys ...string) (recentSearch, bool) {
+	if h.cfg().searchRepeatWindow <= 0 {
+		return recentSearch{}, false
+	}
+	recentSearches.Lock()
+	defer recentSearches.Unlock()
+	for _, k := range keys {
+		if r, ok := recentSearches.items[k]; ok && time.Since(r.posted) < h.cfg().searchRepeatWindow {
+			return r, true
+		}
+	}
+	return recentSearch{}, false
+}
+
+// rememberResult records a posted result under the given keys, dropping expired entries
+func (h *handler) rememberResult(r recentSearch, keys ...string) {
+	if h.cfg().searchRepeatWindow <= 0 {
+		return
+	}
+	recentSearches.Lock()
+	defer recentSearches.Unlock()
+	for k, v := range recentSearches.items {
+		if time.Since(v.posted) >= h.cfg().searchRepeatWindow {
+			delete(recentSearches.items, k)
+		}
+	}
+	for _, k := range keys {
+		recentSearches.items[k] = r
+	}
+}
+
+// pointToRecent answers a repeated search with a jump link to the earlier result
+func (h *handler) pointToRecent(s *discordgo.Session, m *discordgo.MessageCreate, r recentSearch) error {
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", m.GuildID, m.ChannelID, r.messageID)
+	if m.GuildID == "" {
+		link = fmt.Sprintf("https://discord.com/channels/@me/%s/%s", m.ChannelID, r.messageID)
+	}
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{
+		Content: h.channelLocalizer(s, m.ChannelID).T("search.repeated", r.title, r.posted.Unix(), link),
+	}, m.Message))
+	return err
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Backends of the cache setting
+const (
+	cacheMemory = "memory" // each process keeps its own hot state (default)
+	cacheRedis  = "redis"  // hot state lives in the Redis server of redis_url
+)
+
+// redisTimeout bounds every Redis call; a slow cache must not hold up replies
+const redisTimeout = 2 * time.Second
+
+// forumTagsTTL is how long the tags of a forum are reused from Redis. Forum updates drop them
+// right away, the TTL only covers updates missed while no replica was connected.
+const forumTagsTTL = 10 * time.Minute
+
+// sharedCache keeps search results, forum tags and lookup cooldowns in Redis when cache: redis is
+// set, so replicas and shards of one deployment share them. nil keeps them in process memory.
+var sharedCache *redisCache
+
+type redisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// openRedisCache connects to the Redis server of url, e.g. "redis://:secret@redis:6379/0"
+func openRedisCache(url, prefix string) (*redisCache, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisCache{client: redis.NewClient(opts), prefix: prefix}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		c.client.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// get decodes the value cached under key into v. Errors are logged and reported as a miss, so
+// the caller falls back to the source.
+func (c *redisCache) get(key string, v interface{}) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	b, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err == redis.Nil {
+		return false
+	}
+	if err == nil {
+		err = json.Unmarshal(b, v)
+	}
+	if err != nil {
+		log.Printf("cache: failed to read %s: %v", key, err)
+		return false
+	}
+	return true
+}
+
+// set caches v under key for ttl
+func (c *redisCache) set(key string, v interface{}, ttl time.Duration) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("cache: failed to encode %s: %v", key, err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := c.client.Set(ctx, c.prefix+key, b, ttl).Err(); err != nil {
+		log.Printf("cache: failed to write %s: %v", key, err)
+	}
+}
+
+// del drops key from the cache
+func (c *redisCache) del(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := c.client.Del(ctx, c.prefix+key).Err(); err != nil {
+		log.Printf("cache: failed to drop %s: %v", key, err)
+	}
+}
+
+// claim sets key for ttl unless it is set already and reports whether this call set it. When
+// Redis fails the claim is granted, so an outage does not silence the bot.
+func (c *redisCache) claim(key string, ttl time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	ok, err := c.client.SetNX(ctx, c.prefix+key, 1, ttl).Result()
+	if err != nil {
+		log.Printf("cache: failed to claim %s: %v", key, err)
+		return true
+	}
+	return ok
+}
+
+// searchLimitScript checks the search limits and counts the lookup in one step, so two replicas
+// cannot both pass the check before either has counted. KEYS are the channel's sorted set and the
+// user's counter; ARGV the time and the cutoff a minute earlier in nanoseconds, the set member,
+// channel_per_minute, user_per_day and whether the user is known. It returns 1 when the channel
+// limit is reached, 2 when the user limit is and 0 once the lookup is counted.
+var searchLimitScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[2])
+if tonumber(ARGV[4]) > 0 and redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[4]) then
+	return 1
+end
+if ARGV[6] == '1' and tonumber(ARGV[5]) > 0 and tonumber(redis.call('GET', KEYS[2]) or '0') >= tonumber(ARGV[5]) then
+	return 2
+end
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[3])
+redis.call('PEXPIRE', KEYS[1], 60000)
+if ARGV[6] == '1' then
+	redis.call('INCR', KEYS[2])
+	redis.call('EXPIRE', KEYS[2], 172800)
+end
+return 0
+`)
+
+// countSearch is the shared version of countSearch: the lookups of a channel are kept in a sorted
+// set by time, the lookups of a user in a counter per day, and replicas count into the same keys.
+// When Redis fails the lookup is allowed.
+func (c *redisCache) countSearch(channelID, userKey, day string, limits SearchLimitsConfig, now time.Time) (exceeded string, notify bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	channelKey := c.prefix + "search:channel:" + channelID
+	userCountKey := c.prefix + "search:user:" + day + ":" + userKey
+	member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+	knownUser := "0"
+	if userKey != "" {
+		knownUser = "1"
+	}
+	res, err := searchLimitScript.Run(ctx, c.client, []string{channelKey, userCountKey},
+		now.UnixNano(), now.Add(-time.Minute).UnixNano(), member, limits.ChannelPerMinute, limits.UserPerDay, knownUser).Int()
+	if err != nil {
+		log.Printf("cache: failed to count search: %v", err)
+		return "", false
+	}
+	switch res {
+	case 1:
+		exceeded = "channel:" + channelID
+	case 2:
+		exceeded = "user:" + userKey
+	default:
+		return "", false
+	}
+	return exceeded, c.claim("search:notified:"+day+":"+exceeded, time.Minute)
+}
+
+func (c *redisCache) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const releasesBucket = "releases"
+
+// nightlyTagRe recognises nightly builds among releases
+var nightlyTagRe = regexp.MustCompile(`(?i)nightly`)
+
+// githubRelease is the subset of the GitHub release object used for announcements
+type githubRelease struct {
+	ID          int64     `json:"id"`
+	TagName     string    `json:"tag_name"`
+	Name        string    `json:"name"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	Draft       bool      `json:"draft"`
+	Prerelease  bool      `json:"prerelease"`
+	PublishedAt time.Time `json:"published_at"`
+	Assets      []struct {
+		Name        string `json:"name"`
+		Size        int    `json:"size"`
+		DownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// releaseState remembers the newest announced release of a repository
+type releaseState struct {
+	LastPublished time.Time `json:"last_published"`
+	LastTag       string    `json:"last_tag"`
+}
+
+// fetchReleases returns up to limit (max 100) of the most recent releases of a repository, oldest first
+func fetchReleases(token, repo string, limit int) ([]githubRelease, error) {
+	var releases []githubRelease
+	if _, err := githubRequest(context.Background(), token, "GET", fmt.Sprintf("/repos/%s/releases?per_page=%d", repo, limit), nil, &releases); err != nil {
+		return nil, err
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].PublishedAt.Before(releases[j].PublishedAt) })
+	return releases, nil
+}
+
+// wantRelease applies the pre-release and nightly filters
+func (rc ReleasesConfig) wantRelease(r githubRelease) bool {
+	if r.Draft {
+		return false
+	}
+	if nightlyTagRe.MatchString(r.TagName) || nightlyTagRe.MatchString(r.Name) {
+		return rc.IncludeNightly
+	}
+	return !r.Prerelease || rc.IncludePrereleases
+}
+
+// watchReleases is the `release-watch` job: it announces releases published since the last run.
+// The first run only records the newest release so enabling the feature does not repost history.
+func (h *handler) watchReleases(ctx context.Context, job jobRecord) error {
+	rc := h.cfg().Releases
+	releases, err := fetchReleases(h.cfg().GitHubToken, rc.Repo, 20)
+	if err != nil || len(releases) == 0 {
+		return err
+	}
+	var state releaseState
+	found, err := h.store.Get(releasesBucket, rc.Repo, &state)
+	if err != nil {
+		return err
+	}
+	latest := releases[len(releases)-1]
+	if !found {
+		log.Printf("releases: now watching %s, latest release is %s", rc.Repo, latest.TagName)
+		return h.store.Put(releasesBucket, rc.Repo, releaseState{LastPublished: latest.PublishedAt, LastTag: latest.TagName})
+	}
+	for _, r := range releases {
+		if !r.PublishedAt.After(state.LastPublished) {
+			continue
+		}
+		if rc.wantRelease(r) {
+			if err := h.announceRelease(r); err != nil {
+				return err
+			}
+		}
+		state = releaseState{LastPublished: r.PublishedAt, LastTag: r.TagName}
+		if err := h.store.Put(releasesBucket, rc.Repo, state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseHighlights returns the first lines of the release notes, preferring list items
+func releaseHighlights(body string, maxLines int) string {
+	var bullets, other []string
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			bullets = append(bullets, "• "+strings.TrimSpace(line[2:]))
+		case !strings.HasPrefix(line, "#"):
+			other = append(other, line)
+		}
+	}
+	lines := bullets
+	if len(lines) == 0 {
+		lines = other
+	}
+	more := ""
+	if len(lines) > maxLines {
+		more = fmt.Sprintf("\n…and %d more", len(lines)-maxLines)
+		lines = lines[:maxLines]
+	}
+	out := strings.Join(lines, "\n")
+	if r := []rune(out); len(r) > 3500 {
+		out = string(r[:3500]) + "…"
+	}
+	return out + more
+}
+
+// announceRelease posts the release embed and crossposts it when configured
+func (h *handler) announceRelease(r githubRelease) error {
+	rc := h.cfg().Releases
+	name := r.Name
+	if name == "" {
+		name = r.TagName
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       name,
+		URL:         r.HTMLURL,
+		Description: releaseHighlights(r.Body, 12),
+		Color:       0x5865f2,
+		Timestamp:   r.PublishedAt.Format(time.RFC3339),
+		Footer:      &discordgo.MessageEmbedFooter{Text: rc.Repo},
+	}
+	if r.Prerelease {
+		embed.Color = 0xf1c40f
+		embed.Title += " (pre-release)"
+	}
+	var apks []string
+	for _, a := range r.Assets {
+		if strings.HasSuffix(strings.ToLower(a.Name), ".apk") {
+			apks = append(apks, fmt.Sprintf("[%s](%s) (%.1f MB)", a.Name, a.DownloadURL, float64(a.Size)/(1<<20)))
+		}
+	}
+	if len(apks) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Download", Value: strings.Join(apks, "\n")})
+	}
+
+	link := r.HTMLURL
+	if h.suppressLinkEmbeds(featureReleases) {
+		link = "<" + link + ">"
+	}
+	msg, err := h.dg.ChannelMessageSendComplex(rc.ChannelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("📦 **%s** is out: %s", name, link),
+		Embeds:  []*discordgo.MessageEmbed{embed},
+	})
+	if err != nil {
+		return fmt.Errorf("announce %s: %v", r.TagName, err)
+	}
+	log.Printf("releases: announced %s %s", rc.Repo, r.TagName)
+	if rc.Crosspost {
+		if _, err := h.dg.ChannelMessageCrosspost(rc.ChannelID, msg.ID); err != nil {
+			log.Printf("releases: failed to crosspost %s: %v", r.TagName, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const needsInfoBucket = "needs_info"
+
+// needsInfoRecord tracks a post that is waiting for its author to supply missing report fields
+type needsInfoRecord struct {
+	ForumID string    `json:"forum_id"`
+	Missing []string  `json:"missing"`
+	Since   time.Time `json:"since"`
+}
+
+// fetchStarterMessage returns the first message of a forum post. The message can arrive slightly
+// after the ThreadCreate event, so a few retries are made.
+func fetchStarterMessage(s *discordgo.Session, threadID string) (*discordgo.Message, error) {
+	var lastErr error
+	for attempt := 0; attempt < 5; attempt++ {
+		if attempt > 0 {
+			time.Sleep(2 * time.Second)
+		}
+		// the starter message of a forum post shares the thread's ID
+		msg, err := s.ChannelMessage(threadID, threadID)
+		if err == nil {
+			return msg, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// missingReportFields returns the names of required fields whose pattern does not match content
+func missingReportFields(fields []RequiredField, content string) []string {
+	var missing []string
+	for _, f := range fields {
+		if f.re != nil && !f.re.MatchString(content) {
+			missing = append(missing, f.Name)
+		}
+	}
+	return missing
+}
+
+// enforceReportTemplate checks a new post against the forum's required fields and, if any are
+// missing, tags the post and asks the author to supply them
+func (h *handler) enforceReportTemplate(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message, fc ForumConfig) {
+	missing := missingReportFields(fc.RequiredFields, th.Name+"\n"+starter.Content)
+	if len(missing) == 0 {
+		return
+	}
+	if _, err := editThreadTags(s, th, []string{fc.NeedsInfoTag}, nil); err != nil {
+		log.Printf("report template: failed to tag %s as %q: %v", th.ID, fc.NeedsInfoTag, err)
+	}
+	tr := h.localizer(th.GuildID, th.ID, th.ParentID)
+	sendMessage(s, th.ID, tr.T("report.missing", th.OwnerID, strings.Join(missing, "\n- ")))
+	if h.store == nil {
+		return
+	}
+	rec := needsInfoRecord{ForumID: th.ParentID, Missing: missing, Since: time.Now()}
+	if err := h.store.Put(needsInfoBucket, th.ID, rec); err != nil {
+		log.Printf("report template: failed to save pending fields for %s: %v", th.ID, err)
+	}
+}
+
+// checkNeedsInfoReply re-checks the missing fields when the author of a flagged post replies and
+// removes the tag once everything has been supplied
+func (h *handler) checkNeedsInfoReply(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel) {
+	if h.store == nil || !isThreadChannel(ch) || m.Author.ID != ch.OwnerID {
+		return
+	}
+	var rec needsInfoRecord
+	found, err := h.store.Get(needsInfoBucket, ch.ID, &rec)
+	if err != nil || !found {
+		return
+	}
+	fc := h.cfg().Forums[rec.ForumID]
+	var fields []RequiredField
+	for _, f := range fc.RequiredFields {
+		for _, name := range rec.Missing {
+			if f.Name == name {
+				fields = append(fields, f)
+			}
+		}
+	}
+	still := missingReportFields(fields, m.Content)
+	if len(still) == len(rec.Missing) {
+		return
+	}
+	if len(still) > 0 {
+		rec.Missing = still
+		if err := h.store.Put(needsInfoBucket, ch.ID, rec); err != nil {
+			log.Printf("report template: failed to update pending fields for %s: %v", ch.ID, err)
+		}
+		sendMessage(s, ch.ID, h.localizer(ch.GuildID, ch.ID, ch.ParentID).T("report.still_missing", strings.Join(still, "\n- ")))
+		return
+	}
+	if err := h.store.Delete(needsInfoBucket, ch.ID); err != nil {
+		log.Printf("report template: failed to clear pending fields for %s: %v", ch.ID, err)
+	}
+	if _, err := editThreadTags(s, ch, nil, []string{fc.NeedsInfoTag}); err != nil {
+		log.Printf("report template: failed to remove %q from %s: %v", fc.NeedsInfoTag, ch.ID, err)
+	}
+	sendMessage(s, ch.ID, h.localizer(ch.GuildID, ch.ID, ch.ParentID).T("report.complete"))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// Discord only bulk-deletes messages younger than 14 days; older ones are deleted one by one
+	bulkDeleteMaxAge = 14*24*time.Hour - time.Hour
+	// maxRetentionDeletesPerRun bounds the work of a single sweep; the rest is picked up next run
+	maxRetentionDeletesPerRun = 1000
+	// singleDeleteInterval spaces out individual deletes to stay clear of the per-route rate limit
+	singleDeleteInterval = 1200 * time.Millisecond
+)
+
+// sweepRetention is the scheduled job that enforces every configured retention policy
+func (h *handler) sweepRetention(ctx context.Context, job jobRecord) error {
+	var failed []string
+	for _, rp := range h.cfg().Retention {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		n, err := h.enforceRetention(ctx, h.dg, rp)
+		if err != nil {
+			log.Printf("retention: channel %s: %v", rp.ChannelID, err)
+			failed = append(failed, rp.ChannelID)
+		}
+		if n > 0 {
+			log.Printf("retention: deleted %d messages older than %d days in %s", n, rp.MaxAgeDays, rp.ChannelID)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("retention failed for channels %v", failed)
+	}
+	return nil
+}
+
+// enforceRetention deletes messages older than the policy's age limit and returns how many were deleted
+func (h *handler) enforceRetention(ctx context.Context, s *discordgo.Session, rp RetentionPolicy) (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -rp.MaxAgeDays)
+	keepPinned := rp.KeepPinned == nil || *rp.KeepPinned
+	exempt := map[string]bool{}
+	for _, id := range rp.ExemptUserIDs {
+		exempt[id] = true
+	}
+
+	var bulk, single []string
+	before := ""
+	for len(bulk)+len(single) < maxRetentionDeletesPerRun {
+		batch, err := s.ChannelMessages(rp.ChannelID, 100, before, "", "")
+		if err != nil {
+			return 0, err
+		}
+		for _, msg := range batch {
+			if !msg.Timestamp.Before(cutoff) || (keepPinned && msg.Pinned) || (msg.Author != nil && exempt[msg.Author.ID]) {
+				continue
+			}
+			if time.Since(msg.Timestamp) < bulkDeleteMaxAge {
+				bulk = append(bulk, msg.ID)
+			} else {
+				single = append(single, msg.ID)
+			}
+		}
+		if len(batch) < 100 {
+			break
+		}
+		before = batch[len(batch)-1].ID
+	}
+
+	deleted := 0
+	for len(bulk) > 0 {
+		n := len(bulk)
+		if n > 100 {
+			n = 100
+		}
+		chunk := bulk[:n]
+		bulk = bulk[n:]
+		var err error
+		if len(chunk) == 1 {
+			err = s.ChannelMessageDelete(rp.ChannelID, chunk[0])
+		} else {
+			err = s.ChannelMessagesBulkDelete(rp.ChannelID, chunk)
+		}
+		if err != nil {
+			return deleted, err
+		}
+		deleted += len(chunk)
+	}
+	for _, id := range single {
+		select {
+		case <-ctx.Done():
+			return deleted, ctx.Err()
+		case <-time.After(singleDeleteInterval):
+		}
+		if err := s.ChannelMessageDelete(rp.ChannelID, id); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// jobFunc runs one occurrence of a job. The context is cancelled when the scheduler stops.
+type jobFunc func(ctx context.Context, job jobRecord) error
+
+// Catch-up policies applied at startup to recurring jobs whose run was missed while the bot was offline
+const (
+	catchUpOnce = "once" // run a single missed occurrence immediately (default)
+	catchUpSkip = "skip" // drop missed occurrences and wait for the next scheduled time
+)
+
+// jobRecord is the persisted state of a scheduled job
+type jobRecord struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Schedule is a cron expression for recurring jobs; empty for one-shot jobs which use RunAt
+	Schedule       string          `json:"schedule,omitempty"`
+	RunAt          time.Time       `json:"run_at,omitempty"`
+	JitterSeconds  int             `json:"jitter_seconds,omitempty"`
+	CatchUp        string          `json:"catch_up,omitempty"`
+	MaxConcurrency int             `json:"max_concurrency,omitempty"`
+	Paused         bool            `json:"paused,omitempty"`
+	NextRun        time.Time       `json:"next_run,omitempty"`
+	LastRun        time.Time       `json:"last_run,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty"`
+
+	// Attempts counts the failed runs of a one-shot job, which is retried up to onceMaxAttempts times
+	Attempts int `json:"attempts,omitempty"`
+}
+
+// A failed one-shot job is retried after onceRetryDelay, until it failed onceMaxAttempts times
+const (
+	onceRetryDelay  = 5 * time.Minute
+	onceMaxAttempts = 3
+)
+
+// scheduler runs recurring (cron) and one-shot jobs and persists their state in the store
+type scheduler struct {
+	mu      sync.Mutex
+	store   Store
+	kinds   map[string]jobFunc
+	jobs    map[string]*jobRecord
+	running map[string]int
+	// zone cron expressions are evaluated in
+	loc    *time.Location
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+const jobsBucket = "jobs"
+
+// newScheduler creates a scheduler evaluating cron expressions in loc and loads persisted job
+// state. store may be nil, in which case jobs only live in memory.
+func newScheduler(store Store, loc *time.Location) *scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	sc := &scheduler{
+		store:   store,
+		kinds:   map[string]jobFunc{},
+		jobs:    map[string]*jobRecord{},
+		running: map[string]int{},
+		loc:     loc,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	sc.load()
+	return sc
+}
+
+// load adds the persisted jobs the scheduler does not know yet. Jobs already in memory are kept
+// as they are, so load can run again once a store that failed at startup becomes available and
+// the one-shot jobs scheduled before the restart are not lost.
+func (sc *scheduler) load() {
+	if sc.store == nil {
+		return
+	}
+	raw, err := sc.store.List(jobsBucket)
+	if err != nil {
+		log.Printf("scheduler: failed to load jobs: %v", err)
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for id, r := range raw {
+		if _, ok := sc.jobs[id]; ok {
+			continue
+		}
+		var rec jobRecord
+		if err := json.Unmarshal(r, &rec); err != nil {
+			log.Printf("scheduler: dropping unreadable job %s: %v", id, err)
+			continue
+		}
+		// one-shot jobs interrupted mid-run are retried
+		if rec.Schedule == "" && rec.NextRun.IsZero() {
+			rec.NextRun = rec.RunAt
+		}
+		sc.jobs[id] = &rec
+	}
+}
+
+// Handle registers the function that runs jobs of the given kind
+func (sc *scheduler) Handle(kind string, fn jobFunc) {
+	sc.mu.Lock()
+	sc.kinds[kind] = fn
+	sc.mu.Unlock()
+}
+
+// Recurring creates or updates a cron job. Persisted pause state and run history are kept, and
+// entries from the `jobs:` config section override the defaults passed in by the feature.
+func (sc *scheduler) Recurring(id, kind, schedule string, cfg *Config) error {
+	rec := jobRecord{ID: id, Kind: kind, Schedule: schedule, CatchUp: catchUpOnce, MaxConcurrency: 1}
+	if cfg != nil {
+		if jc, ok := cfg.Jobs[id]; ok {
+			if jc.Schedule != "" {
+				rec.Schedule = jc.Schedule
+			}
+			if jc.Jitter != "" {
+				d, err := time.ParseDuration(jc.Jitter)
+				if err != nil {
+					return fmt.Errorf("job %s: invalid jitter %q: %v", id, jc.Jitter, err)
+				}
+				rec.JitterSeconds = int(d / time.Second)
+			}
+			if jc.CatchUp != "" {
+				rec.CatchUp = jc.CatchUp
+			}
+			if jc.MaxConcurrency > 0 {
+				rec.MaxConcurrency = jc.MaxConcurrency
+			}
+			if jc.Paused != nil {
+				rec.Paused = *jc.Paused
+			}
+		}
+	}
+	if rec.CatchUp != catchUpOnce && rec.CatchUp != catchUpSkip {
+		return fmt.Errorf("job %s: unknown catch_up policy %q (use %q or %q)", id, rec.CatchUp, catchUpOnce, catchUpSkip)
+	}
+	cs, err := parseCron(rec.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %s: %v", id, err)
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if old, ok := sc.jobs[id]; ok {
+		rec.LastRun = old.LastRun
+		rec.LastError = old.LastError
+		if cfg == nil || cfg.Jobs[id].Paused == nil {
+			rec.Paused = old.Paused
+		}
+		// keep the persisted next run unless the schedule changed
+		if old.Schedule == rec.Schedule {
+			rec.NextRun = old.NextRun
+		}
+	}
+	if rec.NextRun.IsZero() {
+		rec.NextRun = sc.nextRun(cs, time.Now(), rec.JitterSeconds)
+	}
+	sc.jobs[id] = &rec
+	sc.persistLocked(&rec)
+	return nil
+}
+
+// Once schedules a one-shot job that runs at the given time and is then removed
+func (sc *scheduler) Once(id, kind string, at time.Time, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	rec := &jobRecord{ID: id, Kind: kind, RunAt: at, NextRun: at, MaxConcurrency: 1, Payload: raw}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.jobs[id] = rec
+	sc.persistLocked(rec)
+	return nil
+}
+
+// Cancel removes a job
+func (sc *scheduler) Cancel(id string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.jobs, id)
+	if sc.store != nil {
+		if err := sc.store.Delete(jobsBucket, id); err != nil {
+			log.Printf("scheduler: failed to delete job %s: %v", id, err)
+		}
+	}
+}
+
+// Start applies catch-up policies to missed runs and starts the dispatch loop
+func (sc *scheduler) Start() {
+	now := time.Now()
+	sc.mu.Lock()
+	for _, rec := range sc.jobs {
+		if rec.Schedule == "" || !rec.NextRun.Before(now) {
+			continue
+		}
+		if rec.CatchUp == catchUpSkip {
+			if cs, err := parseCron(rec.Schedule); err == nil {
+				log.Printf("scheduler: job %s missed its run at %s; skipping to next occurrence", rec.ID, rec.NextRun.Format(time.RFC3339))
+				rec.NextRun = sc.nextRun(cs, now, rec.JitterSeconds)
+				sc.persistLocked(rec)
+			}
+		} else {
+			log.Printf("scheduler: job %s missed its run at %s; running once to catch up", rec.ID, rec.NextRun.Format(time.RFC3339))
+		}
+	}
+	sc.mu.Unlock()
+
+	sc.wg.Add(1)
+	go func() {
+		defer sc.wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sc.ctx.Done():
+				return
+			case t := <-ticker.C:
+				sc.dispatchDue(t)
+			}
+		}
+	}()
+}
+
+// Stop cancels running jobs and waits for them to return
+func (sc *scheduler) Stop() {
+	sc.cancel()
+	sc.wg.Wait()
+}
+
+func (sc *scheduler) dispatchDue(now time.Time) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, rec := range sc.jobs {
+		if rec.Paused || rec.NextRun.IsZero() || rec.NextRun.After(now) {
+			continue
+		}
+		sc.startLocked(rec, now)
+	}
+}
+
+// startLocked launches one run of rec and advances its next run time. Must hold sc.mu.
+func (sc *scheduler) startLocked(rec *jobRecord, now time.Time) {
+	fn, ok := sc.kinds[rec.Kind]
+	if rec.Schedule != "" {
+		if cs, err := parseCron(rec.Schedule); err == nil {
+			rec.NextRun = sc.nextRun(cs, now, rec.JitterSeconds)
+		} else {
+			rec.NextRun = time.Time{}
+		}
+	} else {
+		rec.NextRun = time.Time{}
+	}
+	if !ok {
+		log.Printf("scheduler: no handler registered for job %s (kind=%s)", rec.ID, rec.Kind)
+		sc.persistLocked(rec)
+		return
+	}
+	max := rec.MaxConcurrency
+	if max <= 0 {
+		max = 1
+	}
+	if sc.running[rec.ID] >= max {
+		log.Printf("scheduler: job %s still running (%d/%d); skipping this occurrence", rec.ID, sc.running[rec.ID], max)
+		sc.persistLocked(rec)
+		return
+	}
+	sc.running[rec.ID]++
+	sc.persistLocked(rec)
+	job := *rec
+
+	sc.wg.Add(1)
+	go func() {
+		defer sc.wg.Done()
+		start := time.Now()
+		err := fn(sc.ctx, job)
+		if err != nil {
+			log.Printf("scheduler: job %s failed after %s: %v", job.ID, time.Since(start).Round(time.Millisecond), err)
+		}
+
+		sc.mu.Lock()
+		defer sc.mu.Unlock()
+		sc.running[job.ID]--
+		cur, ok := sc.jobs[job.ID]
+		if !ok {
+			return
+		}
+		if cur.Schedule == "" && err != nil && cur.Attempts+1 < onceMaxAttempts && cur.RunAt.Equal(job.RunAt) {
+			cur.Attempts++
+			cur.LastRun, cur.LastError = start, err.Error()
+			cur.NextRun = time.Now().Add(onceRetryDelay)
+			log.Printf("scheduler: retrying job %s in %s (attempt %d of %d)", job.ID, onceRetryDelay, cur.Attempts+1, onceMaxAttempts)
+			sc.persistLocked(cur)
+			return
+		}
+		if cur.Schedule == "" {
+			// one-shot jobs are done once they have run, unless they were scheduled again meanwhile
+			if !cur.RunAt.Equal(job.RunAt) {
+				return
+			}
+			delete(sc.jobs, job.ID)
+			if sc.store != nil {
+				if e := sc.store.Delete(jobsBucket, job.ID); e != nil {
+					log.Printf("scheduler: failed to delete job %s: %v", job.ID, e)
+				}
+			}
+			return
+		}
+		cur.LastRun = start
+		cur.LastError = ""
+		if err != nil {
+			cur.LastError = err.Error()
+		}
+		sc.persistLocked(cur)
+	}()
+}
+
+// RunNow triggers a job immediately, independent of its schedule and pause state
+func (sc *scheduler) RunNow(id string) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	rec, ok := sc.jobs[id]
+	if !ok {
+		return fmt.Errorf("unknown job %q", id)
+	}
+	next := rec.NextRun
+	sc.startLocked(rec, time.Now())
+	// a manual run must not shift the regular schedule of recurring jobs
+	if rec.Schedule != "" {
+		rec.NextRun = next
+		sc.persistLocked(rec)
+	}
+	return nil
+}
+
+// SetPaused pauses or resumes a job
+func (sc *scheduler) SetPaused(id string, paused bool) error {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	rec, ok := sc.jobs[id]
+	if !ok {
+		return fmt.Errorf("unknown job %q", id)
+	}
+	rec.Paused = paused
+	if !paused && rec.Schedule != "" && rec.NextRun.Before(time.Now()) {
+		if cs, err := parseCron(rec.Schedule); err == nil {
+			rec.NextRun = sc.nextRun(cs, time.Now(), rec.JitterSeconds)
+		}
+	}
+	sc.persistLocked(rec)
+	return nil
+}
+
+// List returns a snapshot of all jobs sorted by ID along with how many runs of each are in flight
+func (sc *scheduler) List() ([]jobRecord, map[string]int) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	out := make([]jobRecord, 0, len(sc.jobs))
+	running := make(map[string]int, len(sc.running))
+	for _, rec := range sc.jobs {
+		out = append(out, *rec)
+	}
+	for id, n := range sc.running {
+		running[id] = n
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, running
+}
+
+// PersistAll writes the state of every job, e.g. after the store became available again
+func (sc *scheduler) PersistAll() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for _, rec := range sc.jobs {
+		sc.persistLocked(rec)
+	}
+}
+
+func (sc *scheduler) nextRun(cs *cronSchedule, after time.Time, jitterSeconds int) time.Time {
+	next := cs.Next(after.In(sc.loc))
+	if jitterSeconds > 0 && !next.IsZero() {
+		next = next.Add(time.Duration(rand.Intn(jitterSeconds)) * time.Second)
+	}
+	return next
+}
+
+func (sc *scheduler) persistLocked(rec *jobRecord) {
+	if sc.store == nil {
+		return
+	}
+	if err := sc.store.Put(jobsBucket, rec.ID, rec); err != nil {
+		log.Printf("scheduler: failed to persist job %s: %v", rec.ID, err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerOnceRetry(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name string
+		// results are returned by the job's runs, one run per entry while the job exists
+		results      []error
+		reschedule   bool
+		wantKept     bool
+		wantAttempts int
+	}{
+		{"succeeds", []error{nil}, false, false, 0},
+		{"fails once", []error{errFailed}, false, true, 1},
+		{"fails twice", []error{errFailed, errFailed}, false, true, 2},
+		{"gives up after the last attempt", []error{errFailed, errFailed, errFailed}, false, false, 0},
+		{"succeeds on retry", []error{errFailed, nil}, false, false, 0},
+		{"scheduled again while running", []error{errFailed}, true, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newScheduler(nil, time.UTC)
+			defer sc.Stop()
+			later := time.Now().Add(time.Hour)
+			runs := 0
+			sc.Handle("test", func(ctx context.Context, job jobRecord) error {
+				err := tt.results[runs]
+				runs++
+				if tt.reschedule {
+					if e := sc.Once("job", "test", later, nil); e != nil {
+						t.Error(e)
+					}
+				}
+				return err
+			})
+			if err := sc.Once("job", "test", time.Now(), nil); err != nil {
+				t.Fatal(err)
+			}
+			for range tt.results {
+				if err := sc.RunNow("job"); err != nil {
+					break
+				}
+				sc.wg.Wait()
+			}
+			if want := len(tt.results); runs != want {
+				t.Errorf("ran %d times, want %d", runs, want)
+			}
+
+			jobs, _ := sc.List()
+			if !tt.wantKept {
+				if len(jobs) != 0 {
+					t.Fatalf("job kept after its last run: %+v", jobs[0])
+				}
+				return
+			}
+			if len(jobs) != 1 {
+				t.Fatal("job removed, want it kept")
+			}
+			job := jobs[0]
+			if job.Attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", job.Attempts, tt.wantAttempts)
+			}
+			if tt.reschedule {
+				if !job.NextRun.Equal(later) || job.LastError != "" {
+					t.Errorf("rescheduled job changed by the failed run: next run %s, last error %q", job.NextRun, job.LastError)
+				}
+				return
+			}
+			if job.LastError != errFailed.Error() {
+				t.Errorf("last error = %q, want %q", job.LastError, errFailed)
+			}
+			if wait := time.Until(job.NextRun); wait <= onceRetryDelay-time.Minute || wait > onceRetryDelay {
+				t.Errorf("retried in %s, want %s", wait.Round(time.Second), onceRetryDelay)
+			}
+		})
+	}
+}
@@ -1,14 +1,12 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,29 +16,16 @@ import (
 // trySearchInMessage inspects a non-command message and, if patterns match and config allows,
 // queries AniList and responds with an embed. It returns nil when no action was taken.
 func (h *handler) trySearchInMessage(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel) error {
-	if h.cfg == nil || h.cfg.SearchEnabled == nil || !*h.cfg.SearchEnabled {
+	if !h.searchAllowed(m, ch) {
 		return nil
 	}
-
-	// Respect configured channel restrictions: if SearchChannels is non-empty, only operate there
-	if len(h.cfg.SearchChannels) > 0 {
-		allowed := false
-		for _, id := range h.cfg.SearchChannels {
-			if id == ch.ID || id == ch.ParentID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
+	if m.Author != nil {
+		// an opt-out that cannot be read is honoured; loadUserPrefs logs the error
+		if p, err := h.loadUserPrefs(m.Author.ID); err != nil || p.SearchOptOut {
 			return nil
 		}
 	}
 
-	// Do not attempt to search on messages from bots
-	if m.Author != nil && m.Author.Bot {
-		return nil
-	}
-
 	// Define regexes inspired by the Python implementation
 	animeRe := regexp.MustCompile("`[\\s\\S]*?`|\\{(.*?)\\}")
 	mangaRe := regexp.MustCompile("<.*?https?:\\/\\/.*?>|<a?:.+?:\\d*>|`[\\s\\S]*?`|<(.*?)>")
@@ -52,68 +37,184 @@ func (h *handler) trySearchInMessage(s *discordgo.Session, m *discordgo.MessageC
 		allowAdult = true
 	}
 
+	// Links to tracker pages are expanded independently of the title syntax below
+	h.expandMediaLinks(s, m, allowAdult)
+
+	// ((name)) looks up people rather than titles
+	if h.searchStaffInMessage(s, m, allowAdult) {
+		return nil
+	}
+
 	// Try anime
 	if names := extractNamesFromRegex(animeRe, m.Content); len(names) > 0 {
-		// debug
-		log.Printf("search: anime regex matched names=%v in channel=%s (nsfw=%v)", names, ch.ID, ch.NSFW)
+		log.Printf("debug: search: anime regex matched names=%v in channel=%s (nsfw=%v)", names, ch.ID, ch.NSFW)
+		if !h.allowSearch(s, m) {
+			return nil
+		}
 		// If multiple names, build a compact list response; otherwise send detailed embed
 		if len(names) > 1 {
-			var lines []string
-			for _, n := range names {
-				if media, err := searchAniList(n, "ANIME", allowAdult); err == nil && media != nil {
-					lines = append(lines, fmt.Sprintf("[**%s**](%s)", media.Title, media.SiteURL))
-				}
-			}
-			if len(lines) > 0 {
-				emb := &discordgo.MessageEmbed{Description: strings.Join(lines, "\n"), Color: 0x2f3136}
-				_, _ = s.ChannelMessageSendEmbed(m.ChannelID, emb)
-			}
+			h.sendTitleList(s, m, ch, names, "ANIME", allowAdult)
 			return nil
 		}
-		// single
-		media, err := searchAniList(names[0], "ANIME", allowAdult)
-		if err != nil {
-			log.Printf("search: AniList error for %q: %v", names[0], err)
-		}
-		if media == nil {
-			log.Printf("search: no AniList results for %q (anime)", names[0])
-		} else {
-			emb := media.toEmbed()
-			_, _ = s.ChannelMessageSendEmbed(m.ChannelID, emb)
+		// single; with search_combined {title} also finds manga
+		mediaType := "ANIME"
+		if h.cfg().forGuild(ch.GuildID).SearchCombined {
+			mediaType = mediaTypeAny
 		}
+		h.sendSearchResult(s, m, ch, names[0], mediaType, allowAdult)
 		return nil
 	}
 
 	// Try manga
 	if names := extractNamesFromRegex(mangaRe, m.Content); len(names) > 0 {
-		log.Printf("search: manga regex matched names=%v in channel=%s (nsfw=%v)", names, ch.ID, ch.NSFW)
+		log.Printf("debug: search: manga regex matched names=%v in channel=%s (nsfw=%v)", names, ch.ID, ch.NSFW)
+		if !h.allowSearch(s, m) {
+			return nil
+		}
 		if len(names) > 1 {
-			var lines []string
-			for _, n := range names {
-				if media, err := searchAniList(n, "MANGA", allowAdult); err == nil && media != nil {
-					lines = append(lines, fmt.Sprintf("[**%s**](%s)", media.Title, media.SiteURL))
+			h.sendTitleList(s, m, ch, names, "MANGA", allowAdult)
+			return nil
+		}
+		h.sendSearchResult(s, m, ch, names[0], "MANGA", allowAdult)
+		return nil
+	}
+
+	return nil
+}
+
+// sendTitleList answers a search for several titles with a list of links to the matches, paged
+// when it is too long for one embed
+func (h *handler) sendTitleList(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, names []string, mediaType string, allowAdult bool) {
+	stop := showTyping(s, m.ChannelID)
+	var lines []string
+	for _, media := range h.withTitleLanguage(m.Author, h.searchMediaBatch(ch, names, mediaType, allowAdult)...) {
+		lines = append(lines, fmt.Sprintf("[**%s**](%s)", media.Title, media.SiteURL))
+	}
+	stop()
+	if len(lines) == 0 {
+		return
+	}
+	if err := replyPaged(s, m.Message, embedPages("", lines, 20)); err != nil {
+		log.Printf("search: failed to send title list: %v", err)
+		return
+	}
+	h.suppressSourcePreviews(s, featureSearch, m.Message)
+}
+
+// sendSearchResult answers a single-title search with the best match. When the match is
+// uncertain, the other top matches are offered in a select menu.
+func (h *handler) sendSearchResult(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, name, mediaType string, allowAdult bool) {
+	queryKey := recentQueryKey(m.ChannelID, mediaType, name)
+	if r, ok := h.recentResult(queryKey); ok {
+		if err := h.pointToRecent(s, m, r); err != nil {
+			log.Printf("search: failed to link earlier result: %v", err)
+		}
+		return
+	}
+	defer showTyping(s, m.ChannelID)()
+	started := time.Now()
+	var results []*aniListMedia
+	var companion *aniListMedia
+	var err error
+	id, byID := parseIDQuery(name)
+	if byID {
+		var media *aniListMedia
+		media, mediaType, err = h.lookupByID(id, mediaType, allowAdult)
+		if media != nil {
+			results = []*aniListMedia{media}
+		}
+	} else if mediaType == mediaTypeAny {
+		results, mediaType, companion, err = h.searchBothTypes(ch, name, allowAdult)
+	} else {
+		results, err = h.searchMediaCandidates(ch, name, mediaType, allowAdult, searchCandidates)
+	}
+	if len(results) > 0 {
+		h.recordSearch(m.GuildID, results[0].Title, m.ChannelID, results[0].Provider, true, time.Since(started))
+	} else {
+		h.recordSearch(m.GuildID, name, m.ChannelID, "", false, time.Since(started))
+	}
+	if err != nil {
+		log.Printf("search: lookup error for %q: %v", name, err)
+	}
+	results = h.withTitleLanguage(m.Author, results...)
+	if len(results) == 0 {
+		log.Printf("search: no results for %q (%s)", name, strings.ToLower(mediaType))
+		if err == nil && !byID {
+			if suggestions := h.suggestTitles(name, mediaType); len(suggestions) > 0 {
+				if err := h.sendSuggestions(s, m.Message, name, suggestions); err != nil {
+					log.Printf("search: failed to send suggestions: %v", err)
 				}
 			}
-			if len(lines) > 0 {
-				emb := &discordgo.MessageEmbed{Description: strings.Join(lines, "\n"), Color: 0x2f3136}
-				_, _ = s.ChannelMessageSendEmbed(m.ChannelID, emb)
-			}
-			return nil
 		}
-		media, err := searchAniList(names[0], "MANGA", allowAdult)
-		if err != nil {
-			log.Printf("search: AniList error for %q: %v", names[0], err)
+		return
+	}
+	// another spelling of a title that was just posted
+	mediaKey := recentMediaKey(m.ChannelID, results[0])
+	if r, ok := h.recentResult(mediaKey); ok && !ambiguous(name, results) {
+		if err := h.pointToRecent(s, m, r); err != nil {
+			log.Printf("search: failed to link earlier result: %v", err)
+		}
+		return
+	}
+	tr := h.channelLocalizer(s, m.ChannelID)
+	emb := h.mediaEmbed(tr, results[0], mediaType)
+	if companion != nil {
+		companionType := "MANGA"
+		if mediaType == "MANGA" {
+			companionType = "ANIME"
 		}
-		if media == nil {
-			log.Printf("search: no AniList results for %q (manga)", names[0])
-		} else {
-			emb := media.toEmbed()
-			_, _ = s.ChannelMessageSendEmbed(m.ChannelID, emb)
+		addCompanionField(tr, emb, h.withTitleLanguage(m.Author, companion)[0], companionType)
+	}
+	lowConfidence := h.lowConfidence(results[0])
+	if lowConfidence {
+		emb.Description = truncateRunes(tr.T("search.low_confidence")+"\n"+emb.Description, maxEmbedDescriptionLength)
+	}
+	files := h.adultCoverFiles([]*discordgo.MessageEmbed{emb}, results[:1])
+	var sent *discordgo.Message
+	if (ambiguous(name, results) || lowConfidence && len(results) > 1) && m.Author != nil {
+		log.Printf("search: %q is ambiguous or a low-confidence match, offering %s", name, describeCandidates(results))
+		sent, err = h.sendWithCandidates(s, tr, m.Message, mediaType, results, emb, files)
+	} else {
+		sent, err = s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{
+			Embeds:     []*discordgo.MessageEmbed{emb},
+			Components: trailerButtons(tr, results[0]),
+			Files:      files,
+		}, m.Message))
+	}
+	if err != nil {
+		log.Printf("search: failed to send result: %v", err)
+		return
+	}
+	h.rememberResult(recentSearch{title: results[0].Title, messageID: sent.ID, posted: time.Now()}, queryKey, mediaKey)
+	h.suppressSourcePreviews(s, featureSearch, m.Message)
+}
+
+// searchAllowed reports whether title searches and link expansion may answer a message in ch
+func (h *handler) searchAllowed(m *discordgo.MessageCreate, ch *discordgo.Channel) bool {
+	cfg := h.cfg().forGuild(ch.GuildID)
+	if cfg == nil || cfg.SearchEnabled == nil || !*cfg.SearchEnabled {
+		return false
+	}
+	if ch.Type == discordgo.ChannelTypeDM {
+		return cfg.SearchInDMs && (m.Author == nil || !m.Author.Bot)
+	}
+
+	// Respect configured channel restrictions: if SearchChannels is non-empty, only operate there
+	if len(cfg.SearchChannels) > 0 {
+		allowed := false
+		for _, id := range cfg.SearchChannels {
+			if id == ch.ID || id == ch.ParentID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
 		}
-		return nil
 	}
 
-	return nil
+	// Do not attempt to search on messages from bots
+	return m.Author == nil || !m.Author.Bot
 }
 
 func extractNamesFromRegex(re *regexp.Regexp, content string) []string {
@@ -137,22 +238,58 @@ func extractNamesFromRegex(re *regexp.Regexp, content string) []string {
 
 // aniListMedia is a minimal structure for AniList media data used to build embeds
 type aniListMedia struct {
-	ID       int
-	SiteURL  string
-	Title    string
+	ID      int
+	SiteURL string
+	Title   string
+	// other known titles (romaji, native, synonyms), when the provider lists them
+	AltTitles []string
+	// the title in each language, as far as the provider knows it
+	Titles   mediaTitles
 	Desc     string
 	Genres   []string
 	CoverURL string
 	Format   string
+	// publication status (e.g. "ONGOING"), when the source reports one
+	Status   string
 	ColorHex string
 	// optional timestamp
 	StartDate string
+	// AniList season ("SPRING") and its year, for anime
+	Season     string
+	SeasonYear int
+	// mean score out of 100 and number of users listing the title, 0 when unknown
+	Score      int
+	Popularity int
+	// episode count for anime, chapter and volume counts for manga, 0 when unknown or ongoing
+	Episodes int
+	Chapters int
+	Volumes  int
+	// search provider that returned the media, when it came from the fallback chain
+	Provider string
+	// official streaming or reading platforms
+	Links []mediaLink
+	// trailer video page, when AniList lists one
+	TrailerURL string
+	// the provider classifies the title as adult content
+	Adult bool
 }
 
-func (m *aniListMedia) toEmbed() *discordgo.MessageEmbed {
+// mediaLink is an official platform where a title can be watched or read
+type mediaLink struct {
+	Site     string
+	URL      string
+	Language string
+}
+
+func (m *aniListMedia) toEmbed(tr localizer) *discordgo.MessageEmbed {
 	desc := m.Desc
-	if len(desc) > 800 {
-		desc = desc[:800] + "..."
+	if r := []rune(desc); len(r) > 800 {
+		desc = string(r[:800])
+		// keep a cut-off spoiler hidden
+		if strings.Count(desc, "||")%2 == 1 {
+			desc += "||"
+		}
+		desc += "..."
 	}
 	embed := &discordgo.MessageEmbed{
 		Title:       m.Title,
@@ -160,98 +297,276 @@ func (m *aniListMedia) toEmbed() *discordgo.MessageEmbed {
 		URL:         m.SiteURL,
 		Color:       0x2f3136,
 	}
+	if c, err := strconv.ParseInt(strings.TrimPrefix(m.ColorHex, "#"), 16, 32); err == nil && m.ColorHex != "" {
+		embed.Color = int(c)
+	}
 	if m.CoverURL != "" {
 		embed.Image = &discordgo.MessageEmbedImage{URL: m.CoverURL}
 	}
+	embed.Fields = m.embedFields(tr)
+	if m.Provider != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: tr.T("search.source", providerLabel(m.Provider))}
+	}
 	return embed
 }
 
-// searchAniList queries AniList GraphQL for the given name and media type ("ANIME"/"MANGA").
-func searchAniList(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
-	if strings.TrimSpace(name) == "" {
-		return nil, errors.New("empty search")
+// embedFields lists the structured facts known about the media as inline embed fields
+func (m *aniListMedia) embedFields(tr localizer) []*discordgo.MessageEmbedField {
+	var fields []*discordgo.MessageEmbedField
+	add := func(name, value string) {
+		if value != "" {
+			fields = append(fields, &discordgo.MessageEmbedField{Name: name, Value: value, Inline: true})
+		}
 	}
-	// Use the Page -> media search form which returns a list; this matches AniList examples in 2025 docs.
-	query := `query ($search: String!, $type: MediaType, $isAdult: Boolean = false) {
-		Page(page: 1, perPage: 1) {
-			media(search: $search, type: $type, isAdult: $isAdult) {
-				id
-				siteUrl
-				title { romaji english native }
-				description(asHtml: false)
-				genres
-				coverImage { large, color }
-				format
-				startDate { year month day }
+	add(tr.T("search.format"), humanizeEnum(m.Format))
+	add(tr.T("search.status"), humanizeEnum(m.Status))
+	if m.Episodes > 0 {
+		add(tr.T("search.episodes"), strconv.Itoa(m.Episodes))
+	}
+	switch {
+	case m.Chapters > 0 && m.Volumes > 0:
+		add(tr.T("search.chapters"), tr.T("search.chapters_volumes", m.Chapters, m.Volumes))
+	case m.Chapters > 0:
+		add(tr.T("search.chapters"), strconv.Itoa(m.Chapters))
+	case m.Volumes > 0:
+		add(tr.T("search.volumes"), strconv.Itoa(m.Volumes))
+	}
+	if m.Score > 0 {
+		add(tr.T("search.score"), fmt.Sprintf("%d%%", m.Score))
+	}
+	if m.Popularity > 0 {
+		add(tr.T("search.popularity"), tr.T("search.users", m.Popularity))
+	}
+	if m.Season != "" && m.SeasonYear > 0 {
+		add(tr.T("search.season"), fmt.Sprintf("%s %d", humanizeEnum(m.Season), m.SeasonYear))
+	}
+	add(tr.T("search.start_date"), m.StartDate)
+	if len(m.Links) > 0 {
+		links := make([]string, 0, len(m.Links))
+		for _, l := range m.Links {
+			label := l.Site
+			if l.Language != "" && l.Language != "English" {
+				label += " (" + l.Language + ")"
 			}
+			links = append(links, fmt.Sprintf("[%s](%s)", label, l.URL))
 		}
-	}`
-	vars := map[string]interface{}{
-		"search":  name,
-		"type":    mediaType,
-		"isAdult": allowAdult,
+		fields = append(fields, &discordgo.MessageEmbedField{Name: tr.T("search.links"), Value: truncateRunes(strings.Join(links, " · "), 1024)})
 	}
-	payload := map[string]interface{}{"query": query, "variables": vars}
-	body, _ := json.Marshal(payload)
+	return fields
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
-	defer cancel()
+// trailerButtons returns a "Watch trailer" link button row for the media that have a trailer,
+// labelled with their titles when there are several (at most five)
+func trailerButtons(tr localizer, media ...*aniListMedia) []discordgo.MessageComponent {
+	var withTrailer []*aniListMedia
+	for _, m := range media {
+		if m != nil && m.TrailerURL != "" && len(withTrailer) < 5 {
+			withTrailer = append(withTrailer, m)
+		}
+	}
+	if len(withTrailer) == 0 {
+		return nil
+	}
+	buttons := make([]discordgo.MessageComponent, 0, len(withTrailer))
+	for _, m := range withTrailer {
+		label := tr.T("search.trailer")
+		if len(withTrailer) > 1 {
+			label = truncateRunes(tr.T("search.trailer_of", m.Title), 80)
+		}
+		buttons = append(buttons, discordgo.Button{Label: label, Style: discordgo.LinkButton, URL: m.TrailerURL, Emoji: &discordgo.ComponentEmoji{Name: "🎬"}})
+	}
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://graphql.anilist.co", strings.NewReader(string(body)))
-	if err != nil {
-		return nil, err
+// compactEmbed renders the media as a small card: status and genres with the cover as thumbnail
+func (m *aniListMedia) compactEmbed() *discordgo.MessageEmbed {
+	var meta []string
+	if m.Status != "" {
+		meta = append(meta, humanizeEnum(m.Status))
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if m.StartDate != "" {
+		meta = append(meta, m.StartDate)
+	}
+	desc := strings.Join(meta, " · ")
+	if len(m.Genres) > 0 {
+		genres := m.Genres
+		if len(genres) > 8 {
+			genres = genres[:8]
+		}
+		desc += "\n*" + strings.Join(genres, ", ") + "*"
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       m.Title,
+		Description: strings.TrimSpace(desc),
+		URL:         m.SiteURL,
+		Color:       0x2f3136,
+	}
+	if m.CoverURL != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: m.CoverURL}
+	}
+	return embed
+}
+
+// humanizeEnum turns API enum values such as "NOT_YET_RELEASED" into "Not yet released"
+func humanizeEnum(v string) string {
+	v = strings.ToLower(strings.ReplaceAll(v, "_", " "))
+	if v == "" {
+		return v
+	}
+	return strings.ToUpper(v[:1]) + v[1:]
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+// aniListGraphQL runs a query against the AniList API and decodes its "data" object into out
+func aniListGraphQL(query string, vars map[string]interface{}, out interface{}) error {
+	var res struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer resp.Body.Close()
-	// Read body for diagnostics
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != 200 {
-		log.Printf("search: AniList response status=%d body=%s", resp.StatusCode, string(respBody))
-		return nil, fmt.Errorf("anilist returned status %d", resp.StatusCode)
+	body, err := aniListRequest(payload)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+	if len(res.Errors) > 0 {
+		return fmt.Errorf("anilist: %s", res.Errors[0].Message)
 	}
+	return json.Unmarshal(res.Data, out)
+}
 
-	var data struct {
-		Data struct {
-			Page struct {
-				Media []struct {
-					ID      int    `json:"id"`
-					SiteURL string `json:"siteUrl"`
-					Title   struct {
-						Romaji  string `json:"romaji"`
-						English string `json:"english"`
-						Native  string `json:"native"`
-					} `json:"title"`
-					Description string   `json:"description"`
-					Genres      []string `json:"genres"`
-					CoverImage  struct {
-						Large string `json:"large"`
-						Color string `json:"color"`
-					} `json:"coverImage"`
-					Format    string `json:"format"`
-					StartDate struct {
-						Year  int `json:"year"`
-						Month int `json:"month"`
-						Day   int `json:"day"`
-					} `json:"startDate"`
-				} `json:"media"`
-			} `json:"Page"`
-		} `json:"data"`
+// aniListProvider is the default MediaProvider
+type aniListProvider struct{}
+
+func (aniListProvider) Name() string { return trackerAniList }
+
+func (aniListProvider) Search(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	return searchAniList(name, mediaType, allowAdult)
+}
+
+// SearchCandidates returns up to limit matches, best first
+func (aniListProvider) SearchCandidates(name, mediaType string, allowAdult bool, limit int) ([]*aniListMedia, error) {
+	return cachedAniListSearch(name, mediaType, allowAdult, limit)
+}
+
+func (aniListProvider) GetByID(id, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AniList ID %q", id)
 	}
-	// Decode from the bytes we already read
-	if err := json.Unmarshal(respBody, &data); err != nil {
-		log.Printf("search: failed to decode AniList JSON: %v; body=%s", err, string(respBody))
+	return fetchAniListByID(n, mediaType, allowAdult)
+}
+
+// searchAniList queries AniList GraphQL for the given name and media type ("ANIME"/"MANGA").
+func searchAniList(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("empty search")
+	}
+	results, err := cachedAniListSearch(name, mediaType, allowAdult, 1)
+	if err != nil || len(results) == 0 {
 		return nil, err
 	}
-	if len(data.Data.Page.Media) == 0 {
-		return nil, nil
+	return results[0], nil
+}
+
+// fetchAniListByID loads one AniList media by ID, of any type when mediaType is "". Adult media
+// are only returned when allowAdult.
+func fetchAniListByID(id int, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	vars := map[string]interface{}{"id": id}
+	if mediaType != "" {
+		vars["type"] = mediaType
+	}
+	if !allowAdult {
+		vars["isAdult"] = false
 	}
-	m := &data.Data.Page.Media[0]
+	return queryAniListMedia(vars)
+}
+
+// queryAniListMedia runs the media query with the given variables (search or id, type, isAdult)
+// and returns the first result, or nil when nothing matched
+func queryAniListMedia(vars map[string]interface{}) (*aniListMedia, error) {
+	list, err := queryAniListMediaList(vars, 1)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+// aniListMediaFields selects the media fields decoded by aniListMediaData
+const aniListMediaFields = `
+	id
+	siteUrl
+	title { romaji english native }
+	synonyms
+	description(asHtml: true)
+	genres
+	coverImage { large, color }
+	format
+	status
+	startDate { year month day }
+	season
+	seasonYear
+	averageScore
+	popularity
+	episodes
+	chapters
+	volumes
+	externalLinks { site url type language isDisabled }
+	trailer { id site }
+	isAdult
+`
+
+// aniListMediaData is an AniList media object as selected by aniListMediaFields
+type aniListMediaData struct {
+	ID      int    `json:"id"`
+	SiteURL string `json:"siteUrl"`
+	Title   struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+		Native  string `json:"native"`
+	} `json:"title"`
+	Synonyms    []string `json:"synonyms"`
+	Description string   `json:"description"`
+	Genres      []string `json:"genres"`
+	CoverImage  struct {
+		Large string `json:"large"`
+		Color string `json:"color"`
+	} `json:"coverImage"`
+	Format        string `json:"format"`
+	Status        string `json:"status"`
+	Season        string `json:"season"`
+	SeasonYear    int    `json:"seasonYear"`
+	AverageScore  int    `json:"averageScore"`
+	Popularity    int    `json:"popularity"`
+	Episodes      int    `json:"episodes"`
+	Chapters      int    `json:"chapters"`
+	Volumes       int    `json:"volumes"`
+	ExternalLinks []struct {
+		Site       string `json:"site"`
+		URL        string `json:"url"`
+		Type       string `json:"type"`
+		Language   string `json:"language"`
+		IsDisabled bool   `json:"isDisabled"`
+	} `json:"externalLinks"`
+	Trailer *struct {
+		ID   string `json:"id"`
+		Site string `json:"site"`
+	} `json:"trailer"`
+	IsAdult   bool `json:"isAdult"`
+	StartDate struct {
+		Year  int `json:"year"`
+		Month int `json:"month"`
+		Day   int `json:"day"`
+	} `json:"startDate"`
+}
+
+func (m aniListMediaData) toMedia() *aniListMedia {
 	title := m.Title.English
 	if title == "" {
 		title = m.Title.Romaji
@@ -259,38 +574,134 @@ func searchAniList(name, mediaType string, allowAdult bool) (*aniListMedia, erro
 	if title == "" {
 		title = m.Title.Native
 	}
-	// strip simple HTML from description
-	desc := stripTags(m.Description)
-
-	cover := m.CoverImage.Large
-	color := m.CoverImage.Color
 	startDate := ""
 	if m.StartDate.Year != 0 {
 		startDate = fmt.Sprintf("%04d-%02d-%02d", m.StartDate.Year, m.StartDate.Month, m.StartDate.Day)
 	}
+	var alt []string
+	for _, t := range append([]string{m.Title.Romaji, m.Title.English, m.Title.Native}, m.Synonyms...) {
+		if t != "" && t != title {
+			alt = append(alt, t)
+		}
+	}
+	// streaming links cover both video services and official manga readers; info and
+	// social links are left out
+	var links []mediaLink
+	for _, l := range m.ExternalLinks {
+		if l.Type == "STREAMING" && !l.IsDisabled {
+			links = append(links, mediaLink{Site: l.Site, URL: l.URL, Language: l.Language})
+		}
+	}
+	trailer := ""
+	if m.Trailer != nil {
+		switch m.Trailer.Site {
+		case "youtube":
+			trailer = "https://www.youtube.com/watch?v=" + m.Trailer.ID
+		case "dailymotion":
+			trailer = "https://www.dailymotion.com/video/" + m.Trailer.ID
+		}
+	}
 	return &aniListMedia{
-		ID:        m.ID,
-		SiteURL:   m.SiteURL,
-		Title:     title,
-		Desc:      desc,
-		Genres:    m.Genres,
-		CoverURL:  cover,
-		Format:    m.Format,
-		ColorHex:  color,
-		StartDate: startDate,
-	}, nil
+		ID:         m.ID,
+		SiteURL:    m.SiteURL,
+		Title:      title,
+		AltTitles:  alt,
+		Titles:     mediaTitles{Romaji: m.Title.Romaji, English: m.Title.English, Native: m.Title.Native},
+		Desc:       htmlToMarkdown(m.Description),
+		Genres:     m.Genres,
+		CoverURL:   m.CoverImage.Large,
+		Format:     m.Format,
+		Status:     m.Status,
+		ColorHex:   m.CoverImage.Color,
+		StartDate:  startDate,
+		Season:     m.Season,
+		SeasonYear: m.SeasonYear,
+		Score:      m.AverageScore,
+		Popularity: m.Popularity,
+		Episodes:   m.Episodes,
+		Chapters:   m.Chapters,
+		Volumes:    m.Volumes,
+		Links:      links,
+		TrailerURL: trailer,
+		Adult:      m.IsAdult,
+	}
 }
 
-var tagRe = regexp.MustCompile(`<[^>]*>`)
+// queryAniListMediaList is queryAniListMedia returning up to perPage results, best match first
+func queryAniListMediaList(vars map[string]interface{}, perPage int) ([]*aniListMedia, error) {
+	vars["perPage"] = perPage
+	// Use the Page -> media search form which returns a list; this matches AniList examples in 2025 docs.
+	query := `query ($id: Int, $search: String, $type: MediaType, $isAdult: Boolean, $perPage: Int) {
+		Page(page: 1, perPage: $perPage) {
+			media(id: $id, search: $search, type: $type, isAdult: $isAdult) {` + aniListMediaFields + `}
+		}
+	}`
+	payload := map[string]interface{}{"query": query, "variables": vars}
+	body, _ := json.Marshal(payload)
+	respBody, err := aniListRequest(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Data struct {
+			Page struct {
+				Media []aniListMediaData `json:"media"`
+			} `json:"Page"`
+		} `json:"data"`
+	}
+	// Decode from the bytes we already read
+	if err := json.Unmarshal(respBody, &data); err != nil {
+		log.Printf("search: failed to decode AniList JSON: %v; body=%s", err, string(respBody))
+		return nil, err
+	}
+	var out []*aniListMedia
+	for _, m := range data.Data.Page.Media {
+		out = append(out, m.toMedia())
+	}
+	return out, nil
+}
 
-func stripTags(s string) string {
-	if s == "" {
-		return s
+// searchAniListBatch searches AniList for several titles in one aliased GraphQL request and
+// returns the best match of each name by index (nil when AniList has none). Cached searches
+// are not requested again.
+func searchAniListBatch(names []string, mediaType string, allowAdult bool) ([]*aniListMedia, error) {
+	out := make([]*aniListMedia, len(names))
+	vars := map[string]interface{}{"type": mediaType, "isAdult": allowAdult}
+	params := []string{"$type: MediaType", "$isAdult: Boolean"}
+	var pages []string
+	var pending []int
+	for i, name := range names {
+		if cached, ok := searchCache.get(mediaCacheKey(name, mediaType, allowAdult), 1); ok {
+			if len(cached) > 0 {
+				out[i] = cached[0]
+			}
+			continue
+		}
+		vars[fmt.Sprintf("s%d", i)] = name
+		params = append(params, fmt.Sprintf("$s%d: String", i))
+		pages = append(pages, fmt.Sprintf("q%d: Page(page: 1, perPage: 1) { media(search: $s%d, type: $type, isAdult: $isAdult) {%s} }", i, i, aniListMediaFields))
+		pending = append(pending, i)
 	}
-	// Remove simple HTML tags
-	out := tagRe.ReplaceAllString(s, "")
-	// Collapse whitespace
-	out = strings.ReplaceAll(out, "\n\n", "\n")
-	out = strings.TrimSpace(out)
-	return out
+	if len(pending) == 0 {
+		return out, nil
+	}
+	var data map[string]struct {
+		Media []aniListMediaData `json:"media"`
+	}
+	query := fmt.Sprintf("query (%s) {\n%s\n}", strings.Join(params, ", "), strings.Join(pages, "\n"))
+	if err := aniListGraphQL(query, vars, &data); err != nil {
+		return out, err
+	}
+	for _, i := range pending {
+		var results []*aniListMedia
+		for _, m := range data[fmt.Sprintf("q%d", i)].Media {
+			results = append(results, m.toMedia())
+		}
+		searchCache.put(mediaCacheKey(names[i], mediaType, allowAdult), 1, results)
+		if len(results) > 0 {
+			out[i] = results[0]
+		}
+	}
+	return out, nil
 }
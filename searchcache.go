@@ -0,0 +1,178 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchCache keeps recent AniList search results keyed by query, media type and adult flag, so
+// repeated lookups of popular titles are answered without a GraphQL request. The least recently
+// used entry is evicted once the cache is full.
+var searchCache = newMediaCache(time.Hour, 500)
+
+type mediaCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List // front is the most recently used entry
+	entries map[string]*list.Element
+	hits    int
+	misses  int
+}
+
+// sharedMediaEntry is a cached search in Redis
+type sharedMediaEntry struct {
+	Results []*aniListMedia
+	Limit   int
+}
+
+type mediaCacheEntry struct {
+	key     string
+	results []*aniListMedia
+	// number of results that were asked for; fewer results means there are no more
+	limit   int
+	fetched time.Time
+}
+
+func newMediaCache(ttl time.Duration, size int) *mediaCache {
+	return &mediaCache{ttl: ttl, size: size, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// configure applies the search_cache settings; a zero TTL or size disables the cache
+func (c *mediaCache) configure(ttl time.Duration, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl, c.size = ttl, size
+	c.order.Init()
+	c.entries = map[string]*list.Element{}
+}
+
+func mediaCacheKey(query, mediaType string, allowAdult bool) string {
+	return fmt.Sprintf("%s|%t|%s", mediaType, allowAdult, strings.ToLower(strings.TrimSpace(query)))
+}
+
+// get returns copies of up to limit cached results, if an entry fetched with at least that
+// limit is still fresh
+func (c *mediaCache) get(key string, limit int) ([]*aniListMedia, bool) {
+	if sharedCache != nil {
+		return c.getShared(key, limit)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	e := el.Value.(*mediaCacheEntry)
+	if time.Since(e.fetched) >= c.ttl {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		c.misses++
+		return nil, false
+	}
+	if e.limit < limit && len(e.results) >= e.limit {
+		c.misses++
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	c.hits++
+	n := len(e.results)
+	if n > limit {
+		n = limit
+	}
+	// callers annotate the results (provider, etc.), so each gets its own copies
+	out := make([]*aniListMedia, n)
+	for i := range out {
+		m := *e.results[i]
+		out[i] = &m
+	}
+	return out, true
+}
+
+// put stores the results of a search that asked for limit matches
+func (c *mediaCache) put(key string, limit int, results []*aniListMedia) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 || c.size <= 0 {
+		return
+	}
+	if sharedCache != nil {
+		sharedCache.set("search:"+key, sharedMediaEntry{Results: results, Limit: limit}, c.ttl)
+		return
+	}
+	stored := make([]*aniListMedia, len(results))
+	for i, r := range results {
+		m := *r
+		stored[i] = &m
+	}
+	e := &mediaCacheEntry{key: key, results: stored, limit: limit, fetched: time.Now()}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*mediaCacheEntry).key)
+	}
+}
+
+// getShared is get with the cache in Redis, where entries expire by themselves and the size limit
+// is up to the server's eviction policy
+func (c *mediaCache) getShared(key string, limit int) ([]*aniListMedia, bool) {
+	var e sharedMediaEntry
+	ok := sharedCache.get("search:"+key, &e) && (e.Limit >= limit || len(e.Results) < e.Limit)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	if len(e.Results) > limit {
+		e.Results = e.Results[:limit]
+	}
+	return e.Results, true
+}
+
+// stats reports the number of cached searches and the hit rate since start
+func (c *mediaCache) stats() (entries, hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len(), c.hits, c.misses
+}
+
+// searchCacheSummary describes the search cache for the heartbeat
+func searchCacheSummary() string {
+	entries, hits, misses := searchCache.stats()
+	if sharedCache != nil {
+		if hits+misses == 0 {
+			return "searches cached in Redis"
+		}
+		return fmt.Sprintf("searches cached in Redis (%d%% hits)", hits*100/(hits+misses))
+	}
+	if hits+misses == 0 {
+		return fmt.Sprintf("%d cached searches", entries)
+	}
+	return fmt.Sprintf("%d cached searches (%d%% hits)", entries, hits*100/(hits+misses))
+}
+
+// cachedAniListSearch returns up to limit AniList matches for a search, from the cache when possible
+func cachedAniListSearch(name, mediaType string, allowAdult bool, limit int) ([]*aniListMedia, error) {
+	key := mediaCacheKey(name, mediaType, allowAdult)
+	if results, ok := searchCache.get(key, limit); ok {
+		return results, nil
+	}
+	results, err := queryAniListMediaList(map[string]interface{}{"search": name, "type": mediaType, "isAdult": allowAdult}, limit)
+	if err != nil {
+		return nil, err
+	}
+	searchCache.put(key, limit, results)
+	return results, nil
+}
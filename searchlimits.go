@@ -0,0 +1,117 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// searchLimitNoticeTTL is how long the notice about an exceeded search limit stays visible
+const searchLimitNoticeTTL = 10 * time.Second
+
+// searchUsage counts passive lookups for search_limits: the lookup times of each channel in the
+// last minute, and the lookups of each user on the current UTC day, per server so lookups in one
+// server do not use up a member's quota in another
+var searchUsage = struct {
+	sync.Mutex
+	channels map[string][]time.Time
+	day      string
+	users    map[string]int
+	// users and channels that were already told about the limit, so the notice is not repeated
+	notified map[string]time.Time
+}{channels: map[string][]time.Time{}, users: map[string]int{}, notified: map[string]time.Time{}}
+
+// allowSearch counts a passive lookup in a channel and reports whether it stays within
+// search_limits. Over the limit, the author gets a short notice that removes itself.
+func (h *handler) allowSearch(s *discordgo.Session, m *discordgo.MessageCreate) bool {
+	limits := h.cfg().SearchLimits
+	if limits.ChannelPerMinute <= 0 && limits.UserPerDay <= 0 {
+		return true
+	}
+	now := time.Now()
+	day := h.cfg().day(now)
+	userKey := ""
+	if m.Author != nil {
+		userKey = m.GuildID + "/" + m.Author.ID
+	}
+	var exceeded string
+	var notify bool
+	if sharedCache != nil {
+		exceeded, notify = sharedCache.countSearch(m.ChannelID, userKey, day, limits, now)
+	} else {
+		exceeded, notify = countSearch(m.ChannelID, userKey, day, limits, now)
+	}
+	var notice string
+	var noticeArgs []interface{}
+	switch {
+	case exceeded == "":
+		return true
+	case strings.HasPrefix(exceeded, "user:"):
+		notice, noticeArgs = "search.limit_user", []interface{}{limits.UserPerDay, h.cfg().nextMidnight(now).Unix()}
+	default:
+		notice = "search.limit_channel"
+	}
+
+	log.Printf("search: limit reached in channel %s (%s)", m.ChannelID, exceeded)
+	if notify {
+		msg, err := s.ChannelMessageSendReply(m.ChannelID, h.channelLocalizer(s, m.ChannelID).T(notice, noticeArgs...), m.Reference())
+		if err != nil {
+			log.Printf("search: failed to send limit notice: %v", err)
+			return false
+		}
+		time.AfterFunc(searchLimitNoticeTTL, func() {
+			if err := s.ChannelMessageDelete(msg.ChannelID, msg.ID); err != nil {
+				log.Printf("search: failed to remove limit notice: %v", err)
+			}
+		})
+	}
+	return false
+}
+
+// countSearch counts a lookup of userKey ("" for unknown authors) in a channel against
+// search_limits. It returns the limit the lookup exceeds, "channel:<id>" or "user:<key>", or ""
+// when it was counted, and whether the author should be told, which happens once a minute.
+func countSearch(channelID, userKey, day string, limits SearchLimitsConfig, now time.Time) (exceeded string, notify bool) {
+	searchUsage.Lock()
+	defer searchUsage.Unlock()
+	if day != searchUsage.day {
+		searchUsage.day, searchUsage.users, searchUsage.notified = day, map[string]int{}, map[string]time.Time{}
+		// channels without a lookup in the last minute are forgotten once a day
+		for id, times := range searchUsage.channels {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= time.Minute {
+				delete(searchUsage.channels, id)
+			}
+		}
+	}
+	var recent []time.Time
+	for _, t := range searchUsage.channels[channelID] {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	switch {
+	case limits.ChannelPerMinute > 0 && len(recent) >= limits.ChannelPerMinute:
+		exceeded = "channel:" + channelID
+	case limits.UserPerDay > 0 && userKey != "" && searchUsage.users[userKey] >= limits.UserPerDay:
+		exceeded = "user:" + userKey
+	default:
+		searchUsage.channels[channelID] = append(recent, now)
+		if userKey != "" {
+			searchUsage.users[userKey]++
+		}
+		return "", false
+	}
+	if len(recent) > 0 {
+		searchUsage.channels[channelID] = recent
+	} else {
+		delete(searchUsage.channels, channelID)
+	}
+	notify = now.Sub(searchUsage.notified[exceeded]) >= time.Minute
+	if notify {
+		searchUsage.notified[exceeded] = now
+	}
+	return exceeded, notify
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// searchReactionCooldown is how long a message answered through a reaction is not looked up again
+// when more members react to it
+const searchReactionCooldown = 10 * time.Minute
+
+var reactionSearches = struct {
+	sync.Mutex
+	handled map[string]time.Time
+}{handled: map[string]time.Time{}}
+
+// claimReactionSearch reports whether a reaction lookup of messageID may run, false when one ran
+// within searchReactionCooldown
+func claimReactionSearch(messageID string) bool {
+	reactionSearches.Lock()
+	defer reactionSearches.Unlock()
+	for id, t := range reactionSearches.handled {
+		if time.Since(t) >= searchReactionCooldown {
+			delete(reactionSearches.handled, id)
+		}
+	}
+	if _, seen := reactionSearches.handled[messageID]; seen {
+		return false
+	}
+	reactionSearches.handled[messageID] = time.Now()
+	return true
+}
+
+// onSearchReaction runs the passive lookup of a message again when someone reacts to it with
+// search_reaction_emoji, e.g. when the bot was offline or rate limited as the message was posted
+func (h *handler) onSearchReaction(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	emoji := h.cfg().SearchReactionEmoji
+	if emoji == "" || (r.Emoji.Name != emoji && r.Emoji.APIName() != emoji) {
+		return
+	}
+	if s.State != nil && s.State.User != nil && r.UserID == s.State.User.ID {
+		return
+	}
+	if r.Member != nil && r.Member.User != nil && r.Member.User.Bot {
+		return
+	}
+
+	if sharedCache != nil {
+		if !sharedCache.claim("reaction:"+r.MessageID, searchReactionCooldown) {
+			return
+		}
+	} else if !claimReactionSearch(r.MessageID) {
+		return
+	}
+
+	ch, err := s.Channel(r.ChannelID)
+	if err != nil {
+		log.Printf("search: failed to fetch channel %s: %v", r.ChannelID, err)
+		return
+	}
+	msg, err := s.ChannelMessage(r.ChannelID, r.MessageID)
+	if err != nil {
+		log.Printf("search: failed to fetch message %s: %v", r.MessageID, err)
+		return
+	}
+	if h.cfg().forGuild(r.GuildID).commandPrefix(strings.TrimSpace(msg.Content)) != "" {
+		return
+	}
+	// fetched messages carry no guild ID, which reply links need
+	msg.GuildID = r.GuildID
+	log.Printf("search: lookup of message %s requested by %s", msg.ID, r.UserID)
+	if err := h.trySearchInMessage(s, &discordgo.MessageCreate{Message: msg}, ch); err != nil {
+		log.Printf("search: error: %v", err)
+	}
+}
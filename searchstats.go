@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	searchStatsBucket = "search_stats"
+	// searchStatsDays is how many days of search statistics are kept
+	searchStatsDays = 30
+	// searchStatsTop is the number of titles and channels listed by /searchstats
+	searchStatsTop = 10
+)
+
+// searchDayStats aggregates the lookups of one UTC day
+type searchDayStats struct {
+	Lookups   int            `json:"lookups"`
+	Misses    int            `json:"misses"`
+	LatencyMs int64          `json:"latency_ms"`
+	Titles    map[string]int `json:"titles"`
+	Providers map[string]int `json:"providers"`
+	Channels  map[string]int `json:"channels"`
+}
+
+// clone returns a copy of d that shares no maps with it, for use after searchStats is unlocked
+func (d *searchDayStats) clone() searchDayStats {
+	c := *d
+	c.Titles, c.Providers, c.Channels = copyCounts(d.Titles), copyCounts(d.Providers), copyCounts(d.Channels)
+	return c
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	c := make(map[string]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// searchStats holds the days recorded since start, keyed by searchStatsKey; dirty days are
+// written to the store by the search-stats-save job
+var searchStats = struct {
+	sync.Mutex
+	days  map[string]*searchDayStats
+	dirty map[string]bool
+}{days: map[string]*searchDayStats{}, dirty: map[string]bool{}}
+
+func init() {
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "searchstats",
+		Description: "Most searched titles, miss rate and latency of lookups",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "days", Description: "Days to cover (default and maximum 30)"},
+		},
+	}, (*handler).handleSearchStatsCommand)
+	requireStore("searchstats")
+}
+
+// searchStatsKey keys the statistics of a server's day, so each server only sees its own lookups.
+// Lookups in DMs are counted under "dm"; days recorded before statistics were kept per server
+// are keyed by the date alone.
+func searchStatsKey(guildID, day string) string {
+	if guildID == "" {
+		guildID = "dm"
+	}
+	return guildID + "/" + day
+}
+
+// searchStatsDay returns the date of a searchStatsKey
+func searchStatsDay(key string) string {
+	return key[strings.LastIndex(key, "/")+1:]
+}
+
+// recordSearch counts a lookup. title is the title found, or the query when nothing was found.
+func (h *handler) recordSearch(guildID, title, channelID, provider string, hit bool, latency time.Duration) {
+	day := searchStatsKey(guildID, h.cfg().day(time.Now()))
+	searchStats.Lock()
+	defer searchStats.Unlock()
+	d := searchStats.days[day]
+	if d == nil {
+		d = &searchDayStats{}
+		// continue the day's counts from before a restart
+		if h.store != nil {
+			if _, err := h.store.Get(searchStatsBucket, day, d); err != nil {
+				log.Printf("search stats: failed to load %s: %v", day, err)
+			}
+		}
+		if d.Titles == nil {
+			d.Titles, d.Providers, d.Channels = map[string]int{}, map[string]int{}, map[string]int{}
+		}
+		searchStats.days[day] = d
+	}
+	d.Lookups++
+	d.LatencyMs += latency.Milliseconds()
+	d.Titles[strings.ToLower(strings.TrimSpace(title))]++
+	d.Channels[channelID]++
+	if hit {
+		d.Providers[provider]++
+	} else {
+		d.Misses++
+	}
+	searchStats.dirty[day] = true
+}
+
+// saveSearchStats is the `search-stats-save` job: it persists the days that changed and deletes
+// days older than searchStatsDays
+func (h *handler) saveSearchStats(ctx context.Context, job jobRecord) error {
+	searchStats.Lock()
+	pending := map[string]searchDayStats{}
+	for day := range searchStats.dirty {
+		pending[day] = searchStats.days[day].clone()
+	}
+	searchStats.dirty = map[string]bool{}
+	// past days no longer change once saved
+	today := h.cfg().day(time.Now())
+	for key := range searchStats.days {
+		if searchStatsDay(key) != today {
+			delete(searchStats.days, key)
+		}
+	}
+	searchStats.Unlock()
+
+	for day, d := range pending {
+		if err := h.store.Put(searchStatsBucket, day, d); err != nil {
+			searchStats.Lock()
+			if searchStats.days[day] == nil {
+				d := d
+				searchStats.days[day] = &d
+			}
+			searchStats.dirty[day] = true
+			searchStats.Unlock()
+			return err
+		}
+	}
+	stored, err := h.store.List(searchStatsBucket)
+	if err != nil {
+		return err
+	}
+	cutoff := h.cfg().day(time.Now().AddDate(0, 0, -searchStatsDays))
+	for key := range stored {
+		if searchStatsDay(key) < cutoff {
+			if err := h.store.Delete(searchStatsBucket, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleSearchStatsCommand implements /searchstats [days] (moderators only)
+func (h *handler) handleSearchStatsCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !h.interactionCanManage(s, i) {
+		respondEphemeral(s, i, h.interactionReply(s, i, "permission.search_stats"))
+		return
+	}
+	days := searchStatsDays
+	if o, ok := slashOptions(i)["days"]; ok && o.IntValue() > 0 && o.IntValue() < searchStatsDays {
+		days = int(o.IntValue())
+	}
+	stored, err := h.store.List(searchStatsBucket)
+	if err != nil {
+		respondEphemeral(s, i, "Could not read the search statistics, please try again later.")
+		return
+	}
+	// only this server's days; those recorded since the last save are newer than their stored copy
+	prefix := searchStatsKey(i.GuildID, "")
+	searchStats.Lock()
+	all := map[string]searchDayStats{}
+	for key, raw := range stored {
+		var d searchDayStats
+		if err := json.Unmarshal(raw, &d); err == nil && strings.HasPrefix(key, prefix) {
+			all[searchStatsDay(key)] = d
+		}
+	}
+	for key, d := range searchStats.days {
+		if strings.HasPrefix(key, prefix) {
+			all[searchStatsDay(key)] = d.clone()
+		}
+	}
+	searchStats.Unlock()
+
+	cutoff := h.cfg().day(time.Now().AddDate(0, 0, -days))
+	var total searchDayStats
+	titles, providers, channels := map[string]int{}, map[string]int{}, map[string]int{}
+	for day, d := range all {
+		if day <= cutoff {
+			continue
+		}
+		total.Lookups += d.Lookups
+		total.Misses += d.Misses
+		total.LatencyMs += d.LatencyMs
+		for k, v := range d.Titles {
+			titles[k] += v
+		}
+		for k, v := range d.Providers {
+			providers[k] += v
+		}
+		for k, v := range d.Channels {
+			channels[k] += v
+		}
+	}
+	if total.Lookups == 0 {
+		respondEphemeral(s, i, fmt.Sprintf("No lookups in the last %d days.", days))
+		return
+	}
+
+	var providerParts []string
+	for _, kv := range topCounts(providers, len(providers)) {
+		providerParts = append(providerParts, fmt.Sprintf("%s %d", providerLabel(kv.key), kv.count))
+	}
+	var titleLines, channelLines []string
+	for n, kv := range topCounts(titles, searchStatsTop) {
+		titleLines = append(titleLines, fmt.Sprintf("%d. %s (%d)", n+1, kv.key, kv.count))
+	}
+	for _, kv := range topCounts(channels, searchStatsTop) {
+		channelLines = append(channelLines, fmt.Sprintf("<#%s> (%d)", kv.key, kv.count))
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Search statistics, last %d days", days),
+		Color: 0x2f3136,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Lookups", Value: fmt.Sprint(total.Lookups), Inline: true},
+			{Name: "Miss rate", Value: fmt.Sprintf("%.1f%%", float64(total.Misses)*100/float64(total.Lookups)), Inline: true},
+			{Name: "Average latency", Value: fmt.Sprintf("%d ms", total.LatencyMs/int64(total.Lookups)), Inline: true},
+			{Name: "Served by", Value: orNone(strings.Join(providerParts, ", ")), Inline: false},
+			{Name: "Most searched", Value: truncateRunes(strings.Join(titleLines, "\n"), 1024), Inline: false},
+			{Name: "Channels", Value: truncateRunes(strings.Join(channelLines, "\n"), 1024), Inline: false},
+		},
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Embeds: []*discordgo.MessageEmbed{embed}, Flags: discordgo.MessageFlagsEphemeral},
+	})
+	if err != nil {
+		log.Printf("search stats: failed to respond: %v", err)
+	}
+}
+
+type keyCount struct {
+	key   string
+	count int
+}
+
+// topCounts returns the n largest counts, ties in key order
+func topCounts(m map[string]int, n int) []keyCount {
+	out := make([]keyCount, 0, len(m))
+	for k, v := range m {
+		out = append(out, keyCount{k, v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].count != out[j].count {
+			return out[i].count > out[j].count
+		}
+		return out[i].key < out[j].key
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "seasonal",
+		Description: "List the anime of a season, most popular first",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "season", Description: "Season (defaults to the current one)", Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Winter", Value: "WINTER"},
+				{Name: "Spring", Value: "SPRING"},
+				{Name: "Summer", Value: "SUMMER"},
+				{Name: "Fall", Value: "FALL"},
+			}},
+			{Type: discordgo.ApplicationCommandOptionInteger, Name: "year", Description: "Year (defaults to the current one)"},
+		},
+	}, (*handler).handleSeasonalCommand)
+}
+
+// currentSeason returns AniList's season and year for t (winter is January to March)
+func currentSeason(t time.Time) (string, int) {
+	return []string{"WINTER", "SPRING", "SUMMER", "FALL"}[(int(t.Month())-1)/3], t.Year()
+}
+
+// aniListListing is a short media entry used by list commands such as /seasonal and /trending
+type aniListListing struct {
+	Title struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+	} `json:"title"`
+	SiteURL           string         `json:"siteUrl"`
+	Format            string         `json:"format"`
+	AverageScore      int            `json:"averageScore"`
+	NextAiringEpisode *airingEpisode `json:"nextAiringEpisode"`
+}
+
+// line renders the entry as a numbered list line
+func (l aniListListing) line(n int) string {
+	title := l.Title.English
+	if title == "" {
+		title = l.Title.Romaji
+	}
+	line := fmt.Sprintf("%d. [%s](%s)", n, title, l.SiteURL)
+	if l.Format != "" {
+		line += " · " + strings.ReplaceAll(l.Format, "_", " ")
+	}
+	if l.AverageScore > 0 {
+		line += fmt.Sprintf(" · ⭐ %d%%", l.AverageScore)
+	}
+	if l.NextAiringEpisode != nil {
+		line += fmt.Sprintf(" · ep %d <t:%d:R>", l.NextAiringEpisode.Episode, l.NextAiringEpisode.AiringAt)
+	}
+	return line
+}
+
+// handleSeasonalCommand implements /seasonal [season] [year]
+func (h *handler) handleSeasonalCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	opts := slashOptions(i)
+	season, year := currentSeason(time.Now())
+	if o, ok := opts["season"]; ok {
+		season = o.StringValue()
+	}
+	if o, ok := opts["year"]; ok {
+		year = int(o.IntValue())
+	}
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}); err != nil {
+		log.Printf("seasonal: failed to acknowledge: %v", err)
+		return
+	}
+	vars := map[string]interface{}{"season": season, "year": year}
+	if ch, err := s.Channel(i.ChannelID); err != nil || !ch.NSFW {
+		vars["isAdult"] = false
+	}
+	var data struct {
+		Page struct {
+			Media []aniListListing `json:"media"`
+		} `json:"Page"`
+	}
+	err := aniListGraphQL(`query ($season: MediaSeason, $year: Int, $isAdult: Boolean) {
+		Page(page: 1, perPage: 50) {
+			media(season: $season, seasonYear: $year, type: ANIME, isAdult: $isAdult, sort: POPULARITY_DESC) {
+				title { romaji english }
+				siteUrl
+				format
+				averageScore
+				nextAiringEpisode { episode airingAt }
+			}
+		}
+	}`, vars, &data)
+	if err != nil {
+		log.Printf("seasonal: AniList error: %v", err)
+		msg := "❌ Could not reach AniList, try again later."
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+		return
+	}
+	label := fmt.Sprintf("%s %d", humanizeEnum(season), year)
+	if len(data.Page.Media) == 0 {
+		msg := fmt.Sprintf("No anime found for %s.", label)
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+		return
+	}
+	lines := make([]string, 0, len(data.Page.Media))
+	for n, m := range data.Page.Media {
+		lines = append(lines, m.line(n+1))
+	}
+	if err := editPaged(s, i, embedPages(label+" anime", lines, 10)); err != nil {
+		log.Printf("seasonal: failed to send: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are common words ignored when comparing thread titles and bodies
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "but": true,
+	"can": true, "cant": true, "do": true, "does": true, "dont": true, "for": true, "from": true,
+	"have": true, "help": true, "how": true, "i": true, "im": true, "in": true, "is": true, "it": true,
+	"its": true, "me": true, "my": true, "not": true, "of": true, "on": true, "or": true, "please": true,
+	"the": true, "this": true, "to": true, "when": true, "why": true, "with": true, "won": true, "wont": true,
+}
+
+// tokenize lowercases text and splits it into distinct significant words
+func tokenize(text string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	}) {
+		f = strings.Trim(f, ".")
+		if len(f) < 2 || stopWords[f] || seen[f] {
+			continue
+		}
+		seen[f] = true
+		out = append(out, f)
+	}
+	return out
+}
+
+// jaccard returns the overlap of two token sets (0..1)
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	inter := 0
+	for _, t := range b {
+		if set[t] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// trigrams returns the distinct three-letter sequences of the lowercased letters and digits of
+// text, padded so short words still yield some; used for fuzzy title matching
+func trigrams(text string) []string {
+	var b strings.Builder
+	b.WriteString("  ")
+	space := true
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+		} else if !space {
+			b.WriteRune(' ')
+			space = true
+		}
+	}
+	b.WriteRune(' ')
+	runes := []rune(b.String())
+	seen := map[string]bool{}
+	var out []string
+	for i := 0; i+3 <= len(runes); i++ {
+		t := string(runes[i : i+3])
+		if !seen[t] && strings.TrimSpace(t) != "" {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// trackerChoices are the provider options offered by the media slash commands
+var trackerChoices = []*discordgo.ApplicationCommandOptionChoice{
+	{Name: "AniList", Value: trackerAniList},
+	{Name: "Shikimori", Value: trackerShikimori},
+	{Name: "Kitsu", Value: trackerKitsu},
+	{Name: "MyAnimeList", Value: trackerMAL},
+}
+
+func init() {
+	for _, c := range []struct{ name, what string }{{"anime", "an anime"}, {"manga", "a manga"}} {
+		registerSlashCommand(&discordgo.ApplicationCommand{
+			Name:        c.name,
+			Description: "Look up " + c.what + " on your preferred tracker",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionString, Name: "title", Description: "Title to search for", Autocomplete: true},
+				{Type: discordgo.ApplicationCommandOptionInteger, Name: "id", Description: "AniList ID to show instead of searching", MinValue: &minAniListID},
+				{Type: discordgo.ApplicationCommandOptionString, Name: "provider", Description: "Tracker to search (defaults to your /tracker preference)", Choices: trackerChoices},
+				{Type: discordgo.ApplicationCommandOptionBoolean, Name: "ephemeral", Description: "Only show the result to you"},
+			},
+		}, (*handler).handleMediaCommand)
+	}
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "tracker",
+		Description: "Choose which tracker /anime and /manga link to by default",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "provider", Description: "Tracker you use with Kotatsu", Required: true, Choices: trackerChoices},
+		},
+	}, (*handler).handleTrackerCommand)
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "searchoptout",
+		Description: "Stop (or resume) lookups triggered by {title} and <title> in your messages",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionBoolean, Name: "resume", Description: "Let your messages trigger lookups again"},
+		},
+	}, (*handler).handleSearchOptOutCommand)
+	requireStore("tracker", "searchoptout")
+	allowInDMs("anime", "manga", "tracker", "searchoptout")
+}
+
+// minAniListID is the lowest value accepted by the id option of /anime and /manga
+var minAniListID = 1.0
+
+// slashOptions maps the top-level options of a slash command by name
+func slashOptions(i *discordgo.InteractionCreate) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	opts := map[string]*discordgo.ApplicationCommandInteractionDataOption{}
+	for _, o := range i.ApplicationCommandData().Options {
+		opts[o.Name] = o
+	}
+	return opts
+}
+
+// handleMediaCommand implements /anime and /manga title:<title>|id:<AniList ID> [provider:<tracker>]
+// [ephemeral:<bool>]. Without a provider option or /tracker preference, the channel's search
+// providers are tried in order; IDs (also typed as title "id:123") always go to AniList.
+func (h *handler) handleMediaCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	opts := slashOptions(i)
+	mediaType := strings.ToUpper(i.ApplicationCommandData().Name)
+	title := ""
+	if o, ok := opts["title"]; ok {
+		title = strings.TrimSpace(o.StringValue())
+	}
+	if o, ok := opts["id"]; ok {
+		title = fmt.Sprintf("id:%d", o.IntValue())
+	}
+	user := interactionUser(i)
+	ch, _ := s.Channel(i.ChannelID)
+	tracker := ""
+	if user != nil {
+		p, _ := h.loadUserPrefs(user.ID)
+		tracker = p.Tracker
+	}
+	if o, ok := opts["provider"]; ok {
+		tracker = o.StringValue()
+	}
+	allowAdult := ch != nil && ch.NSFW
+	tr := h.localizer(i.GuildID, i.ChannelID)
+	if ch != nil {
+		tr = h.localizer(ch.GuildID, ch.ID, ch.ParentID)
+	}
+	if title == "" {
+		respondEphemeral(s, i, tr.T("search.title_or_id"))
+		return
+	}
+
+	// tracker APIs can take several seconds; acknowledge first
+	ack := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}
+	if o, ok := opts["ephemeral"]; ok && o.BoolValue() {
+		ack.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	if err := s.InteractionRespond(i.Interaction, ack); err != nil {
+		log.Printf("media command: failed to acknowledge: %v", err)
+		return
+	}
+	var media *aniListMedia
+	var err error
+	started := time.Now()
+	if id, ok := parseIDQuery(title); ok {
+		tracker = providerLabel(trackerAniList)
+		media, _, err = h.lookupByID(id, mediaType, allowAdult)
+	} else if provider, ok := mediaProviders[tracker]; ok {
+		media, err = provider.Search(title, mediaType, allowAdult)
+		recordProviderResult(tracker, err, media != nil)
+		if media != nil {
+			media.Provider = tracker
+			if h.blocked(media) {
+				media = nil
+			}
+		}
+	} else {
+		tracker = strings.Join(h.searchProvidersFor(ch), ", ")
+		media, err = h.searchMedia(ch, title, mediaType, allowAdult)
+	}
+	if err != nil {
+		log.Printf("media command: %s error for %q: %v", tracker, title, err)
+	}
+	if media != nil {
+		h.recordSearch(i.GuildID, media.Title, i.ChannelID, media.Provider, true, time.Since(started))
+	} else {
+		h.recordSearch(i.GuildID, title, i.ChannelID, "", false, time.Since(started))
+	}
+	media = h.withTitleLanguage(user, media)[0]
+	edit := &discordgo.WebhookEdit{}
+	if media == nil {
+		msg := tr.T("search.no_results_on", title, tracker)
+		edit.Content = &msg
+	} else {
+		emb := h.mediaEmbed(tr, media, mediaType)
+		edit.Files = h.adultCoverFiles([]*discordgo.MessageEmbed{emb}, []*aniListMedia{media})
+		edit.Embeds = &[]*discordgo.MessageEmbed{emb}
+		if buttons := trailerButtons(tr, media); buttons != nil {
+			edit.Components = &buttons
+		}
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		log.Printf("media command: failed to send result: %v", err)
+	}
+}
+
+// handleTrackerCommand implements /tracker provider:<tracker>
+func (h *handler) handleTrackerCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
+	if user == nil {
+		return
+	}
+	tracker := slashOptions(i)["provider"].StringValue()
+	tr := h.channelLocalizer(s, i.ChannelID)
+	p, err := h.loadUserPrefs(user.ID)
+	if err != nil {
+		respondEphemeral(s, i, tr.T("prefs.save_failed"))
+		return
+	}
+	p.Tracker = tracker
+	if err := h.saveUserPrefs(user.ID, p); err != nil {
+		log.Printf("failed to save tracker preference of %s: %v", user.ID, err)
+		respondEphemeral(s, i, tr.T("prefs.save_failed"))
+		return
+	}
+	respondEphemeral(s, i, tr.T("prefs.tracker_saved", tracker))
+}
+
+// handleSearchOptOutCommand implements /searchoptout [resume:<bool>]
+func (h *handler) handleSearchOptOutCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
+	if user == nil {
+		return
+	}
+	optOut := true
+	if o, ok := slashOptions(i)["resume"]; ok && o.BoolValue() {
+		optOut = false
+	}
+	tr := h.channelLocalizer(s, i.ChannelID)
+	p, err := h.loadUserPrefs(user.ID)
+	if err != nil {
+		respondEphemeral(s, i, tr.T("prefs.save_failed"))
+		return
+	}
+	p.SearchOptOut = optOut
+	if err := h.saveUserPrefs(user.ID, p); err != nil {
+		log.Printf("failed to save search opt-out of %s: %v", user.ID, err)
+		respondEphemeral(s, i, tr.T("prefs.save_failed"))
+		return
+	}
+	if optOut {
+		respondEphemeral(s, i, tr.T("search.opted_out"))
+	} else {
+		respondEphemeral(s, i, tr.T("search.opted_in"))
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxSourceMatches bounds the sources listed when a `.source` query is ambiguous
+const maxSourceMatches = 5
+
+// findSources returns the catalog sources matching query: an exact name or title match alone,
+// otherwise titles and names containing the query, best first
+func findSources(cat parserCatalog, query string) []parserSource {
+	q := strings.ToLower(strings.TrimSpace(query))
+	norm := func(s string) string {
+		return strings.NewReplacer(" ", "", "-", "", "_", "", ".", "").Replace(strings.ToLower(s))
+	}
+	nq := norm(q)
+	var partial []parserSource
+	for _, src := range cat.Sources {
+		if strings.EqualFold(src.Name, q) || strings.EqualFold(src.Title, q) || norm(src.Title) == nq {
+			return []parserSource{src}
+		}
+		if strings.Contains(norm(src.Title), nq) || strings.Contains(norm(src.Name), nq) {
+			partial = append(partial, src)
+		}
+	}
+	// shorter titles are closer to the query
+	sort.Slice(partial, func(i, j int) bool {
+		if len(partial[i].Title) != len(partial[j].Title) {
+			return len(partial[i].Title) < len(partial[j].Title)
+		}
+		return partial[i].Title < partial[j].Title
+	})
+	return partial
+}
+
+// sourceEmbed describes one source of the catalog
+func (h *handler) sourceEmbed(src parserSource) *discordgo.MessageEmbed {
+	status, color := "✅ Working", 0x2ecc71
+	if src.Broken {
+		status, color = "❌ Broken", 0xe74c3c
+		if src.BrokenReason != "" {
+			status += " — " + src.BrokenReason
+		}
+	}
+	locale := src.Locale
+	if locale == "" {
+		locale = "multi"
+	}
+	nsfw := "No"
+	if src.NSFW() {
+		nsfw = "Yes"
+	}
+	return &discordgo.MessageEmbed{
+		Title: src.Title,
+		URL:   fmt.Sprintf("https://github.com/%s/blob/%s/%s", h.cfg().Parsers.Repo, h.cfg().Parsers.Branch, src.Path),
+		Color: color,
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Status", Value: status},
+			{Name: "Locale", Value: locale, Inline: true},
+			{Name: "Content", Value: strings.ToLower(src.ContentType), Inline: true},
+			{Name: "NSFW", Value: nsfw, Inline: true},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: src.Name},
+	}
+}
+
+// handleSource implements `.source <name>`: look a manga source up in the kotatsu-parsers catalog
+func (h *handler) handleSource(s *discordgo.Session, m *discordgo.MessageCreate, query string) {
+	if query == "" {
+		replyMessage(s, m.Message, "usage: "+h.cfg().forGuild(m.GuildID).command("source")+" <source name>")
+		return
+	}
+	cat, err := h.loadParserCatalog()
+	if err != nil || len(cat.Sources) == 0 {
+		replyMessage(s, m.Message, "The source catalog is not available yet.")
+		return
+	}
+	matches := findSources(cat, query)
+	if len(matches) == 0 {
+		replyMessage(s, m.Message, fmt.Sprintf("No source matching %q is supported.", query))
+		return
+	}
+	send := &discordgo.MessageSend{Reference: m.Reference(), AllowedMentions: &discordgo.MessageAllowedMentions{}}
+	if len(matches) == 1 {
+		send.Embeds = []*discordgo.MessageEmbed{h.sourceEmbed(matches[0])}
+	} else {
+		sb := &strings.Builder{}
+		fmt.Fprintf(sb, "%d sources match %q:\n", len(matches), query)
+		for i, src := range matches {
+			if i == maxSourceMatches {
+				fmt.Fprintf(sb, "…and %d more\n", len(matches)-maxSourceMatches)
+				break
+			}
+			state := "working"
+			if src.Broken {
+				state = "broken"
+			}
+			fmt.Fprintf(sb, "- **%s** (`%s`, %s) — %s\n", src.Title, src.Name, src.Locale, state)
+		}
+		send.Content = sb.String()
+	}
+	if _, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(send, m.Message)); err != nil {
+		log.Printf("source: failed to reply: %v", err)
+	}
+}
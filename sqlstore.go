@@ -0,0 +1,331 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Backends of the store setting
+const (
+	storeFile     = "file"     // a JSON file rewritten on every change (default)
+	storeSQLite   = "sqlite"   // a SQLite database, one row per key
+	storePostgres = "postgres" // a PostgreSQL database reached through database_url
+	storeBolt     = "bolt"     // an embedded bbolt key-value file, one key per entry
+)
+
+// sqlMigrations are the schema changes of the SQL stores, applied in order at startup. A
+// migration is never edited once released; changes go into a new entry appended at the end.
+var sqlMigrations = []string{
+	`CREATE TABLE kv (
+		bucket TEXT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		PRIMARY KEY (bucket, key)
+	)`,
+}
+
+// sqlStore keeps the buckets in a database table with a row per key, so a change writes that row
+// only instead of the whole state as the file store does.
+type sqlStore struct {
+	db *sql.DB
+	// postgres selects the PostgreSQL dialect; queries are written for SQLite
+	postgres bool
+}
+
+// q rewrites the ? placeholders of query into $1, $2... for PostgreSQL. A ? inside a quoted
+// string literal is left alone.
+func (st *sqlStore) q(query string) string {
+	if !st.postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var sb strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		if r == '\'' {
+			quoted = !quoted
+		}
+		if r != '?' || quoted {
+			sb.WriteRune(r)
+			continue
+		}
+		n++
+		sb.WriteString("$" + strconv.Itoa(n))
+	}
+	return sb.String()
+}
+
+// openSQLiteStore opens (or creates) the SQLite database at path and brings its schema up to
+// date. A new database takes over the state of a state.json file next to it.
+//
+// A dry run does not create or migrate the database: it reads an existing one, or starts from an
+// empty in-memory one, and keeps changes in memory.
+func openSQLiteStore(path string) (Store, error) {
+	if dryRun {
+		return openSQLiteDryRun(path)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	// one connection serializes the writers, which SQLite would otherwise answer with "database is locked"
+	db.SetMaxOpenConns(1)
+	st := &sqlStore{db: db}
+	if err := st.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if legacy := filepath.Join(filepath.Dir(path), "state.json"); legacy != path {
+		if err := st.importFileStore(legacy); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("importing %s: %v", legacy, err)
+		}
+	}
+	return st, nil
+}
+
+// openSQLiteDryRun opens the database at path read-only for a dry run
+func openSQLiteDryRun(path string) (Store, error) {
+	dsn := "file:" + path + "?mode=ro&_busy_timeout=5000"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		dsn = ":memory:"
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	st := &sqlStore{db: db}
+	if dsn == ":memory:" {
+		err = st.migrate()
+	} else {
+		err = st.checkSchema()
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return newOverlayStore(st), nil
+}
+
+// openPostgresStore connects to the PostgreSQL database of dsn and brings its schema up to date.
+// A new database takes over the state of the JSON state file at legacy when there is one.
+//
+// A dry run only reads the database, which must be migrated already; a new one is replaced by an
+// empty in-memory store.
+func openPostgresStore(dsn, legacy string) (Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	st := &sqlStore{db: db, postgres: true}
+	if dryRun {
+		version, err := st.schemaVersion()
+		switch {
+		case err == nil && version == 0:
+			db.Close()
+			return newOverlayStore(&fileStore{buckets: map[string]map[string]json.RawMessage{}}), nil
+		case err == nil:
+			err = st.checkSchema()
+		}
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		return newOverlayStore(st), nil
+	}
+	if err := st.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := st.importFileStore(legacy); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("importing %s: %v", legacy, err)
+	}
+	return st, nil
+}
+
+// schemaVersion returns the number of migrations applied to the database, 0 for a new one
+func (st *sqlStore) schemaVersion() (int, error) {
+	exists := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	if st.postgres {
+		exists = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+	}
+	var n int
+	if err := st.db.QueryRow(exists).Scan(&n); err != nil || n == 0 {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := st.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// checkSchema reports an error unless the database is at the schema version of this build
+func (st *sqlStore) checkSchema() error {
+	version, err := st.schemaVersion()
+	switch {
+	case err != nil:
+		return err
+	case version > len(sqlMigrations):
+		return fmt.Errorf("the database has schema version %d, newer than this build supports (%d)", version, len(sqlMigrations))
+	case version < len(sqlMigrations):
+		return fmt.Errorf("the database needs migrating to schema version %d, which a dry run does not do; start the bot once without --dry-run", len(sqlMigrations))
+	}
+	return nil
+}
+
+// migrate applies the migrations the database has not seen yet, each in its own transaction. On
+// PostgreSQL an advisory lock keeps replicas starting at the same time from applying one twice.
+func (st *sqlStore) migrate() error {
+	if _, err := st.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at BIGINT NOT NULL)`); err != nil {
+		return err
+	}
+	version, err := st.schemaVersion()
+	if err != nil {
+		return err
+	}
+	if version > len(sqlMigrations) {
+		return fmt.Errorf("the database has schema version %d, newer than this build supports (%d)", version, len(sqlMigrations))
+	}
+	for i := version; i < len(sqlMigrations); i++ {
+		if err := st.applyMigration(i + 1); err != nil {
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration applies migration version unless another process did so first
+func (st *sqlStore) applyMigration(version int) error {
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if st.postgres {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(7283104615)`); err != nil {
+			return err
+		}
+	}
+	var n int
+	if err := tx.QueryRow(st.q(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`), version).Scan(&n); err != nil || n > 0 {
+		return err
+	}
+	if _, err := tx.Exec(sqlMigrations[version-1]); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(st.q(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), version, time.Now().Unix()); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("store: applied schema migration %d", version)
+	return nil
+}
+
+// importFileStore copies the buckets of the JSON state file at path into an empty database, so
+// switching store keeps jobs, links and statistics. The file is left in place.
+func (st *sqlStore) importFileStore(path string) error {
+	var n int
+	if err := st.db.QueryRow(`SELECT COUNT(*) FROM kv`).Scan(&n); err != nil || n > 0 {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	fs, err := openFileStore(path)
+	if err != nil {
+		return err
+	}
+	tx, err := st.db.Begin()
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for bucket, values := range fs.buckets {
+		for key, raw := range values {
+			if _, err := tx.Exec(st.q(`INSERT INTO kv (bucket, key, value, updated_at) VALUES (?, ?, ?, ?)`), bucket, key, string(raw), now); err != nil {
+				tx.Rollback()
+				return err
+			}
+			n++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("store: imported %d entries from %s", n, path)
+	return nil
+}
+
+func (st *sqlStore) Get(bucket, key string, v interface{}) (bool, error) {
+	var raw string
+	err := st.db.QueryRow(st.q(`SELECT value FROM kv WHERE bucket = ? AND key = ?`), bucket, key).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(raw), v)
+}
+
+func (st *sqlStore) Put(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = st.db.Exec(st.q(`INSERT INTO kv (bucket, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`),
+		bucket, key, string(raw), time.Now().Unix())
+	return err
+}
+
+func (st *sqlStore) Delete(bucket, key string) error {
+	_, err := st.db.Exec(st.q(`DELETE FROM kv WHERE bucket = ? AND key = ?`), bucket, key)
+	return err
+}
+
+func (st *sqlStore) List(bucket string) (map[string]json.RawMessage, error) {
+	rows, err := st.db.Query(st.q(`SELECT key, value FROM kv WHERE bucket = ?`), bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]json.RawMessage{}
+	for rows.Next() {
+		var key, raw string
+		if err := rows.Scan(&key, &raw); err != nil {
+			return nil, err
+		}
+		out[key] = json.RawMessage(raw)
+	}
+	return out, rows.Err()
+}
+
+func (st *sqlStore) Close() error {
+	return st.db.Close()
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestSQLStoreQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		postgres bool
+		query    string
+		want     string
+	}{
+		{"sqlite unchanged", false, "SELECT value FROM kv WHERE bucket = ? AND key = ?", "SELECT value FROM kv WHERE bucket = ? AND key = ?"},
+		{"no placeholders", true, "SELECT COUNT(*) FROM kv", "SELECT COUNT(*) FROM kv"},
+		{"one placeholder", true, "DELETE FROM kv WHERE bucket = ?", "DELETE FROM kv WHERE bucket = $1"},
+		{"numbered in order", true, "INSERT INTO kv (bucket, key, value, updated_at) VALUES (?, ?, ?, ?)", "INSERT INTO kv (bucket, key, value, updated_at) VALUES ($1, $2, $3, $4)"},
+		{"more than nine", true, "VALUES (?,?,?,?,?,?,?,?,?,?)", "VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)"},
+		{"across lines", true, "SELECT ?\nFROM kv WHERE key = ?", "SELECT $1\nFROM kv WHERE key = $2"},
+		{"string literal kept", true, "SELECT key FROM kv WHERE value = '?' AND bucket = ?", "SELECT key FROM kv WHERE value = '?' AND bucket = $1"},
+		{"escaped quote in a literal", true, "SELECT 'it''s ?', ?", "SELECT 'it''s ?', $1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &sqlStore{postgres: tt.postgres}
+			if got := st.q(tt.query); got != tt.want {
+				t.Errorf("q(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// staffTriggerRe matches the ((staff name)) search syntax in messages
+var staffTriggerRe = regexp.MustCompile(`\(\(([^()]{2,80})\)\)`)
+
+func init() {
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "staff",
+		Description: "Look up a mangaka, voice actor or other creator on AniList",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "name", Description: "Name to search for", Required: true},
+			{Type: discordgo.ApplicationCommandOptionBoolean, Name: "ephemeral", Description: "Only show the result to you"},
+		},
+	}, (*handler).handleStaffCommand)
+	allowInDMs("staff")
+}
+
+type staffWork struct {
+	Title struct {
+		Romaji  string `json:"romaji"`
+		English string `json:"english"`
+	} `json:"title"`
+	SiteURL string `json:"siteUrl"`
+	Type    string `json:"type"`
+	IsAdult bool   `json:"isAdult"`
+}
+
+func (w staffWork) title() string {
+	if w.Title.English != "" {
+		return w.Title.English
+	}
+	return w.Title.Romaji
+}
+
+// aniListStaff is the subset of an AniList staff entry shown by staff lookups
+type aniListStaff struct {
+	Name struct {
+		Full   string `json:"full"`
+		Native string `json:"native"`
+	} `json:"name"`
+	SiteURL string `json:"siteUrl"`
+	Image   struct {
+		Large string `json:"large"`
+	} `json:"image"`
+	Description        string   `json:"description"`
+	PrimaryOccupations []string `json:"primaryOccupations"`
+	StaffMedia         struct {
+		Edges []struct {
+			StaffRole string    `json:"staffRole"`
+			Node      staffWork `json:"node"`
+		} `json:"edges"`
+	} `json:"staffMedia"`
+	CharacterMedia struct {
+		Edges []struct {
+			Characters []struct {
+				Name struct {
+					Full string `json:"full"`
+				} `json:"name"`
+			} `json:"characters"`
+			Node staffWork `json:"node"`
+		} `json:"edges"`
+	} `json:"characterMedia"`
+}
+
+// searchAniListStaff returns the staff entry best matching name, or nil
+func searchAniListStaff(name string) (*aniListStaff, error) {
+	var data struct {
+		Page struct {
+			Staff []aniListStaff `json:"staff"`
+		} `json:"Page"`
+	}
+	err := aniListGraphQL(`query ($search: String) {
+		Page(page: 1, perPage: 1) {
+			staff(search: $search, sort: SEARCH_MATCH) {
+				name { full native }
+				siteUrl
+				image { large }
+				description(asHtml: true)
+				primaryOccupations
+				staffMedia(sort: POPULARITY_DESC, perPage: 10) {
+					edges { staffRole node { title { romaji english } siteUrl type isAdult } }
+				}
+				characterMedia(sort: POPULARITY_DESC, perPage: 10) {
+					edges { characters { name { full } } node { title { romaji english } siteUrl type isAdult } }
+				}
+			}
+		}
+	}`, map[string]interface{}{"search": name}, &data)
+	if err != nil || len(data.Page.Staff) == 0 {
+		return nil, err
+	}
+	return &data.Page.Staff[0], nil
+}
+
+// toEmbed lists the person's best-known works; adult works are left out unless allowAdult
+func (st *aniListStaff) toEmbed(allowAdult bool) *discordgo.MessageEmbed {
+	title := st.Name.Full
+	if st.Name.Native != "" {
+		title += " (" + st.Name.Native + ")"
+	}
+	desc := strings.TrimSpace(markdownSpoilerRe.ReplaceAllString(htmlToMarkdown(st.Description), ""))
+	if r := []rune(desc); len(r) > 500 {
+		desc = string(r[:500]) + "..."
+	}
+	embed := &discordgo.MessageEmbed{Title: title, URL: st.SiteURL, Description: desc, Color: 0x2f3136}
+	if st.Image.Large != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: st.Image.Large}
+	}
+	if len(st.PrimaryOccupations) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Occupation", Value: strings.Join(st.PrimaryOccupations, ", "), Inline: true})
+	}
+
+	var works []string
+	seen := map[string]bool{}
+	for _, e := range st.StaffMedia.Edges {
+		if (e.Node.IsAdult && !allowAdult) || seen[e.Node.SiteURL] || len(works) >= 6 {
+			continue
+		}
+		seen[e.Node.SiteURL] = true
+		works = append(works, fmt.Sprintf("[%s](%s) · %s", e.Node.title(), e.Node.SiteURL, e.StaffRole))
+	}
+	if len(works) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Works", Value: truncateRunes(strings.Join(works, "\n"), 1024)})
+	}
+	var roles []string
+	for _, e := range st.CharacterMedia.Edges {
+		if (e.Node.IsAdult && !allowAdult) || len(e.Characters) == 0 || len(roles) >= 6 {
+			continue
+		}
+		roles = append(roles, fmt.Sprintf("%s in [%s](%s)", e.Characters[0].Name.Full, e.Node.title(), e.Node.SiteURL))
+	}
+	if len(roles) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{Name: "Voiced", Value: truncateRunes(strings.Join(roles, "\n"), 1024)})
+	}
+	return embed
+}
+
+// handleStaffCommand implements /staff name:<name> [ephemeral:<bool>]
+func (h *handler) handleStaffCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	opts := slashOptions(i)
+	name := strings.TrimSpace(opts["name"].StringValue())
+	ack := &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}
+	if o, ok := opts["ephemeral"]; ok && o.BoolValue() {
+		ack.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	if err := s.InteractionRespond(i.Interaction, ack); err != nil {
+		log.Printf("staff command: failed to acknowledge: %v", err)
+		return
+	}
+	staff, err := searchAniListStaff(name)
+	if err != nil {
+		log.Printf("staff command: AniList error for %q: %v", name, err)
+	}
+	edit := &discordgo.WebhookEdit{}
+	if staff == nil {
+		msg := fmt.Sprintf("No staff found for %q.", name)
+		edit.Content = &msg
+	} else {
+		ch, _ := s.Channel(i.ChannelID)
+		edit.Embeds = &[]*discordgo.MessageEmbed{staff.toEmbed(ch != nil && ch.NSFW)}
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+		log.Printf("staff command: failed to send result: %v", err)
+	}
+}
+
+// searchStaffInMessage answers the ((name)) trigger with the first matching staff entry
+func (h *handler) searchStaffInMessage(s *discordgo.Session, m *discordgo.MessageCreate, allowAdult bool) bool {
+	match := staffTriggerRe.FindStringSubmatch(m.Content)
+	if match == nil {
+		return false
+	}
+	name := strings.TrimSpace(match[1])
+	defer showTyping(s, m.ChannelID)()
+	staff, err := searchAniListStaff(name)
+	if err != nil {
+		log.Printf("search: AniList staff error for %q: %v", name, err)
+	}
+	if staff == nil {
+		log.Printf("search: no staff found for %q", name)
+		return true
+	}
+	if _, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{staff.toEmbed(allowAdult)}}, m.Message)); err != nil {
+		log.Printf("search: failed to send staff result: %v", err)
+	}
+	return true
+}
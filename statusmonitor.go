@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// serviceHealth tracks probe results of one monitored upstream service
+type serviceHealth struct {
+	failures  int
+	down      bool
+	downSince time.Time
+	lastError string
+}
+
+// probeServices is the scheduled job that checks every monitored service and announces outages
+// once a service fails failure_threshold probes in a row, and recoveries when it answers again
+func (h *handler) probeServices(ctx context.Context, job jobRecord) error {
+	mc := h.cfg().StatusMonitor
+	threshold := mc.FailureThreshold
+	if threshold <= 0 {
+		threshold = 3
+	}
+	for _, svc := range mc.Services {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		err := probeService(ctx, svc)
+
+		h.mu.Lock()
+		st := h.serviceHealth[svc.Name]
+		if st == nil {
+			st = &serviceHealth{}
+			h.serviceHealth[svc.Name] = st
+		}
+		var announce string
+		if err != nil {
+			st.failures++
+			st.lastError = err.Error()
+			if !st.down && st.failures >= threshold {
+				st.down = true
+				st.downSince = time.Now()
+				announce = fmt.Sprintf("⚠️ **%s** appears to be down upstream (%d failed checks, last error: %s). Related reports are likely not Kotatsu bugs.", svc.Name, st.failures, st.lastError)
+			}
+		} else {
+			if st.down {
+				announce = fmt.Sprintf("✅ **%s** is responding again (was down since <t:%d:R>).", svc.Name, st.downSince.Unix())
+			}
+			*st = serviceHealth{}
+		}
+		h.mu.Unlock()
+
+		if err != nil {
+			log.Printf("status monitor: %s probe failed: %v", svc.Name, err)
+		}
+		if announce != "" && mc.ChannelID != "" {
+			sendMessage(h.dg, mc.ChannelID, announce)
+		}
+	}
+	return nil
+}
+
+// probeService performs one health probe. Any answer below 500 counts as healthy unless the
+// service lists the exact status codes it expects.
+func probeService(ctx context.Context, svc MonitoredService) error {
+	method := svc.Method
+	if method == "" {
+		method = "GET"
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, svc.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if len(svc.ExpectStatus) > 0 {
+		for _, code := range svc.ExpectStatus {
+			if resp.StatusCode == code {
+				return nil
+			}
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// appendOutageBanner posts a note in a new post that mentions a service currently marked down
+func (h *handler) appendOutageBanner(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message) {
+	text := strings.ToLower(th.Name + "\n" + starter.Content)
+	var notes []string
+	h.mu.Lock()
+	for _, svc := range h.cfg().StatusMonitor.Services {
+		st := h.serviceHealth[svc.Name]
+		if st == nil || !st.down {
+			continue
+		}
+		keywords := svc.Keywords
+		if len(keywords) == 0 {
+			keywords = []string{svc.Name}
+		}
+		for _, kw := range keywords {
+			if strings.Contains(text, strings.ToLower(kw)) {
+				notes = append(notes, fmt.Sprintf("⚠️ **%s** is currently down upstream (since <t:%d:R>). Sync or login problems with it are expected until it recovers.", svc.Name, st.downSince.Unix()))
+				break
+			}
+		}
+	}
+	h.mu.Unlock()
+	if len(notes) > 0 {
+		sendMessage(s, th.ID, strings.Join(notes, "\n"))
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Title styles of status_style
+const (
+	statusStyleBrackets = "brackets" // "[Solved] title" (default)
+	statusStyleEmoji    = "emoji"    // "✅ title"
+	statusStyleTag      = "tag"      // the forum tag only; the title is left alone
+)
+
+// checkStatusStyle validates a status_style and status_prefixes pair; field prefixes errors
+func checkStatusStyle(field, style string, prefixes map[string]string) error {
+	switch style {
+	case "", statusStyleBrackets, statusStyleEmoji, statusStyleTag:
+	default:
+		return fmt.Errorf("%sstatus_style: unknown value %q (use %q, %q or %q)", field, style, statusStyleBrackets, statusStyleEmoji, statusStyleTag)
+	}
+	for cmd := range prefixes {
+		if _, ok := commandConfig[cmd]; !ok {
+			return fmt.Errorf("%sstatus_prefixes: unknown status %q (use one of the status commands, e.g. \"solved\")", field, cmd)
+		}
+	}
+	return nil
+}
+
+// statusPrefix returns the title prefix of the status command cmd in the configured style; ""
+// means the title carries no status
+func (cfg *Config) statusPrefix(cmd string) string {
+	if p, ok := cfg.StatusPrefixes[cmd]; ok {
+		return strings.TrimSpace(p)
+	}
+	switch cfg.StatusStyle {
+	case statusStyleEmoji:
+		return commandConfig[cmd].Emoji
+	case statusStyleTag:
+		return ""
+	}
+	return commandConfig[cmd].Prefix
+}
+
+// statusTitle returns name with its status prefix replaced by the one of cmd. Prefixes of every
+// style are removed, so switching styles cleans titles up as statuses change.
+func (cfg *Config) statusTitle(name, cmd string) string {
+	stripped := stripStatusPrefixes(name, cfg.customStatusPrefixes()...)
+	if p := cfg.statusPrefix(cmd); p != "" {
+		return p + " " + stripped
+	}
+	return stripped
+}
+
+// customStatusPrefixes returns the prefixes set through status_prefixes
+func (cfg *Config) customStatusPrefixes() []string {
+	var prefixes []string
+	for _, p := range cfg.StatusPrefixes {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// statusFromTitle returns the status command whose prefix the thread title starts with, in any
+// style, or ""
+func (cfg *Config) statusFromTitle(name string) string {
+	lowered := strings.ToLower(strings.TrimSpace(name))
+	for cmd, c := range commandConfig {
+		for _, p := range []string{cfg.statusPrefix(cmd), c.Prefix, c.Emoji} {
+			if p != "" && strings.HasPrefix(lowered, strings.ToLower(p)) {
+				return cmd
+			}
+		}
+	}
+	return ""
+}
+
+// statusTag returns the name of the forum tag of the status command cmd in a forum
+func (cfg *Config) statusTag(forumID, cmd string) string {
+	if tag, ok := cfg.Forums[forumID].StatusTags[cmd]; ok {
+		return tag
+	}
+	return commandConfig[cmd].TagName
+}
+
+// threadStatus returns the status of a thread from its title or, when the title carries none (as
+// with status_style: tag), from the status tag applied to it
+func (h *handler) threadStatus(s *discordgo.Session, th *discordgo.Channel) string {
+	if cmd := h.cfg().forGuild(th.GuildID).statusFromTitle(th.Name); cmd != "" || len(th.AppliedTags) == 0 {
+		return cmd
+	}
+	tags, err := fetchForumTags(s, th.ParentID)
+	if err != nil {
+		return ""
+	}
+	cfg := h.cfg()
+	for cmd := range commandConfig {
+		t, ok := findForumTag(tags, cfg.statusTag(th.ParentID, cmd))
+		if !ok {
+			continue
+		}
+		for _, id := range th.AppliedTags {
+			if id == t.ID {
+				return cmd
+			}
+		}
+	}
+	return ""
+}
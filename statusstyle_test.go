@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestStatusTitle(t *testing.T) {
+	custom := map[string]string{"solved": "Fixed:"}
+	tests := []struct {
+		name     string
+		style    string
+		prefixes map[string]string
+		title    string
+		cmd      string
+		want     string
+	}{
+		{"default style", "", nil, "my bug", "solved", "[Solved] my bug"},
+		{"brackets", statusStyleBrackets, nil, "my bug", "solved", "[Solved] my bug"},
+		{"status replaced", "", nil, "[Duplicate] my bug", "solved", "[Solved] my bug"},
+		{"status matched case-insensitively", "", nil, "[solved] my bug", "duplicate", "[Duplicate] my bug"},
+		{"user brackets kept", "", nil, "[Help!] my bug", "solved", "[Solved] [Help!] my bug"},
+		{"emoji", statusStyleEmoji, nil, "my bug", "solved", "✅ my bug"},
+		{"emoji replaces brackets", statusStyleEmoji, nil, "[Solved] my bug", "aware", "🔁 my bug"},
+		{"brackets replace emoji", "", nil, "✅ my bug", "known", "[Known issue] my bug"},
+		{"tag strips the title", statusStyleTag, nil, "[Solved] my bug", "solved", "my bug"},
+		{"custom prefix", "", custom, "my bug", "solved", "Fixed: my bug"},
+		{"custom prefix replaced", "", custom, "Fixed: my bug", "aware", "[Devs aware] my bug"},
+		{"custom prefix over the style", statusStyleTag, custom, "my bug", "solved", "Fixed: my bug"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{StatusStyle: tt.style, StatusPrefixes: tt.prefixes}
+			if got := cfg.statusTitle(tt.title, tt.cmd); got != tt.want {
+				t.Errorf("statusTitle(%q, %q) = %q, want %q", tt.title, tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusFromTitle(t *testing.T) {
+	custom := map[string]string{"solved": "Fixed:"}
+	tests := []struct {
+		name     string
+		style    string
+		prefixes map[string]string
+		title    string
+		want     string
+	}{
+		{"brackets", "", nil, "[Solved] my bug", "solved"},
+		{"lower case", "", nil, "[known issue] my bug", "known"},
+		{"leading space", "", nil, "  [Duplicate] my bug", "duplicate"},
+		{"emoji in the brackets style", "", nil, "✅ my bug", "solved"},
+		{"brackets in the emoji style", statusStyleEmoji, nil, "[Wrong channel] my bug", "wrong"},
+		{"no status", "", nil, "my bug", ""},
+		{"user brackets", "", nil, "[Help!] my bug", ""},
+		{"status later in the title", "", nil, "my bug [Solved]", ""},
+		{"tag style title", statusStyleTag, nil, "my bug", ""},
+		{"custom prefix", "", custom, "Fixed: my bug", "solved"},
+		{"default prefix with a custom one set", "", custom, "[Solved] my bug", "solved"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{StatusStyle: tt.style, StatusPrefixes: tt.prefixes}
+			if got := cfg.statusFromTitle(tt.title); got != tt.want {
+				t.Errorf("statusFromTitle(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+		})
+	}
+}
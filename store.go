@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store is a small key-value persistence layer. Values are JSON-encoded and
+// grouped into buckets (one bucket per feature, e.g. "jobs").
+type Store interface {
+	// Get decodes the value stored under bucket/key into v. It reports false when the key does not exist.
+	Get(bucket, key string, v interface{}) (bool, error)
+	Put(bucket, key string, v interface{}) error
+	Delete(bucket, key string) error
+	// List returns every raw value in the bucket keyed by its key.
+	List(bucket string) (map[string]json.RawMessage, error)
+	Close() error
+}
+
+// fileStore keeps all buckets in memory and rewrites a single JSON file on every change.
+type fileStore struct {
+	mu      sync.Mutex
+	path    string
+	buckets map[string]map[string]json.RawMessage
+}
+
+// openFileStore loads (or creates) the JSON state file at path
+func openFileStore(path string) (*fileStore, error) {
+	fs := &fileStore{path: path, buckets: map[string]map[string]json.RawMessage{}}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fs, nil
+		}
+		return nil, err
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &fs.buckets); err != nil {
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+func (fs *fileStore) Get(bucket, key string, v interface{}) (bool, error) {
+	fs.mu.Lock()
+	raw, ok := fs.buckets[bucket][key]
+	fs.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+func (fs *fileStore) Put(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.buckets[bucket] == nil {
+		fs.buckets[bucket] = map[string]json.RawMessage{}
+	}
+	fs.buckets[bucket][key] = raw
+	return fs.flushLocked()
+}
+
+func (fs *fileStore) Delete(bucket, key string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if _, ok := fs.buckets[bucket][key]; !ok {
+		return nil
+	}
+	delete(fs.buckets[bucket], key)
+	return fs.flushLocked()
+}
+
+func (fs *fileStore) List(bucket string) (map[string]json.RawMessage, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	out := make(map[string]json.RawMessage, len(fs.buckets[bucket]))
+	for k, v := range fs.buckets[bucket] {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (fs *fileStore) Close() error {
+	return nil
+}
+
+// flushLocked writes the state to a temp file and renames it over the original so a crash never leaves a partial file.
+// In a dry run changes are only kept in memory.
+func (fs *fileStore) flushLocked() error {
+	if dryRun {
+		return nil
+	}
+	b, err := json.Marshal(fs.buckets)
+	if err != nil {
+		return err
+	}
+	tmp := fs.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fs.path)
+}
+
+// overlayStore keeps the changes of a dry run in memory on top of a store that must not be
+// written, so features still read back what they stored
+type overlayStore struct {
+	Store
+	mu sync.Mutex
+	// changes holds the values put per bucket; nil marks a deleted key
+	changes map[string]map[string]json.RawMessage
+}
+
+func newOverlayStore(inner Store) *overlayStore {
+	return &overlayStore{Store: inner, changes: map[string]map[string]json.RawMessage{}}
+}
+
+func (o *overlayStore) Get(bucket, key string, v interface{}) (bool, error) {
+	o.mu.Lock()
+	raw, ok := o.changes[bucket][key]
+	o.mu.Unlock()
+	if !ok {
+		return o.Store.Get(bucket, key, v)
+	}
+	if raw == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+func (o *overlayStore) Put(bucket, key string, v interface{}) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	o.set(bucket, key, raw)
+	return nil
+}
+
+func (o *overlayStore) Delete(bucket, key string) error {
+	o.set(bucket, key, nil)
+	return nil
+}
+
+func (o *overlayStore) set(bucket, key string, raw json.RawMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.changes[bucket] == nil {
+		o.changes[bucket] = map[string]json.RawMessage{}
+	}
+	o.changes[bucket][key] = raw
+}
+
+func (o *overlayStore) List(bucket string) (map[string]json.RawMessage, error) {
+	out, err := o.Store.List(bucket)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for k, raw := range o.changes[bucket] {
+		if raw == nil {
+			delete(out, k)
+		} else {
+			out[k] = raw
+		}
+	}
+	return out, nil
+}
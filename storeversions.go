@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// storeVersion is the latest version of the app published on one distribution channel
+type storeVersion struct {
+	Store   string
+	Version string
+	URL     string
+	Err     error
+}
+
+// fdroidLatest returns the suggested version of pkg from the official F-Droid API
+func fdroidLatest(pkg string) (string, error) {
+	var out struct {
+		SuggestedVersionCode int `json:"suggestedVersionCode"`
+		Packages             []struct {
+			VersionName string `json:"versionName"`
+			VersionCode int    `json:"versionCode"`
+		} `json:"packages"`
+	}
+	if err := getJSON("https://f-droid.org/api/v1/packages/"+pkg, nil, &out); err != nil {
+		return "", err
+	}
+	for _, p := range out.Packages {
+		if p.VersionCode == out.SuggestedVersionCode {
+			return p.VersionName, nil
+		}
+	}
+	if len(out.Packages) > 0 {
+		return out.Packages[0].VersionName, nil
+	}
+	return "", fmt.Errorf("no packages listed")
+}
+
+// repoIndexLatest returns the newest version of pkg in an F-Droid compatible repository index (index-v1.json)
+func repoIndexLatest(indexURL, pkg string) (string, error) {
+	var out struct {
+		Packages map[string][]struct {
+			VersionName string `json:"versionName"`
+			VersionCode int    `json:"versionCode"`
+		} `json:"packages"`
+	}
+	if err := getJSON(indexURL, nil, &out); err != nil {
+		return "", err
+	}
+	best, code := "", -1
+	for _, p := range out.Packages[pkg] {
+		if p.VersionCode > code {
+			best, code = p.VersionName, p.VersionCode
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%s is not in the index", pkg)
+	}
+	return best, nil
+}
+
+// fetchStoreVersions queries every configured distribution channel concurrently
+func (h *handler) fetchStoreVersions() []storeVersion {
+	rc := h.cfg().Releases
+	var lookups []func() storeVersion
+	if rc.Repo != "" {
+		lookups = append(lookups, func() storeVersion {
+			r, err := latestRelease(h.cfg().GitHubToken, rc.Repo)
+			return storeVersion{Store: "GitHub", Version: r.TagName, URL: r.HTMLURL, Err: err}
+		})
+	}
+	if rc.FDroidPackage != "" {
+		lookups = append(lookups, func() storeVersion {
+			v, err := fdroidLatest(rc.FDroidPackage)
+			return storeVersion{Store: "F-Droid", Version: v, URL: "https://f-droid.org/packages/" + rc.FDroidPackage, Err: err}
+		})
+	}
+	if rc.RepoIndexURL != "" {
+		lookups = append(lookups, func() storeVersion {
+			v, err := repoIndexLatest(rc.RepoIndexURL, rc.FDroidPackage)
+			return storeVersion{Store: "Kotatsu repo", Version: v, URL: strings.TrimSuffix(rc.RepoIndexURL, "/index-v1.json"), Err: err}
+		})
+	}
+
+	out := make([]storeVersion, len(lookups))
+	var wg sync.WaitGroup
+	for i, fn := range lookups {
+		wg.Add(1)
+		go func(i int, fn func() storeVersion) {
+			defer wg.Done()
+			out[i] = fn()
+		}(i, fn)
+	}
+	wg.Wait()
+	return out
+}
+
+// handleStores implements `.stores`: the latest version on each distribution channel, flagging
+// the channels that have not caught up with the newest one yet
+func (h *handler) handleStores(s *discordgo.Session, m *discordgo.MessageCreate) {
+	versions := h.fetchStoreVersions()
+	if len(versions) == 0 {
+		replyMessage(s, m.Message, "No distribution channels are configured.")
+		return
+	}
+	newest := ""
+	for _, v := range versions {
+		if v.Err != nil {
+			log.Printf("stores: failed to fetch the %s version: %v", v.Store, v.Err)
+			continue
+		}
+		if newest == "" || compareVersions(strings.TrimPrefix(v.Version, "v"), newest) > 0 {
+			newest = strings.TrimPrefix(v.Version, "v")
+		}
+	}
+
+	var lines []string
+	lagging := false
+	for _, v := range versions {
+		switch {
+		case v.Err != nil:
+			lines = append(lines, fmt.Sprintf("❔ **%s**: unavailable", v.Store))
+		case compareVersions(strings.TrimPrefix(v.Version, "v"), newest) < 0:
+			lagging = true
+			lines = append(lines, fmt.Sprintf("⏳ **%s**: %s (behind %s) — <%s>", v.Store, v.Version, newest, v.URL))
+		default:
+			lines = append(lines, fmt.Sprintf("✅ **%s**: %s — <%s>", v.Store, v.Version, v.URL))
+		}
+	}
+	if lagging {
+		lines = append(lines, "Updates reach every channel at a different pace; a bug fixed in the newest version may still be present on a channel marked ⏳.")
+	}
+	replyMessage(s, m.Message, strings.Join(lines, "\n"))
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// maxSuggestions is the number of "did you mean" titles offered for a search without results
+	maxSuggestions = 3
+	// popularTitlePages is how many pages of 50 popular titles per media type are compared with
+	// failed searches
+	popularTitlePages = 4
+	// popularTitlesTTL is how long the popular titles are kept before they are fetched again
+	popularTitlesTTL = 24 * time.Hour
+	// minSuggestionSimilarity is the trigram similarity a popular title needs to be suggested
+	minSuggestionSimilarity = 0.3
+)
+
+// popularTitle is an entry of the popular titles list with the trigrams of each of its names
+type popularTitle struct {
+	title    string
+	url      string
+	trigrams [][]string
+}
+
+var popularTitles = struct {
+	sync.Mutex
+	lists   map[string][]popularTitle
+	fetched map[string]time.Time
+}{lists: map[string][]popularTitle{}, fetched: map[string]time.Time{}}
+
+// suggestion is a title offered instead of a search without results
+type suggestion struct {
+	title string
+	url   string
+	score float64
+}
+
+// suggestTitles finds up to maxSuggestions titles the user may have meant: AniList is searched
+// again without punctuation and symbols, and the query is compared by trigram similarity with
+// the romaji and English names of the most popular titles
+func (h *handler) suggestTitles(name, mediaType string) []suggestion {
+	var out []suggestion
+	seen := map[string]bool{}
+	add := func(sg suggestion) {
+		if !seen[sg.url] && len(out) < maxSuggestions {
+			seen[sg.url] = true
+			out = append(out, sg)
+		}
+	}
+
+	if relaxed := relaxQuery(name); relaxed != "" && relaxed != strings.ToLower(strings.TrimSpace(name)) {
+		results, err := cachedAniListSearch(relaxed, mediaType, false, maxSuggestions)
+		if err != nil {
+			log.Printf("search: relaxed AniList search for %q failed: %v", relaxed, err)
+		}
+		for _, m := range results {
+			m.Provider = trackerAniList
+			if !h.blocked(m) {
+				add(suggestion{title: m.Title, url: m.SiteURL})
+			}
+		}
+	}
+
+	query := trigrams(name)
+	var scored []suggestion
+	for _, p := range loadPopularTitles(mediaType) {
+		best := 0.0
+		for _, t := range p.trigrams {
+			if sim := jaccard(query, t); sim > best {
+				best = sim
+			}
+		}
+		if best >= minSuggestionSimilarity && !h.blockedTitle(p.title) {
+			scored = append(scored, suggestion{title: p.title, url: p.url, score: best})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+	for _, sg := range scored {
+		add(sg)
+	}
+	return out
+}
+
+// relaxQuery lowercases a query and replaces punctuation and symbols with spaces
+func relaxQuery(q string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// loadPopularTitles returns the most popular non-adult titles of a media type, fetching them
+// from AniList once a day
+func loadPopularTitles(mediaType string) []popularTitle {
+	popularTitles.Lock()
+	list, fetched := popularTitles.lists[mediaType], popularTitles.fetched[mediaType]
+	popularTitles.Unlock()
+	if time.Since(fetched) < popularTitlesTTL {
+		return list
+	}
+	var fresh []popularTitle
+	for page := 1; page <= popularTitlePages; page++ {
+		var data struct {
+			Page struct {
+				Media []struct {
+					SiteURL string `json:"siteUrl"`
+					Title   struct {
+						Romaji  string `json:"romaji"`
+						English string `json:"english"`
+					} `json:"title"`
+				} `json:"media"`
+			} `json:"Page"`
+		}
+		err := aniListGraphQL(`query ($type: MediaType, $page: Int) {
+			Page(page: $page, perPage: 50) {
+				media(type: $type, isAdult: false, sort: POPULARITY_DESC) {
+					siteUrl
+					title { romaji english }
+				}
+			}
+		}`, map[string]interface{}{"type": mediaType, "page": page}, &data)
+		if err != nil {
+			log.Printf("search: failed to load popular %s titles: %v", strings.ToLower(mediaType), err)
+			break
+		}
+		for _, m := range data.Page.Media {
+			p := popularTitle{title: m.Title.English, url: m.SiteURL}
+			if p.title == "" {
+				p.title = m.Title.Romaji
+			}
+			for _, t := range []string{m.Title.Romaji, m.Title.English} {
+				if t != "" {
+					p.trigrams = append(p.trigrams, trigrams(t))
+				}
+			}
+			fresh = append(fresh, p)
+		}
+	}
+	popularTitles.Lock()
+	defer popularTitles.Unlock()
+	if len(fresh) == 0 {
+		// retry a failed fetch after an hour rather than on every search
+		popularTitles.fetched[mediaType] = time.Now().Add(time.Hour - popularTitlesTTL)
+		return list
+	}
+	popularTitles.lists[mediaType] = fresh
+	popularTitles.fetched[mediaType] = time.Now()
+	return fresh
+}
+
+// sendSuggestions answers a search without results with the titles the user may have meant
+func (h *handler) sendSuggestions(s *discordgo.Session, m *discordgo.Message, name string, suggestions []suggestion) error {
+	links := make([]string, len(suggestions))
+	for n, sg := range suggestions {
+		links[n] = fmt.Sprintf("[**%s**](%s)", sg.title, sg.url)
+	}
+	tr := h.channelLocalizer(s, m.ChannelID)
+	text := links[0]
+	if len(links) > 1 {
+		text = strings.Join(links[:len(links)-1], ", ") + tr.T("search.or") + links[len(links)-1]
+	}
+	_, err := s.ChannelMessageSendComplex(m.ChannelID, asReply(&discordgo.MessageSend{
+		Content: tr.T("search.did_you_mean", name, text),
+		Flags:   discordgo.MessageFlagsSuppressEmbeds,
+	}, m))
+	return err
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// templateWelcome is the templates key of the message posted in new threads of forums without
+// their own welcome_message
+const templateWelcome = "welcome"
+
+// replyData is the data available to reply templates. Fields that do not apply to a reply are
+// empty.
+type replyData struct {
+	// User mentions the member who ran the command, or the post author in welcome messages
+	User   string
+	Thread string
+	// Tag is the forum tag of the status being applied
+	Tag   string
+	Forum string
+	// Code is the HTTP status of a failed Discord request
+	Code int
+
+	// the forum whose name fills in an empty Forum when a template is rendered, so replies without
+	// a template do not look it up
+	session *discordgo.Session
+	forumID string
+}
+
+// templateKeys returns the keys a templates section can set: the welcome message, the replies of
+// the thread status commands and the permission errors
+func templateKeys() []string {
+	keys := []string{templateWelcome}
+	for key := range defaultMessages {
+		if strings.HasPrefix(key, "command.") || strings.HasPrefix(key, "permission.") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compileTemplates parses the templates of a templates section; field names it in errors
+func compileTemplates(field string, texts map[string]string) (map[string]*template.Template, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	known := map[string]bool{}
+	for _, key := range templateKeys() {
+		known[key] = true
+	}
+	templates := make(map[string]*template.Template, len(texts))
+	for key, text := range texts {
+		if !known[key] {
+			return nil, fmt.Errorf("%s: unknown template %q (use one of %s)", field, key, strings.Join(templateKeys(), ", "))
+		}
+		tmpl, err := template.New(key).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", field, key, err)
+		}
+		templates[key] = tmpl
+	}
+	return templates, nil
+}
+
+// threadReplyData returns the template data of a reply in a thread, addressed to userID. The
+// forum's name is looked up when a template is rendered.
+func threadReplyData(s *discordgo.Session, th *discordgo.Channel, userID string) replyData {
+	return replyData{User: "<@" + userID + ">", Thread: th.Name, session: s, forumID: th.ParentID}
+}
+
+// interactionReply formats the reply key to an interaction with the language and templates of
+// its channel
+func (h *handler) interactionReply(s *discordgo.Session, i *discordgo.InteractionCreate, key string) string {
+	var data replyData
+	if u := interactionUser(i); u != nil {
+		data.User = u.Mention()
+	}
+	return h.channelLocalizer(s, i.ChannelID).R(key, data)
+}
+
+// render executes tmpl with data, logging failures
+func render(tmpl *template.Template, data replyData) (string, bool) {
+	if data.Forum == "" && data.forumID != "" && data.session != nil {
+		forum, err := data.session.State.Channel(data.forumID)
+		if err != nil {
+			forum, err = data.session.Channel(data.forumID)
+		}
+		if err == nil {
+			data.Forum = forum.Name
+		}
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		log.Printf("templates: failed to render %s: %v", tmpl.Name(), err)
+		return "", false
+	}
+	return sb.String(), true
+}
+
+// R formats the reply key with the server's template for it when its templates section sets
+// one, whatever the channel's language, and otherwise like T with args
+func (l localizer) R(key string, data replyData, args ...interface{}) string {
+	if tmpl, ok := l.templates[key]; ok {
+		if text, ok := render(tmpl, data); ok {
+			return text
+		}
+	}
+	return l.T(key, args...)
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// indexArchivedPerForum bounds how many archived threads of each forum are indexed
+	indexArchivedPerForum = 1000
+	// indexBodyFetchesPerSync bounds the starter messages fetched by one run of the sync job
+	indexBodyFetchesPerSync = 200
+)
+
+// indexedThread is one forum post in the local thread index
+type indexedThread struct {
+	ID       string   `json:"id"`
+	GuildID  string   `json:"guild_id"`
+	ForumID  string   `json:"forum_id"`
+	Title    string   `json:"title"`
+	Body     string   `json:"body,omitempty"`
+	HasBody  bool     `json:"has_body,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Archived bool     `json:"archived,omitempty"`
+
+	titleTokens []string
+	bodyTokens  []string
+}
+
+// threadIndex is an in-memory inverted index of the titles and first messages of forum posts. It
+// is kept current from gateway events, topped up by the `thread-index-sync` job and saved to its
+// own snapshot file so `.find` and duplicate detection do not have to list threads through the API.
+type threadIndex struct {
+	mu       sync.RWMutex
+	path     string
+	threads  map[string]*indexedThread
+	postings map[string]map[string]bool
+	// synced records when each forum was last listed in full
+	synced map[string]time.Time
+	dirty  bool
+}
+
+// threadIndexSnapshot is the on-disk form of the index; postings are rebuilt on load
+type threadIndexSnapshot struct {
+	Threads map[string]*indexedThread `json:"threads"`
+	Synced  map[string]time.Time      `json:"synced"`
+}
+
+// openThreadIndex loads the snapshot at path, starting empty when it does not exist
+func openThreadIndex(path string) (*threadIndex, error) {
+	x := &threadIndex{path: path, threads: map[string]*indexedThread{}, postings: map[string]map[string]bool{}, synced: map[string]time.Time{}}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return x, nil
+		}
+		return nil, err
+	}
+	var snap threadIndexSnapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return nil, err
+	}
+	for id, t := range snap.Threads {
+		t.ID = id
+		x.addLocked(t)
+	}
+	if snap.Synced != nil {
+		x.synced = snap.Synced
+	}
+	return x, nil
+}
+
+// save writes the snapshot if anything changed since the last save
+func (x *threadIndex) save() error {
+	x.mu.Lock()
+	if !x.dirty {
+		x.mu.Unlock()
+		return nil
+	}
+	b, err := json.Marshal(threadIndexSnapshot{Threads: x.threads, Synced: x.synced})
+	x.dirty = false
+	x.mu.Unlock()
+	if err != nil || dryRun {
+		return err
+	}
+	if dir := filepath.Dir(x.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp := x.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, x.path)
+}
+
+func (x *threadIndex) addLocked(t *indexedThread) {
+	t.titleTokens = tokenize(stripStatusPrefixes(t.Title))
+	t.bodyTokens = tokenize(t.Body)
+	x.threads[t.ID] = t
+	for _, tok := range append(append([]string{}, t.titleTokens...), t.bodyTokens...) {
+		if x.postings[tok] == nil {
+			x.postings[tok] = map[string]bool{}
+		}
+		x.postings[tok][t.ID] = true
+	}
+}
+
+func (x *threadIndex) removeLocked(id string) *indexedThread {
+	t, ok := x.threads[id]
+	if !ok {
+		return nil
+	}
+	for _, tok := range append(append([]string{}, t.titleTokens...), t.bodyTokens...) {
+		delete(x.postings[tok], id)
+		if len(x.postings[tok]) == 0 {
+			delete(x.postings, tok)
+		}
+	}
+	delete(x.threads, id)
+	return t
+}
+
+// upsert records the title, tags and archive state of a thread, keeping any indexed body
+func (x *threadIndex) upsert(th *discordgo.Channel) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	t := &indexedThread{ID: th.ID, GuildID: th.GuildID, ForumID: th.ParentID, Title: th.Name, Tags: th.AppliedTags}
+	if th.ThreadMetadata != nil {
+		t.Archived = th.ThreadMetadata.Archived
+	}
+	if old := x.removeLocked(th.ID); old != nil {
+		t.Body, t.HasBody = old.Body, old.HasBody
+	}
+	x.addLocked(t)
+	x.dirty = true
+}
+
+// setBody records the first message of an indexed thread
+func (x *threadIndex) setBody(id, body string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	old := x.removeLocked(id)
+	if old == nil {
+		return
+	}
+	t := *old
+	t.Body, t.HasBody = body, true
+	x.addLocked(&t)
+	x.dirty = true
+}
+
+// remove drops a thread from the index
+func (x *threadIndex) remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.removeLocked(id) != nil {
+		x.dirty = true
+	}
+}
+
+// body returns the indexed first message of a thread
+func (x *threadIndex) body(id string) (string, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	t, ok := x.threads[id]
+	if !ok || !t.HasBody {
+		return "", false
+	}
+	return t.Body, true
+}
+
+// size returns the number of indexed threads
+func (x *threadIndex) size() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.threads)
+}
+
+func (x *threadIndex) isSynced(forumID string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	_, ok := x.synced[forumID]
+	return ok
+}
+
+func (x *threadIndex) markSynced(forumID string) {
+	x.mu.Lock()
+	x.synced[forumID] = time.Now()
+	x.dirty = true
+	x.mu.Unlock()
+}
+
+// lookup returns copies of the threads in the given forums containing any of the tokens in their
+// title or first message. With prefix set, indexed words starting with a token also match.
+func (x *threadIndex) lookup(forumIDs []string, tokens []string, prefix bool) []indexedThread {
+	forums := map[string]bool{}
+	for _, id := range forumIDs {
+		forums[id] = true
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	ids := map[string]bool{}
+	for _, q := range tokens {
+		for id := range x.postings[q] {
+			ids[id] = true
+		}
+		if !prefix {
+			continue
+		}
+		for tok, docs := range x.postings {
+			if tok != q && strings.HasPrefix(tok, q) {
+				for id := range docs {
+					ids[id] = true
+				}
+			}
+		}
+	}
+	out := make([]indexedThread, 0, len(ids))
+	for id := range ids {
+		if t := x.threads[id]; forums[t.ForumID] {
+			out = append(out, *t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out
+}
+
+// missingBodies returns up to limit indexed threads of a forum whose first message is not known yet
+func (x *threadIndex) missingBodies(forumID string, limit int) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var out []string
+	for id, t := range x.threads {
+		if t.ForumID == forumID && !t.HasBody {
+			out = append(out, id)
+		}
+	}
+	// newest first: recent posts are the most likely to be searched for
+	sort.Sort(sort.Reverse(sort.StringSlice(out)))
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// pruneArchived drops the oldest archived threads of a forum beyond indexArchivedPerForum, so
+// posts archived while the bot runs do not grow the index and its first messages without bound
+func (x *threadIndex) pruneArchived(forumID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var archived []string
+	for id, t := range x.threads {
+		if t.ForumID == forumID && t.Archived {
+			archived = append(archived, id)
+		}
+	}
+	if len(archived) <= indexArchivedPerForum {
+		return
+	}
+	// newest first; a snowflake with more digits is newer
+	sort.Slice(archived, func(i, j int) bool {
+		if len(archived[i]) != len(archived[j]) {
+			return len(archived[i]) > len(archived[j])
+		}
+		return archived[i] > archived[j]
+	})
+	for _, id := range archived[indexArchivedPerForum:] {
+		x.removeLocked(id)
+	}
+	x.dirty = true
+}
+
+// indexForum lists a forum's active and archived threads into the index
+func (h *handler) indexForum(s *discordgo.Session, guildID, forumID string) error {
+	threads, err := listForumThreads(s, guildID, forumID, indexArchivedPerForum)
+	for _, th := range threads {
+		h.index.upsert(th)
+	}
+	h.index.pruneArchived(forumID)
+	if err != nil {
+		return err
+	}
+	h.index.markSynced(forumID)
+	return nil
+}
+
+// ensureForumIndexed lists a forum into the index the first time it is searched
+func (h *handler) ensureForumIndexed(s *discordgo.Session, guildID, forumID string) {
+	if h.index.isSynced(forumID) {
+		return
+	}
+	if err := h.indexForum(s, guildID, forumID); err != nil {
+		log.Printf("thread index: failed to list forum %s: %v", forumID, err)
+	}
+}
+
+// syncThreadIndex is the `thread-index-sync` job: it relists the watched forums of every guild,
+// fetches missing first messages and saves the snapshot
+func (h *handler) syncThreadIndex(ctx context.Context, job jobRecord) error {
+	s := h.dg
+	fetched := 0
+	for _, g := range s.State.Guilds {
+		for _, forumID := range h.watchedForumIDs(s, g.ID) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := h.indexForum(s, g.ID, forumID); err != nil {
+				log.Printf("thread index: failed to list forum %s: %v", forumID, err)
+			}
+			for _, id := range h.index.missingBodies(forumID, indexBodyFetchesPerSync-fetched) {
+				msg, err := s.ChannelMessage(id, id)
+				fetched++
+				if err != nil {
+					// the starter message was deleted; remember that so it is not refetched
+					h.index.setBody(id, "")
+					continue
+				}
+				h.index.setBody(id, msg.Content)
+			}
+		}
+	}
+	return h.index.save()
+}
+
+// saveThreadIndex is the `thread-index-save` job that persists changes from gateway events
+func (h *handler) saveThreadIndex(ctx context.Context, job jobRecord) error {
+	return h.index.save()
+}
+
+// onThreadIndexUpdate keeps titles, tags and archive state of indexed threads current
+func (h *handler) onThreadIndexUpdate(s *discordgo.Session, t *discordgo.ThreadUpdate) {
+	if t.Channel == nil || t.ParentID == "" || !h.inWatchedForum(t.Channel) {
+		return
+	}
+	h.index.upsert(t.Channel)
+}
+
+// onThreadIndexDelete drops deleted threads from the index
+func (h *handler) onThreadIndexDelete(s *discordgo.Session, t *discordgo.ThreadDelete) {
+	if t.Channel != nil {
+		h.index.remove(t.ID)
+	}
+}
+
+// onStarterMessageUpdate reindexes a post when its first message is edited. In forums the first
+// message has the same ID as the thread.
+func (h *handler) onStarterMessageUpdate(s *discordgo.Session, m *discordgo.MessageUpdate) {
+	if m.Message == nil || m.ID != m.ChannelID || m.EditedTimestamp == nil {
+		return
+	}
+	h.index.setBody(m.ID, m.Content)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"log"
+	"text/template"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// onThreadCreate runs the new-post automation for threads created in watched forums
+func (h *handler) onThreadCreate(s *discordgo.Session, t *discordgo.ThreadCreate) {
+	// ThreadCreate is also sent when the bot is added to an existing thread
+	if !t.NewlyCreated || t.Channel == nil {
+		return
+	}
+	th := t.Channel
+	if th.ParentID == "" || !h.inWatchedForum(th) {
+		return
+	}
+	h.index.upsert(th)
+	fc := h.cfg().Forums[th.ParentID]
+
+	h.postWelcome(s, th, fc)
+	if fc.TriagePanel {
+		h.postTriagePanel(s, th)
+	}
+
+	starter, err := fetchStarterMessage(s, th.ID)
+	if err != nil {
+		log.Printf("new post: failed to fetch starter message of %s: %v", th.ID, err)
+		return
+	}
+	h.cacheStarter(th.ID, starter.Content)
+	if len(fc.AutoTags) > 0 {
+		h.applyAutoTags(s, th, starter, fc)
+	}
+	if fc.VersionTags {
+		h.applyVersionTag(s, th, starter, fc)
+	}
+	if fc.OutdatedVersionNotice {
+		h.noteOutdatedVersion(s, th, starter)
+	}
+	if len(fc.AndroidVersionTags) > 0 || len(fc.DeviceRules) > 0 || fc.AnnotateDevice {
+		h.applyDeviceInfo(s, th, starter, fc)
+	}
+	if len(fc.RequiredFields) > 0 {
+		h.enforceReportTemplate(s, th, starter, fc)
+	}
+	if fc.DuplicateDetection {
+		h.suggestDuplicates(s, th, starter, fc)
+	}
+	if len(h.cfg().StatusMonitor.Services) > 0 {
+		h.appendOutageBanner(s, th, starter)
+	}
+	if h.cfg().Parsers.Enabled && h.cfg().Parsers.ReplyInThreads {
+		h.noteBrokenSources(s, th, starter)
+	}
+}
+
+// postWelcome renders the forum's welcome_message, or else the server's welcome template, and
+// posts it in the new thread
+func (h *handler) postWelcome(s *discordgo.Session, th *discordgo.Channel, fc ForumConfig) {
+	tmpl := h.cfg().forGuild(th.GuildID).templates[templateWelcome]
+	if fc.WelcomeMessage != "" {
+		var err error
+		if tmpl, err = template.New(templateWelcome).Parse(fc.WelcomeMessage); err != nil {
+			log.Printf("welcome: invalid template for forum %s: %v", th.ParentID, err)
+			return
+		}
+	}
+	if tmpl == nil {
+		return
+	}
+	if text, ok := render(tmpl, threadReplyData(s, th, th.OwnerID)); ok {
+		h.sendFeatureMessage(s, featureWelcome, th.ID, text)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	// zone data for timezone on hosts and images without /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+// defaultDateFormat is the layout of dates shown in transcripts without a date_format
+const defaultDateFormat = "2006-01-02 15:04 MST"
+
+// compileTimezone loads the timezone setting
+func (cfg *Config) compileTimezone() error {
+	if cfg.DateFormat == "" {
+		cfg.DateFormat = defaultDateFormat
+	}
+	if cfg.Timezone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return fmt.Errorf("timezone: unknown time zone %q (use a name such as \"Europe/Berlin\" or \"UTC\")", cfg.Timezone)
+	}
+	cfg.location = loc
+	return nil
+}
+
+// scheduleLocation returns the zone cron schedules are evaluated in: timezone, else the zone of
+// the server the bot runs on
+func (cfg *Config) scheduleLocation() *time.Location {
+	if cfg == nil || cfg.location == nil {
+		return time.Local
+	}
+	return cfg.location
+}
+
+// dateLocation returns the zone of the dates the bot writes and of day boundaries (daily lookup
+// quotas, search statistics): timezone, else UTC
+func (cfg *Config) dateLocation() *time.Location {
+	if cfg == nil || cfg.location == nil {
+		return time.UTC
+	}
+	return cfg.location
+}
+
+// formatDate formats t with date_format in the configured zone
+func (cfg *Config) formatDate(t time.Time) string {
+	return t.In(cfg.dateLocation()).Format(cfg.DateFormat)
+}
+
+// day returns the date of t in the configured zone, as used to key daily counters
+func (cfg *Config) day(t time.Time) string {
+	return t.In(cfg.dateLocation()).Format("2006-01-02")
+}
+
+// nextMidnight returns the start of the day after t in the configured zone
+func (cfg *Config) nextMidnight(t time.Time) time.Time {
+	y, mo, d := t.In(cfg.dateLocation()).Date()
+	return time.Date(y, mo, d+1, 0, 0, 0, 0, cfg.dateLocation())
+}
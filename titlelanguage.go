@@ -0,0 +1,123 @@
+package main
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Languages a title can be displayed in (title_language, /titlelanguage)
+const (
+	titleEnglish = "english"
+	titleRomaji  = "romaji"
+	titleNative  = "native"
+)
+
+// mediaTitles is the title of a media in each language the provider knows it in
+type mediaTitles struct {
+	Romaji  string
+	English string
+	Native  string
+}
+
+func init() {
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "titlelanguage",
+		Description: "Choose whether lookups show English, romaji or native titles",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "language", Description: "Title language (\"Server default\" forgets your choice)", Required: true, Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "English", Value: titleEnglish},
+				{Name: "Romaji", Value: titleRomaji},
+				{Name: "Native", Value: titleNative},
+				{Name: "Server default", Value: "default"},
+			}},
+		},
+	}, (*handler).handleTitleLanguageCommand)
+	requireStore("titlelanguage")
+	allowInDMs("titlelanguage")
+}
+
+// titleIn returns the title in lang, or the provider's default title when it does not know that
+// one (for AniList English, then romaji, then native)
+func (m *aniListMedia) titleIn(lang string) string {
+	var title string
+	switch lang {
+	case titleRomaji:
+		title = m.Titles.Romaji
+	case titleNative:
+		title = m.Titles.Native
+	case titleEnglish:
+		title = m.Titles.English
+	}
+	if title == "" {
+		return m.Title
+	}
+	return title
+}
+
+// titleLanguage returns the title language chosen by the user with /titlelanguage, else
+// title_language
+func (h *handler) titleLanguage(user *discordgo.User) string {
+	if user != nil {
+		if p, _ := h.loadUserPrefs(user.ID); p.TitleLanguage != "" {
+			return p.TitleLanguage
+		}
+	}
+	return h.cfg().TitleLanguage
+}
+
+// withTitleLanguage returns media titled in the user's title language. Titles that change are
+// copied, since results may be shared through the search cache.
+func (h *handler) withTitleLanguage(user *discordgo.User, media ...*aniListMedia) []*aniListMedia {
+	lang := h.titleLanguage(user)
+	out := make([]*aniListMedia, len(media))
+	for n, m := range media {
+		out[n] = m
+		if m == nil {
+			continue
+		}
+		title := m.titleIn(lang)
+		if title == m.Title {
+			continue
+		}
+		c := *m
+		c.Title = title
+		c.AltTitles = []string{m.Title}
+		for _, alt := range m.AltTitles {
+			if alt != title {
+				c.AltTitles = append(c.AltTitles, alt)
+			}
+		}
+		out[n] = &c
+	}
+	return out
+}
+
+// handleTitleLanguageCommand implements /titlelanguage language:<language>
+func (h *handler) handleTitleLanguageCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	user := interactionUser(i)
+	if user == nil {
+		return
+	}
+	lang := slashOptions(i)["language"].StringValue()
+	if lang == "default" {
+		lang = ""
+	}
+	tr := h.channelLocalizer(s, i.ChannelID)
+	p, err := h.loadUserPrefs(user.ID)
+	if err != nil {
+		respondEphemeral(s, i, tr.T("prefs.save_failed"))
+		return
+	}
+	p.TitleLanguage = lang
+	if err := h.saveUserPrefs(user.ID, p); err != nil {
+		log.Printf("failed to save title language of %s: %v", user.ID, err)
+		respondEphemeral(s, i, tr.T("prefs.save_failed"))
+		return
+	}
+	if lang == "" {
+		respondEphemeral(s, i, tr.T("prefs.title_language_reset", h.cfg().TitleLanguage))
+		return
+	}
+	respondEphemeral(s, i, tr.T("prefs.title_language_saved", lang))
+}
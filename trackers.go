@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Tracker names accepted by the provider options and per-user preference
+const (
+	trackerAniList   = "anilist"
+	trackerShikimori = "shikimori"
+	trackerKitsu     = "kitsu"
+	trackerMAL       = "mal"
+	trackerMangaDex  = "mangadex"
+)
+
+// botUserAgent identifies the bot to third-party APIs (Shikimori rejects requests without one)
+const botUserAgent = "go-kotatsu-bot (+https://github.com/galpt/go-kotatsu-bot)"
+
+// searchProvidersFor returns the provider order for a channel: its search_channel_providers entry
+// (or that of its parent, for threads), else search_providers of the channel's server
+func (h *handler) searchProvidersFor(ch *discordgo.Channel) []string {
+	if ch != nil {
+		if p, ok := h.cfg().SearchChannelProviders[ch.ID]; ok {
+			return p
+		}
+		if p, ok := h.cfg().SearchChannelProviders[ch.ParentID]; ok {
+			return p
+		}
+		return h.cfg().forGuild(ch.GuildID).SearchProviders
+	}
+	return h.cfg().SearchProviders
+}
+
+// searchMedia looks a title up on each provider of search_providers in order and returns the
+// first hit, so searches still resolve when a provider is down or does not know the title.
+// The result records which provider served it.
+// The error of the last failing provider is returned only when no provider had a result.
+func (h *handler) searchMedia(ch *discordgo.Channel, name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	results, err := h.searchMediaCandidates(ch, name, mediaType, allowAdult, 1)
+	if len(results) == 0 {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// searchMediaCandidates is searchMedia returning up to limit matches from the first provider
+// that has any; providers that only return their best match yield a single candidate
+func (h *handler) searchMediaCandidates(ch *discordgo.Channel, name, mediaType string, allowAdult bool, limit int) ([]*aniListMedia, error) {
+	return h.searchWith(h.healthyProvidersFor(ch), name, mediaType, allowAdult, limit)
+}
+
+// healthyProvidersFor is searchProvidersFor without the providers that keep failing, unless
+// that leaves none to ask
+func (h *handler) healthyProvidersFor(ch *discordgo.Channel) []string {
+	providers := h.searchProvidersFor(ch)
+	var healthy []string
+	for _, tracker := range providers {
+		if providerHealthy(tracker) {
+			healthy = append(healthy, tracker)
+		}
+	}
+	if len(healthy) > 0 {
+		return healthy
+	}
+	return providers
+}
+
+// searchWith asks the providers in order and returns up to limit matches of the first one that has any
+func (h *handler) searchWith(providers []string, name, mediaType string, allowAdult bool, limit int) ([]*aniListMedia, error) {
+	// ask for extra matches to fall back on when the best ones are blocked
+	fetch := limit
+	if len(h.cfg().SearchBlocklist) > 0 && fetch < searchCandidates {
+		fetch = searchCandidates
+	}
+	var lastErr error
+	for _, tracker := range providers {
+		var results []*aniListMedia
+		var err error
+		if cs, ok := mediaProviders[tracker].(candidateSearcher); ok && fetch > 1 {
+			results, err = cs.SearchCandidates(name, mediaType, allowAdult, fetch)
+		} else {
+			var media *aniListMedia
+			if media, err = mediaProviders[tracker].Search(name, mediaType, allowAdult); media != nil {
+				results = []*aniListMedia{media}
+			}
+		}
+		recordProviderResult(tracker, err, len(results) > 0)
+		if err != nil {
+			log.Printf("search: %s error for %q: %v", tracker, name, err)
+			lastErr = err
+			continue
+		}
+		for _, m := range results {
+			m.Provider = tracker
+		}
+		if results = h.withoutBlocked(results); len(results) > limit {
+			results = results[:limit]
+		}
+		if len(results) > 0 {
+			return results, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// searchMediaBatch looks several titles up at once for list-style messages and returns the
+// matches in the order of names, leaving out titles no provider knows. When AniList comes first
+// in the channel's provider order, all titles are sent to it in a single request and only the
+// titles it does not know go down the rest of the chain. id:<number> names are fetched by ID.
+func (h *handler) searchMediaBatch(ch *discordgo.Channel, names []string, mediaType string, allowAdult bool) []*aniListMedia {
+	started := time.Now()
+	providers := h.healthyProvidersFor(ch)
+	found := make([]*aniListMedia, len(names))
+	// positions of the names searched by title
+	var searched []int
+	for i, name := range names {
+		if id, ok := parseIDQuery(name); ok {
+			found[i], _, _ = h.lookupByID(id, mediaType, allowAdult)
+		} else {
+			searched = append(searched, i)
+		}
+	}
+	if len(searched) > 1 && providers[0] == trackerAniList {
+		titles := make([]string, len(searched))
+		for n, i := range searched {
+			titles[n] = names[i]
+		}
+		batch, err := searchAniListBatch(titles, mediaType, allowAdult)
+		recordProviderResult(trackerAniList, err, len(batch) > 0)
+		if err != nil {
+			log.Printf("search: AniList batch error for %q: %v", titles, err)
+		}
+		for n, m := range batch {
+			if m != nil {
+				m.Provider = trackerAniList
+				if !h.blocked(m) {
+					found[searched[n]] = m
+				}
+			}
+		}
+		providers = providers[1:]
+	}
+	var out []*aniListMedia
+	for i, name := range names {
+		if _, byID := parseIDQuery(name); found[i] == nil && !byID && len(providers) > 0 {
+			if results, err := h.searchWith(providers, name, mediaType, allowAdult, 1); err == nil && len(results) > 0 {
+				found[i] = results[0]
+			}
+		}
+		if found[i] != nil {
+			out = append(out, found[i])
+		}
+	}
+	// the titles share the time of the batch
+	latency := time.Since(started) / time.Duration(len(names))
+	for i, name := range names {
+		if found[i] != nil {
+			h.recordSearch(ch.GuildID, found[i].Title, ch.ID, found[i].Provider, true, latency)
+		} else {
+			h.recordSearch(ch.GuildID, name, ch.ID, "", false, latency)
+		}
+	}
+	return out
+}
+
+// getJSON performs a GET request and decodes a 200 JSON response into out
+func getJSON(rawURL string, headers map[string]string, out interface{}) error {
+	return requestJSON("GET", rawURL, headers, nil, out)
+}
+
+// postJSON sends payload as a JSON POST body and decodes a 200 JSON response into out
+func postJSON(rawURL string, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return requestJSON("POST", rawURL, map[string]string{"Content-Type": "application/json"}, bytes.NewReader(body), out)
+}
+
+func requestJSON(method, rawURL string, headers map[string]string, body io.Reader, out interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", botUserAgent)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// searchShikimori queries the Shikimori API. Titles prefer the Russian name, which is what
+// Shikimori users expect to see.
+func searchShikimori(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	kind := "mangas"
+	if mediaType == "ANIME" {
+		kind = "animes"
+	}
+	q := url.Values{"search": {name}, "limit": {"1"}}
+	if !allowAdult {
+		q.Set("censored", "true")
+	}
+	var results []struct {
+		ID int `json:"id"`
+	}
+	if err := getJSON("https://shikimori.one/api/"+kind+"?"+q.Encode(), nil, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return fetchShikimoriByID(results[0].ID, mediaType, allowAdult)
+}
+
+// fetchShikimoriByID loads one Shikimori entry with its description and genres. Adult entries
+// are only returned with allowAdult; Shikimori itself only hides them from searches.
+func fetchShikimoriByID(id int, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	kind := "mangas"
+	if mediaType == "ANIME" {
+		kind = "animes"
+	}
+	var d struct {
+		ID       int      `json:"id"`
+		Name     string   `json:"name"`
+		Russian  string   `json:"russian"`
+		Japanese []string `json:"japanese"`
+		URL      string   `json:"url"`
+		Kind     string   `json:"kind"`
+		Image    struct {
+			Original string `json:"original"`
+		} `json:"image"`
+		Description string `json:"description"`
+		AiredOn     string `json:"aired_on"`
+		Status      string `json:"status"`
+		Rating      string `json:"rating"`
+		Score       string `json:"score"`
+		Episodes    int    `json:"episodes"`
+		Chapters    int    `json:"chapters"`
+		Volumes     int    `json:"volumes"`
+		Genres      []struct {
+			Name    string `json:"name"`
+			Russian string `json:"russian"`
+		} `json:"genres"`
+	}
+	if err := getJSON(fmt.Sprintf("https://shikimori.one/api/%s/%d", kind, id), nil, &d); err != nil {
+		return nil, err
+	}
+	title := d.Russian
+	if title == "" {
+		title = d.Name
+	}
+	// the Russian title stays the default; Shikimori's English titles are not offered
+	titles := mediaTitles{Romaji: d.Name}
+	if len(d.Japanese) > 0 {
+		titles.Native = d.Japanese[0]
+	}
+	adult := d.Rating == "rx"
+	var genres []string
+	for _, g := range d.Genres {
+		// manga have no rating, only the genres tell
+		if g.Name == "Hentai" || g.Name == "Erotica" {
+			adult = true
+		}
+		if g.Russian != "" {
+			genres = append(genres, g.Russian)
+		} else {
+			genres = append(genres, g.Name)
+		}
+	}
+	m := &aniListMedia{
+		ID:        d.ID,
+		SiteURL:   "https://shikimori.one" + d.URL,
+		Title:     title,
+		Titles:    titles,
+		Desc:      stripShikimoriMarkup(d.Description),
+		Genres:    genres,
+		Format:    strings.ToUpper(d.Kind),
+		Status:    strings.ToUpper(d.Status),
+		StartDate: d.AiredOn,
+		Episodes:  d.Episodes,
+		Chapters:  d.Chapters,
+		Volumes:   d.Volumes,
+		Adult:     adult,
+	}
+	if m.Adult && !allowAdult {
+		return nil, nil
+	}
+	if score, err := strconv.ParseFloat(d.Score, 64); err == nil {
+		m.Score = int(score * 10)
+	}
+	if d.Image.Original != "" {
+		m.CoverURL = "https://shikimori.one" + d.Image.Original
+	}
+	return m, nil
+}
+
+// stripShikimoriMarkup removes Shikimori's BBCode-like tags ([character=1]Name[/character])
+func stripShikimoriMarkup(s string) string {
+	var sb strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '[':
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			sb.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// searchKitsu queries the Kitsu JSON:API
+func searchKitsu(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	return queryKitsu(mediaType, url.Values{"filter[text]": {name}, "page[limit]": {"5"}}, allowAdult)
+}
+
+// fetchKitsuEntry loads the Kitsu entry linked as kitsu.app/<type>/<slug or numeric ID>
+func fetchKitsuEntry(id, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	if _, err := strconv.Atoi(id); err == nil {
+		return queryKitsu(mediaType, url.Values{"filter[id]": {id}}, allowAdult)
+	}
+	return queryKitsu(mediaType, url.Values{"filter[slug]": {id}}, allowAdult)
+}
+
+// queryKitsu returns the first suitable entry matching the filters in q
+func queryKitsu(mediaType string, q url.Values, allowAdult bool) (*aniListMedia, error) {
+	kind := "manga"
+	if mediaType == "ANIME" {
+		kind = "anime"
+	}
+	q.Set("include", "categories")
+	var res struct {
+		Data []struct {
+			ID         string `json:"id"`
+			Attributes struct {
+				Slug           string            `json:"slug"`
+				CanonicalTitle string            `json:"canonicalTitle"`
+				Titles         map[string]string `json:"titles"`
+				Synopsis       string            `json:"synopsis"`
+				Subtype        string            `json:"subtype"`
+				Status         string            `json:"status"`
+				AverageRating  string            `json:"averageRating"`
+				UserCount      int               `json:"userCount"`
+				EpisodeCount   int               `json:"episodeCount"`
+				ChapterCount   int               `json:"chapterCount"`
+				VolumeCount    int               `json:"volumeCount"`
+				StartDate      string            `json:"startDate"`
+				NSFW           bool              `json:"nsfw"`
+				AgeRating      string            `json:"ageRating"`
+				PosterImage    *struct {
+					Large string `json:"large"`
+				} `json:"posterImage"`
+			} `json:"attributes"`
+			Relationships struct {
+				Categories struct {
+					Data []struct {
+						ID string `json:"id"`
+					} `json:"data"`
+				} `json:"categories"`
+			} `json:"relationships"`
+		} `json:"data"`
+		Included []struct {
+			ID         string `json:"id"`
+			Type       string `json:"type"`
+			Attributes struct {
+				Title string `json:"title"`
+			} `json:"attributes"`
+		} `json:"included"`
+	}
+	if err := getJSON("https://kitsu.app/api/edge/"+kind+"?"+q.Encode(), map[string]string{"Accept": "application/vnd.api+json"}, &res); err != nil {
+		return nil, err
+	}
+	categories := map[string]string{}
+	for _, inc := range res.Included {
+		if inc.Type == "categories" {
+			categories[inc.ID] = inc.Attributes.Title
+		}
+	}
+	for _, d := range res.Data {
+		a := d.Attributes
+		if !allowAdult && (a.NSFW || a.AgeRating == "R18") {
+			continue
+		}
+		var genres []string
+		for _, c := range d.Relationships.Categories.Data {
+			if t, ok := categories[c.ID]; ok {
+				genres = append(genres, t)
+			}
+		}
+		m := &aniListMedia{
+			SiteURL:    fmt.Sprintf("https://kitsu.app/%s/%s", kind, a.Slug),
+			Title:      a.CanonicalTitle,
+			Titles:     mediaTitles{Romaji: a.Titles["en_jp"], English: a.Titles["en"], Native: a.Titles["ja_jp"]},
+			Desc:       a.Synopsis,
+			Genres:     genres,
+			Format:     strings.ToUpper(a.Subtype),
+			Status:     strings.ToUpper(a.Status),
+			StartDate:  a.StartDate,
+			Popularity: a.UserCount,
+			Episodes:   a.EpisodeCount,
+			Chapters:   a.ChapterCount,
+			Volumes:    a.VolumeCount,
+			Adult:      a.NSFW || a.AgeRating == "R18",
+		}
+		if rating, err := strconv.ParseFloat(a.AverageRating, 64); err == nil {
+			m.Score = int(rating + 0.5)
+		}
+		fmt.Sscanf(d.ID, "%d", &m.ID)
+		if a.PosterImage != nil {
+			m.CoverURL = a.PosterImage.Large
+		}
+		return m, nil
+	}
+	return nil, nil
+}
+
+// jikanMedia is the subset of a Jikan (MyAnimeList) anime or manga object used for embeds
+type jikanMedia struct {
+	MalID        int     `json:"mal_id"`
+	URL          string  `json:"url"`
+	Title        string  `json:"title"`
+	TitleEnglish string  `json:"title_english"`
+	TitleNative  string  `json:"title_japanese"`
+	Synopsis     string  `json:"synopsis"`
+	Type         string  `json:"type"`
+	Status       string  `json:"status"`
+	Rating       string  `json:"rating"`
+	Score        float64 `json:"score"`
+	Members      int     `json:"members"`
+	Episodes     int     `json:"episodes"`
+	Chapters     int     `json:"chapters"`
+	Volumes      int     `json:"volumes"`
+	Season       string  `json:"season"`
+	Year         int     `json:"year"`
+	Images       struct {
+		JPG struct {
+			LargeImageURL string `json:"large_image_url"`
+		} `json:"jpg"`
+	} `json:"images"`
+	Genres []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	ExplicitGenres []struct {
+		Name string `json:"name"`
+	} `json:"explicit_genres"`
+	Published struct {
+		From string `json:"from"`
+	} `json:"published"`
+	Aired struct {
+		From string `json:"from"`
+	} `json:"aired"`
+}
+
+// adult reports whether MyAnimeList classifies the title as adult content
+func (j jikanMedia) adult() bool {
+	return len(j.ExplicitGenres) > 0 || strings.HasPrefix(j.Rating, "Rx")
+}
+
+func (j jikanMedia) toMedia() *aniListMedia {
+	title := j.TitleEnglish
+	if title == "" {
+		title = j.Title
+	}
+	m := &aniListMedia{
+		ID:       j.MalID,
+		SiteURL:  j.URL,
+		Title:    title,
+		Titles:   mediaTitles{Romaji: j.Title, English: j.TitleEnglish, Native: j.TitleNative},
+		Desc:     j.Synopsis,
+		CoverURL: j.Images.JPG.LargeImageURL,
+		Format:   strings.ToUpper(j.Type),
+		Status:   strings.ToUpper(strings.ReplaceAll(j.Status, " ", "_")),
+		Score:    int(j.Score * 10),
+		// MyAnimeList's closest equivalent of AniList popularity
+		Popularity: j.Members,
+		Episodes:   j.Episodes,
+		Chapters:   j.Chapters,
+		Volumes:    j.Volumes,
+		Season:     strings.ToUpper(j.Season),
+		SeasonYear: j.Year,
+		Adult:      j.adult(),
+	}
+	for _, g := range j.Genres {
+		m.Genres = append(m.Genres, g.Name)
+	}
+	start := j.Published.From
+	if start == "" {
+		start = j.Aired.From
+	}
+	if len(start) >= 10 {
+		m.StartDate = start[:10]
+	}
+	return m
+}
+
+// searchJikan queries MyAnimeList through the Jikan API
+func searchJikan(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	kind := "manga"
+	if mediaType == "ANIME" {
+		kind = "anime"
+	}
+	q := url.Values{"q": {name}, "limit": {"1"}}
+	if !allowAdult {
+		q.Set("sfw", "true")
+	}
+	var res struct {
+		Data []jikanMedia `json:"data"`
+	}
+	if err := getJSON("https://api.jikan.moe/v4/"+kind+"?"+q.Encode(), nil, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, nil
+	}
+	return res.Data[0].toMedia(), nil
+}
+
+// fetchJikanByID loads one MyAnimeList entry by ID; adult entries are only returned when allowAdult
+func fetchJikanByID(id int, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	kind := "manga"
+	if mediaType == "ANIME" {
+		kind = "anime"
+	}
+	var res struct {
+		Data jikanMedia `json:"data"`
+	}
+	if err := getJSON(fmt.Sprintf("https://api.jikan.moe/v4/%s/%d", kind, id), nil, &res); err != nil {
+		return nil, err
+	}
+	if res.Data.MalID == 0 || (!allowAdult && res.Data.adult()) {
+		return nil, nil
+	}
+	return res.Data.toMedia(), nil
+}
+
+// Providers backed by the lookups above
+
+type jikanProvider struct{}
+
+func (jikanProvider) Name() string { return trackerMAL }
+
+func (jikanProvider) Search(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	return searchJikan(name, mediaType, allowAdult)
+}
+
+func (jikanProvider) GetByID(id, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MyAnimeList ID %q", id)
+	}
+	return fetchJikanByID(n, mediaType, allowAdult)
+}
+
+type kitsuProvider struct{}
+
+func (kitsuProvider) Name() string { return trackerKitsu }
+
+func (kitsuProvider) Search(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	return searchKitsu(name, mediaType, allowAdult)
+}
+
+func (kitsuProvider) GetByID(id, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	return fetchKitsuEntry(id, mediaType, allowAdult)
+}
+
+type shikimoriProvider struct{}
+
+func (shikimoriProvider) Name() string { return trackerShikimori }
+
+func (shikimoriProvider) Search(name, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	return searchShikimori(name, mediaType, allowAdult)
+}
+
+func (shikimoriProvider) GetByID(id, mediaType string, allowAdult bool) (*aniListMedia, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Shikimori ID %q", id)
+	}
+	return fetchShikimoriByID(n, mediaType, allowAdult)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Transcript formats (config `transcript_format`)
+const (
+	transcriptMarkdown = "markdown"
+	transcriptHTML     = "html"
+)
+
+var transcriptTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>
+body{font-family:sans-serif;max-width:60em;margin:2em auto;color:#222}
+.msg{border-bottom:1px solid #ddd;padding:.6em 0}
+.author{font-weight:bold}.time{color:#888;font-size:.85em;margin-left:.5em}
+.content{white-space:pre-wrap;margin-top:.3em}
+</style></head><body>
+<h1>{{.Title}}</h1>
+<p>Thread {{.ThreadID}} in forum {{.ForumID}}, exported {{.Exported}}</p>
+{{range .Messages}}<div class="msg"><span class="author">{{.Author}}</span><span class="time">{{.Time}}</span>
+<div class="content">{{.Content}}</div>{{range .Attachments}}
+<div>📎 <a href="{{.URL}}">{{.Filename}}</a></div>{{end}}</div>
+{{end}}</body></html>
+`))
+
+type transcriptMessage struct {
+	Author, Time, Content string
+	Attachments           []*discordgo.MessageAttachment
+}
+
+func init() {
+	registerThreadCommand("transcript", (*handler).handleTranscript)
+}
+
+// renderTranscript formats the messages of a thread as Markdown or HTML and returns the file
+// name and content
+func renderTranscript(cfg *Config, ch *discordgo.Channel, msgs []*discordgo.Message) (string, []byte, error) {
+	format := cfg.TranscriptFormat
+	var tms []transcriptMessage
+	for _, msg := range msgs {
+		tm := transcriptMessage{Author: "unknown", Time: cfg.formatDate(msg.Timestamp), Content: msg.Content, Attachments: msg.Attachments}
+		if msg.Author != nil {
+			tm.Author = msg.Author.String()
+		}
+		tms = append(tms, tm)
+	}
+	exported := cfg.formatDate(time.Now())
+	if format == transcriptHTML {
+		var buf bytes.Buffer
+		err := transcriptTemplate.Execute(&buf, map[string]interface{}{
+			"Title": ch.Name, "ThreadID": ch.ID, "ForumID": ch.ParentID, "Exported": exported, "Messages": tms,
+		})
+		return fmt.Sprintf("transcript-%s.html", ch.ID), buf.Bytes(), err
+	}
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "# %s\n\nThread %s in forum %s, exported %s\n", ch.Name, ch.ID, ch.ParentID, exported)
+	for _, tm := range tms {
+		fmt.Fprintf(sb, "\n---\n\n**%s** · %s\n\n", tm.Author, tm.Time)
+		if tm.Content != "" {
+			sb.WriteString(tm.Content + "\n")
+		}
+		for _, a := range tm.Attachments {
+			fmt.Fprintf(sb, "\n📎 [%s](%s)\n", a.Filename, a.URL)
+		}
+	}
+	return fmt.Sprintf("transcript-%s.md", ch.ID), []byte(sb.String()), nil
+}
+
+// uploadTranscript renders the thread's transcript and uploads it to the transcript_channel_id of
+// the thread's server. It
+// returns the link of the uploaded file.
+func (h *handler) uploadTranscript(s *discordgo.Session, ch *discordgo.Channel, msgs []*discordgo.Message, requestedBy string) (string, error) {
+	name, body, err := renderTranscript(h.cfg(), ch, msgs)
+	if err != nil {
+		return "", err
+	}
+	contentType := "text/markdown"
+	if h.cfg().TranscriptFormat == transcriptHTML {
+		contentType = "text/html"
+	}
+	sent, err := s.ChannelMessageSendComplex(h.cfg().forGuild(ch.GuildID).TranscriptChannelID, &discordgo.MessageSend{
+		Content:         fmt.Sprintf("📜 Transcript of **%s** (`%s`, %d messages), requested by <@%s>", ch.Name, ch.ID, len(msgs), requestedBy),
+		Files:           []*discordgo.File{{Name: name, ContentType: contentType, Reader: bytes.NewReader(body)}},
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(sent.Attachments) > 0 {
+		return sent.Attachments[0].URL, nil
+	}
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", ch.GuildID, sent.ChannelID, sent.ID), nil
+}
+
+// handleTranscript implements `.transcript`: export the whole thread to the transcript channel
+func (h *handler) handleTranscript(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel, args string) {
+	if h.cfg().forGuild(ch.GuildID).TranscriptChannelID == "" {
+		replyMessage(s, m.Message, "No transcript channel is configured.")
+		return
+	}
+	msgs, err := fetchAllMessages(s, ch.ID)
+	if err != nil {
+		log.Printf("transcript: failed to read messages of %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, "❌ Failed to read the thread.")
+		return
+	}
+	kept := msgs[:0]
+	for _, msg := range msgs {
+		if msg.ID != m.ID {
+			kept = append(kept, msg)
+		}
+	}
+	link, err := h.uploadTranscript(s, ch, kept, m.Author.ID)
+	if err != nil {
+		log.Printf("transcript: failed to upload transcript of %s: %v", ch.ID, err)
+		replyMessage(s, m.Message, "❌ Failed to upload the transcript.")
+		return
+	}
+	replyMessage(s, m.Message, "📜 Transcript saved: <"+link+">")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// weblateLanguage is the translation progress of one language in a Weblate project
+type weblateLanguage struct {
+	Name              string  `json:"language"`
+	Code              string  `json:"code"`
+	Total             int     `json:"total"`
+	Translated        int     `json:"translated"`
+	TranslatedPercent float64 `json:"translated_percent"`
+	URL               string  `json:"url"`
+}
+
+// fetchTranslationStats returns the per-language statistics of the configured Weblate project
+func (h *handler) fetchTranslationStats() ([]weblateLanguage, error) {
+	tc := h.cfg().Translations
+	var headers map[string]string
+	if tc.Token != "" {
+		headers = map[string]string{"Authorization": "Token " + tc.Token}
+	}
+	var langs []weblateLanguage
+	err := getJSON(fmt.Sprintf("%s/api/projects/%s/languages/", strings.TrimRight(tc.URL, "/"), tc.Project), headers, &langs)
+	return langs, err
+}
+
+// progressBar renders pct (0-100) as a ten-cell bar
+func progressBar(pct float64) string {
+	filled := int(pct/10 + 0.5)
+	if filled > 10 {
+		filled = 10
+	}
+	return strings.Repeat("▰", filled) + strings.Repeat("▱", 10-filled)
+}
+
+// handleTranslations implements `.translations [language]`
+func (h *handler) handleTranslations(s *discordgo.Session, m *discordgo.MessageCreate, args string) {
+	tc := h.cfg().Translations
+	if tc.Project == "" {
+		replyMessage(s, m.Message, "Translation statistics are not configured.")
+		return
+	}
+	langs, err := h.fetchTranslationStats()
+	if err != nil {
+		log.Printf("translations: failed to fetch statistics of %s: %v", tc.Project, err)
+		replyMessage(s, m.Message, "❌ Could not reach the translation platform, try again later.")
+		return
+	}
+	projectURL := fmt.Sprintf("%s/projects/%s/", strings.TrimRight(tc.URL, "/"), tc.Project)
+
+	if q := strings.ToLower(args); q != "" {
+		for _, l := range langs {
+			if strings.ToLower(l.Code) == q || strings.ToLower(l.Name) == q {
+				replyMessage(s, m.Message, fmt.Sprintf("**%s** (`%s`): %s %.1f%% — %d of %d strings translated. Help translate: <%s>",
+					l.Name, l.Code, progressBar(l.TranslatedPercent), l.TranslatedPercent, l.Translated, l.Total, projectURL))
+				return
+			}
+		}
+		replyMessage(s, m.Message, fmt.Sprintf("No translation found for %q. Languages use their name or code, e.g. `%s de`.", args, h.cfg().forGuild(m.GuildID).command("translations")))
+		return
+	}
+
+	sort.Slice(langs, func(i, j int) bool { return langs[i].TranslatedPercent > langs[j].TranslatedPercent })
+	lines := make([]string, 0, len(langs))
+	for _, l := range langs {
+		lines = append(lines, fmt.Sprintf("`%s` %s %5.1f%% %s", progressBar(l.TranslatedPercent), l.Code, l.TranslatedPercent, l.Name))
+	}
+	if len(lines) == 0 {
+		replyMessage(s, m.Message, "The project has no languages yet.")
+		return
+	}
+	pages := embedPages(fmt.Sprintf("Translation progress (%d languages)", len(lines)), lines, 20)
+	for _, p := range pages {
+		p.URL = projectURL
+	}
+	if err := sendPaged(s, m.ChannelID, pages); err != nil {
+		log.Printf("translations: failed to send: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func init() {
+	registerSlashCommand(&discordgo.ApplicationCommand{
+		Name:        "trending",
+		Description: "Show the titles trending on AniList right now",
+		Options: []*discordgo.ApplicationCommandOption{
+			{Type: discordgo.ApplicationCommandOptionString, Name: "type", Description: "Anime or manga (default manga)", Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "Anime", Value: "ANIME"},
+				{Name: "Manga", Value: "MANGA"},
+			}},
+		},
+	}, (*handler).handleTrendingCommand)
+}
+
+// handleTrendingCommand implements /trending [type]
+func (h *handler) handleTrendingCommand(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	mediaType := "MANGA"
+	if o, ok := slashOptions(i)["type"]; ok {
+		mediaType = o.StringValue()
+	}
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredChannelMessageWithSource}); err != nil {
+		log.Printf("trending: failed to acknowledge: %v", err)
+		return
+	}
+	vars := map[string]interface{}{"type": mediaType}
+	if ch, err := s.Channel(i.ChannelID); err != nil || !ch.NSFW {
+		vars["isAdult"] = false
+	}
+	var data struct {
+		Page struct {
+			Media []aniListListing `json:"media"`
+		} `json:"Page"`
+	}
+	err := aniListGraphQL(`query ($type: MediaType, $isAdult: Boolean) {
+		Page(page: 1, perPage: 30) {
+			media(type: $type, isAdult: $isAdult, sort: TRENDING_DESC) {
+				title { romaji english }
+				siteUrl
+				format
+				averageScore
+				nextAiringEpisode { episode airingAt }
+			}
+		}
+	}`, vars, &data)
+	if err != nil || len(data.Page.Media) == 0 {
+		log.Printf("trending: AniList error: %v", err)
+		msg := "❌ Could not reach AniList, try again later."
+		s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg})
+		return
+	}
+	lines := make([]string, 0, len(data.Page.Media))
+	for n, m := range data.Page.Media {
+		lines = append(lines, m.line(n+1))
+	}
+	if err := editPaged(s, i, embedPages("Trending "+humanizeEnum(mediaType)+" on AniList", lines, 10)); err != nil {
+		log.Printf("trending: failed to send: %v", err)
+	}
+}
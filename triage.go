@@ -0,0 +1,173 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const triagePanelsBucket = "triage_panels"
+
+// statusOrder is the display order of the status commands on the triage panel
+var statusOrder = []string{"solved", "aware", "duplicate", "false", "known", "wrong"}
+
+// triagePanel records the pinned control message of a thread
+type triagePanel struct {
+	MessageID string `json:"message_id"`
+}
+
+func init() {
+	registerComponentHandler("triage:", (*handler).handleTriageButton)
+}
+
+// triagePanelMessage builds the panel embed and buttons for a thread in the given status
+func triagePanelMessage(tr localizer, status, actorID string) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	label := tr.T("triage.open")
+	color := 0x5865f2
+	if c, ok := commandConfig[status]; ok {
+		label = strings.Trim(c.Prefix, "[]")
+		color = 0x57f287
+	}
+	desc := tr.T("triage.status", label, time.Now().Unix())
+	if actorID != "" {
+		desc = tr.T("triage.status_by", label, time.Now().Unix(), actorID)
+	}
+	embed := &discordgo.MessageEmbed{Title: tr.T("triage.title"), Description: desc, Color: color}
+
+	var rows []discordgo.MessageComponent
+	var row []discordgo.MessageComponent
+	for _, cmd := range statusOrder {
+		style := discordgo.SecondaryButton
+		if cmd == status {
+			style = discordgo.SuccessButton
+		}
+		row = append(row, discordgo.Button{
+			Label:    strings.Trim(commandConfig[cmd].Prefix, "[]"),
+			Style:    style,
+			CustomID: "triage:" + cmd,
+			Disabled: cmd == status,
+		})
+		if len(row) == 5 {
+			rows = append(rows, discordgo.ActionsRow{Components: row})
+			row = nil
+		}
+	}
+	row = append(row, discordgo.Button{Label: tr.T("triage.lock"), Style: discordgo.DangerButton, CustomID: "triage:lock"})
+	rows = append(rows, discordgo.ActionsRow{Components: row})
+	return embed, rows
+}
+
+// postTriagePanel posts and pins the control panel in a new thread
+func (h *handler) postTriagePanel(s *discordgo.Session, th *discordgo.Channel) {
+	tr := h.localizer(th.GuildID, th.ID, th.ParentID)
+	embed, components := triagePanelMessage(tr, h.cfg().forGuild(th.GuildID).statusFromTitle(th.Name), "")
+	msg, err := s.ChannelMessageSendComplex(th.ID, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}, Components: components})
+	if err != nil {
+		log.Printf("triage: failed to post panel in %s: %v", th.ID, err)
+		return
+	}
+	if err := s.ChannelMessagePin(th.ID, msg.ID); err != nil {
+		log.Printf("triage: failed to pin panel in %s: %v", th.ID, err)
+	}
+	if h.store != nil {
+		if err := h.store.Put(triagePanelsBucket, th.ID, triagePanel{MessageID: msg.ID}); err != nil {
+			log.Printf("triage: failed to save panel for %s: %v", th.ID, err)
+		}
+	}
+}
+
+// refreshTriagePanel updates a thread's panel, if it has one, to show the given status
+func (h *handler) refreshTriagePanel(s *discordgo.Session, threadID, status, actorID string) {
+	if h.store == nil {
+		return
+	}
+	var panel triagePanel
+	if found, err := h.store.Get(triagePanelsBucket, threadID, &panel); err != nil || !found {
+		return
+	}
+	embed, components := triagePanelMessage(h.channelLocalizer(s, threadID), status, actorID)
+	edit := discordgo.NewMessageEdit(threadID, panel.MessageID).SetEmbeds([]*discordgo.MessageEmbed{embed})
+	edit.Components = &components
+	if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+		log.Printf("triage: failed to update panel in %s: %v", threadID, err)
+	}
+}
+
+// handleTriageButton applies the status behind a panel button
+func (h *handler) handleTriageButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	action := strings.TrimPrefix(i.MessageComponentData().CustomID, "triage:")
+	user := interactionUser(i)
+	ch, err := s.Channel(i.ChannelID)
+	if err != nil || user == nil {
+		log.Printf("triage: failed to fetch channel for button: %v", err)
+		return
+	}
+	allowed := h.interactionCanManage(s, i)
+	if !allowed && action == "solved" && h.cfg().OpCanSolve && ch.OwnerID == user.ID {
+		allowed = true
+	}
+	if !allowed {
+		respondEphemeral(s, i, h.interactionReply(s, i, "permission.triage"))
+		return
+	}
+	// thread edits can exceed the 3s interaction deadline, so acknowledge first
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{Type: discordgo.InteractionResponseDeferredMessageUpdate}); err != nil {
+		log.Printf("triage: failed to acknowledge button: %v", err)
+		return
+	}
+
+	if action == "lock" {
+		archived, locked := true, true
+		// the panel goes first: an archived thread's messages can no longer be unpinned or deleted
+		h.removeTriagePanel(s, ch.ID)
+		if _, err := s.ChannelEdit(ch.ID, &discordgo.ChannelEdit{Locked: &locked, Archived: &archived}); err != nil {
+			log.Printf("triage: failed to lock %s: %v", ch.ID, err)
+			sendMessage(s, ch.ID, "❌ Failed to lock the thread. The bot needs Manage Threads.")
+		}
+		return
+	}
+	if _, ok := commandConfig[action]; !ok {
+		return
+	}
+	newName, ok := h.applyStatus(s, ch, action)
+	if !ok {
+		return
+	}
+	h.refreshTriagePanel(s, ch.ID, action, user.ID)
+	data := threadReplyData(s, ch, user.ID)
+	data.Thread, data.Tag = newName, h.cfg().statusTag(ch.ParentID, action)
+	sendMessage(s, ch.ID, h.localizer(ch.GuildID, ch.ID, ch.ParentID).R("command.updated_thread", data, newName))
+}
+
+// onThreadUpdate removes the triage panel once a thread is resolved and locked
+func (h *handler) onThreadUpdate(s *discordgo.Session, t *discordgo.ThreadUpdate) {
+	if h.store == nil || t.Channel == nil || t.ThreadMetadata == nil || !t.ThreadMetadata.Locked {
+		return
+	}
+	if h.threadStatus(s, t.Channel) == "" {
+		return
+	}
+	h.removeTriagePanel(s, t.ID)
+}
+
+// removeTriagePanel unpins and deletes a thread's panel, if it has one, and forgets it
+func (h *handler) removeTriagePanel(s *discordgo.Session, threadID string) {
+	if h.store == nil {
+		return
+	}
+	var panel triagePanel
+	if found, err := h.store.Get(triagePanelsBucket, threadID, &panel); err != nil || !found {
+		return
+	}
+	if err := s.ChannelMessageUnpin(threadID, panel.MessageID); err != nil {
+		log.Printf("triage: failed to unpin panel in %s: %v", threadID, err)
+	}
+	if err := s.ChannelMessageDelete(threadID, panel.MessageID); err != nil {
+		log.Printf("triage: failed to delete panel in %s: %v", threadID, err)
+	}
+	if err := h.store.Delete(triagePanelsBucket, threadID); err != nil {
+		log.Printf("triage: failed to forget panel for %s: %v", threadID, err)
+	}
+}
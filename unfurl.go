@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Feature names accepted in suppress_link_embeds
+const (
+	featureSearch   = "search"
+	featureTriage   = "triage"
+	featureWelcome  = "welcome"
+	featureArchive  = "archive"
+	featureReleases = "releases"
+)
+
+// suppressLinkEmbeds reports whether Discord's automatic link previews should be suppressed for a feature
+func (h *handler) suppressLinkEmbeds(feature string) bool {
+	return h.cfg() != nil && h.cfg().SuppressLinkEmbeds[feature]
+}
+
+// sendFeatureMessage posts a text message on behalf of a feature, suppressing link previews when
+// the feature is listed in suppress_link_embeds. Only use it for plain content: the flag also hides
+// rich embeds attached to the same message.
+func (h *handler) sendFeatureMessage(s *discordgo.Session, feature, channelID, content string) {
+	msg := &discordgo.MessageSend{Content: content}
+	if h.suppressLinkEmbeds(feature) {
+		msg.Flags = discordgo.MessageFlagsSuppressEmbeds
+	}
+	if _, err := s.ChannelMessageSendComplex(channelID, msg); err != nil {
+		log.Printf("failed to send %s message to %s: %v", feature, channelID, err)
+	}
+}
+
+// suppressSourcePreviews hides the link previews of a user's message after the bot answered it
+// with its own richer embed, so the channel does not show the same title twice. Requires Manage Messages.
+func (h *handler) suppressSourcePreviews(s *discordgo.Session, feature string, m *discordgo.Message) {
+	// the bot cannot edit the flags of messages in direct messages
+	if !h.suppressLinkEmbeds(feature) || !strings.Contains(m.Content, "http") || m.GuildID == "" {
+		return
+	}
+	edit := discordgo.NewMessageEdit(m.ChannelID, m.ID)
+	edit.Flags = m.Flags | discordgo.MessageFlagsSuppressEmbeds
+	if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+		log.Printf("failed to suppress link previews on %s: %v", m.ID, err)
+	}
+}
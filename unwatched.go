@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Behaviours for commands used outside the watched forums (config `unwatched_command_reply`)
+const (
+	unwatchedSilent  = "silent"  // ignore the command (default)
+	unwatchedExplain = "explain" // short notice that is removed again after a few seconds
+	unwatchedHint    = "hint"    // notice listing the watched forums, with a button for admins to watch this one
+)
+
+const (
+	// watchedForumsBucket held the forums watched at runtime before they moved to the overrides file
+	watchedForumsBucket = "watched_forums"
+	// unwatchedNoticeTTL is how long an "explain" notice stays in the channel
+	unwatchedNoticeTTL = 15 * time.Second
+	// unknownForumGuild marks a watched forum whose guild could not be looked up, so it is not
+	// looked up again on every message; the next reload retries it
+	unknownForumGuild = "-"
+)
+
+func init() {
+	registerComponentHandler("watchforum:", (*handler).handleWatchForumButton)
+}
+
+// watchedParentIDs returns a sorted snapshot of the watched forum parents of a guild. An empty
+// list means every forum of the guild is watched.
+func (h *handler) watchedParentIDs(guildID string) []string {
+	h.mu.Lock()
+	watched := make(map[string]string, len(h.watchedParents))
+	for id, g := range h.watchedParents {
+		watched[id] = g
+	}
+	h.mu.Unlock()
+	var out []string
+	for id, g := range watched {
+		if g == "" {
+			g = h.lookupForumGuild(id)
+		}
+		if g == guildID {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// lookupForumGuild finds the guild of a watched forum listed without one (top-level
+// forum_parent_ids and the overrides file) and remembers it
+func (h *handler) lookupForumGuild(forumID string) string {
+	guildID := unknownForumGuild
+	ch, err := h.dg.State.Channel(forumID)
+	if err != nil {
+		ch, err = h.dg.Channel(forumID)
+	}
+	if err != nil {
+		log.Printf("failed to look up the server of watched forum %s: %v", forumID, err)
+	} else {
+		guildID = ch.GuildID
+	}
+	h.mu.Lock()
+	if _, ok := h.watchedParents[forumID]; ok {
+		h.watchedParents[forumID] = guildID
+	}
+	h.mu.Unlock()
+	return guildID
+}
+
+// addWatchedParent starts watching a forum of guildID and remembers it across restarts
+func (h *handler) addWatchedParent(forumID, guildID string) error {
+	return h.updateOverrides(func(o *runtimeOverrides) {
+		h.watchedParents[forumID] = guildID
+		o.watch(forumID)
+	})
+}
+
+// removeWatchedParent stops watching a forum added at runtime
+func (h *handler) removeWatchedParent(forumID string) error {
+	return h.updateOverrides(func(o *runtimeOverrides) {
+		delete(h.watchedParents, forumID)
+		o.unwatch(forumID)
+	})
+}
+
+// isWatchedParent reports whether forumID is on the watch list
+func (h *handler) isWatchedParent(forumID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.watchedParents[forumID]
+	return ok
+}
+
+// configuredParent reports whether forumID is listed in forum_parent_ids (at the top level or in
+// a guild section), which only config.yaml can change
+func (h *handler) configuredParent(forumID string) bool {
+	for _, id := range h.cfg().allForumParentIDs() {
+		if id == forumID {
+			return true
+		}
+	}
+	return false
+}
+
+// userIsServerAdmin checks for Administrator or Manage Server, the permissions needed to change
+// which forums the bot watches
+func userIsServerAdmin(s *discordgo.Session, userID, channelID string) bool {
+	perms, err := s.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		log.Printf("failed to fetch permissions of %s: %v", userID, err)
+		return false
+	}
+	return perms&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0
+}
+
+// watchCommandForum resolves the forum argument of .watch / .unwatch (a channel mention or ID) to
+// a forum of the current server. The reply explaining why it could not is returned otherwise.
+func (h *handler) watchCommandForum(s *discordgo.Session, m *discordgo.MessageCreate, args string) (*discordgo.Channel, string) {
+	id := strings.TrimSuffix(strings.TrimPrefix(args, "<#"), ">")
+	if id == "" || strings.IndexFunc(id, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return nil, "Give the forum as a channel mention or ID, e.g. `" + h.cfg().forGuild(m.GuildID).command("watch") + " #bug-reports`."
+	}
+	forum, err := s.Channel(id)
+	if err != nil || forum.GuildID != m.GuildID {
+		return nil, "That channel does not exist in this server."
+	}
+	if forum.Type != discordgo.ChannelTypeGuildForum {
+		return nil, fmt.Sprintf("<#%s> is not a forum channel.", id)
+	}
+	return forum, ""
+}
+
+// handleWatchCommand implements `.watch <forum>`: start watching a forum without editing
+// config.yaml. Without an argument it lists the watched forums of the server. Administrators only.
+func (h *handler) handleWatchCommand(s *discordgo.Session, m *discordgo.MessageCreate, args string) {
+	if !userIsServerAdmin(s, m.Author.ID, m.ChannelID) {
+		replyMessage(s, m.Message, h.channelLocalizer(s, m.ChannelID).R("permission.watch", replyData{User: m.Author.Mention()}))
+		return
+	}
+	if args == "" {
+		var ids []string
+		for _, id := range h.watchedParentIDs(m.GuildID) {
+			ids = append(ids, "<#"+id+">")
+		}
+		if len(ids) == 0 {
+			replyMessage(s, m.Message, "No forums of this server are on the watch list, so commands work in all of its forums. Use `"+h.cfg().forGuild(m.GuildID).command("watch")+" <forum>` to restrict them.")
+			return
+		}
+		replyMessage(s, m.Message, "Watched forums: "+strings.Join(ids, ", "))
+		return
+	}
+	forum, problem := h.watchCommandForum(s, m, args)
+	if forum == nil {
+		replyMessage(s, m.Message, problem)
+		return
+	}
+	if h.isWatchedParent(forum.ID) {
+		replyMessage(s, m.Message, fmt.Sprintf("<#%s> is already watched.", forum.ID))
+		return
+	}
+	if err := h.addWatchedParent(forum.ID, m.GuildID); err != nil {
+		log.Printf("failed to persist watched forum %s: %v", forum.ID, err)
+	}
+	log.Printf("forum %s (%s) added to the watch list by %s", forum.ID, forum.Name, m.Author.ID)
+	replyMessage(s, m.Message, fmt.Sprintf("<#%s> is now watched; commands work in its posts.", forum.ID))
+}
+
+// handleUnwatchCommand implements `.unwatch <forum>` for forums added with .watch or the hint
+// button. Forums listed in forum_parent_ids stay watched until they are removed from config.yaml.
+func (h *handler) handleUnwatchCommand(s *discordgo.Session, m *discordgo.MessageCreate, args string) {
+	if !userIsServerAdmin(s, m.Author.ID, m.ChannelID) {
+		replyMessage(s, m.Message, h.channelLocalizer(s, m.ChannelID).R("permission.watch", replyData{User: m.Author.Mention()}))
+		return
+	}
+	forum, problem := h.watchCommandForum(s, m, args)
+	if forum == nil {
+		replyMessage(s, m.Message, problem)
+		return
+	}
+	switch {
+	case !h.isWatchedParent(forum.ID):
+		replyMessage(s, m.Message, fmt.Sprintf("<#%s> is not watched.", forum.ID))
+		return
+	case h.configuredParent(forum.ID):
+		replyMessage(s, m.Message, fmt.Sprintf("<#%s> is listed in `forum_parent_ids`; remove it from config.yaml instead.", forum.ID))
+		return
+	case len(h.watchedParentIDs(m.GuildID)) == 1:
+		// an empty watch list means every forum of the server is watched
+		replyMessage(s, m.Message, fmt.Sprintf("<#%s> is the only watched forum of this server; unwatching it would make the bot watch all of its forums.", forum.ID))
+		return
+	}
+	if err := h.removeWatchedParent(forum.ID); err != nil {
+		log.Printf("failed to remove watched forum %s: %v", forum.ID, err)
+	}
+	log.Printf("forum %s (%s) removed from the watch list by %s", forum.ID, forum.Name, m.Author.ID)
+	replyMessage(s, m.Message, fmt.Sprintf("<#%s> is no longer watched.", forum.ID))
+}
+
+// replyUnwatchedCommand answers a known command used outside the watched forums according to
+// unwatched_command_reply. Only members allowed to run commands get a reply so regular users
+// typing a dot word are not answered.
+func (h *handler) replyUnwatchedCommand(s *discordgo.Session, m *discordgo.MessageCreate, ch *discordgo.Channel) {
+	mode := unwatchedSilent
+	if cfg := h.cfg().forGuild(ch.GuildID); cfg != nil && cfg.UnwatchedCommandReply != "" {
+		mode = cfg.UnwatchedCommandReply
+	}
+	if mode == unwatchedSilent {
+		return
+	}
+	if has, err := h.userCanManagePosts(s, m.Author.ID, ch); err != nil || !has {
+		return
+	}
+
+	if mode == unwatchedExplain {
+		msg, err := s.ChannelMessageSendReply(m.ChannelID, "This command only works in posts of the forums the bot watches.", m.Reference())
+		if err != nil {
+			log.Printf("failed to send unwatched notice: %v", err)
+			return
+		}
+		time.AfterFunc(unwatchedNoticeTTL, func() {
+			if err := s.ChannelMessageDelete(msg.ChannelID, msg.ID); err != nil {
+				log.Printf("failed to remove unwatched notice: %v", err)
+			}
+		})
+		return
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("This command only works in posts of the forums the bot watches")
+	var ids []string
+	for _, id := range h.watchedParentIDs(ch.GuildID) {
+		ids = append(ids, "<#"+id+">")
+	}
+	if len(ids) > 0 {
+		sb.WriteString(": " + strings.Join(ids, ", "))
+	}
+	sb.WriteString(".")
+	send := &discordgo.MessageSend{Content: sb.String(), Reference: m.Reference()}
+	if isThreadChannel(ch) && ch.ParentID != "" {
+		if forum, err := s.Channel(ch.ParentID); err == nil && forum.Type == discordgo.ChannelTypeGuildForum {
+			send.Components = []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+				discordgo.Button{Label: "Watch #" + forum.Name, Style: discordgo.SecondaryButton, CustomID: "watchforum:" + forum.ID},
+			}}}
+		}
+	}
+	if _, err := s.ChannelMessageSendComplex(m.ChannelID, send); err != nil {
+		log.Printf("failed to send unwatched hint: %v", err)
+	}
+}
+
+// handleWatchForumButton adds a forum to the watch list from the hint button (administrators only)
+func (h *handler) handleWatchForumButton(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Member == nil || i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) == 0 {
+		respondEphemeral(s, i, h.interactionReply(s, i, "permission.watch"))
+		return
+	}
+	forumID := strings.TrimPrefix(i.MessageComponentData().CustomID, "watchforum:")
+	forum, err := s.Channel(forumID)
+	if err != nil || forum.Type != discordgo.ChannelTypeGuildForum || forum.GuildID != i.GuildID {
+		respondEphemeral(s, i, "That forum no longer exists.")
+		return
+	}
+	if err := h.addWatchedParent(forumID, i.GuildID); err != nil {
+		log.Printf("failed to persist watched forum %s: %v", forumID, err)
+	}
+	log.Printf("forum %s (%s) added to the watch list by %s", forumID, forum.Name, i.Member.User.ID)
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("<#%s> is now watched; commands work in its posts.", forumID),
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	if err != nil {
+		log.Printf("failed to respond to interaction: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log"
+)
+
+const userPrefsBucket = "user_prefs"
+
+// userPrefs holds per-user settings chosen through slash commands
+type userPrefs struct {
+	// Tracker is the preferred lookup provider for /manga (anilist, shikimori, kitsu)
+	Tracker string `json:"tracker,omitempty"`
+	// SearchOptOut keeps the user's messages from triggering passive lookups (/searchoptout)
+	SearchOptOut bool `json:"search_opt_out,omitempty"`
+	// TitleLanguage overrides title_language for the user's lookups (/titlelanguage)
+	TitleLanguage string `json:"title_language,omitempty"`
+}
+
+// loadUserPrefs returns the stored preferences of a user, or the zero value. Failures are logged
+// and returned, so lookups can go on with the defaults while a change is not saved over
+// preferences that could not be read.
+func (h *handler) loadUserPrefs(userID string) (userPrefs, error) {
+	var p userPrefs
+	if h.store == nil {
+		return p, nil
+	}
+	if _, err := h.store.Get(userPrefsBucket, userID, &p); err != nil {
+		log.Printf("failed to load preferences of %s: %v", userID, err)
+		return userPrefs{}, err
+	}
+	return p, nil
+}
+
+// saveUserPrefs persists the preferences of a user
+func (h *handler) saveUserPrefs(userID string, p userPrefs) error {
+	if h.store == nil {
+		return nil
+	}
+	return h.store.Put(userPrefsBucket, userID, p)
+}
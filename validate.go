@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Kinds of Discord objects referenced by ID in the configuration
+type idKind int
+
+const (
+	idChannel idKind = iota
+	idForum          // a channel that must be a forum
+	idRole
+	idGuild
+	idUser
+)
+
+// idFields are the settings holding Discord IDs, by YAML key, wherever they appear
+var idFields = map[string]idKind{
+	"forum_parent_ids":      idForum,
+	"search_channels":       idChannel,
+	"channel_id":            idChannel,
+	"transcript_channel_id": idChannel,
+	"heartbeat_channel_id":  idChannel,
+	"log_channel_id":        idChannel,
+	"alert_channel_id":      idChannel,
+	"allowed_role_ids":      idRole,
+	"contributor_role_id":   idRole,
+	"exempt_user_ids":       idUser,
+}
+
+// idKeyedMaps are the sections whose keys are Discord IDs
+var idKeyedMaps = map[string]idKind{
+	"forums":                   idForum,
+	"channel_locales":          idChannel,
+	"search_channel_providers": idChannel,
+	"guilds":                   idGuild,
+	"guild_locales":            idGuild,
+}
+
+// snowflakeRe matches the shape of a Discord ID
+var snowflakeRe = regexp.MustCompile(`^\d{17,20}$`)
+
+// yamlErrorLineRe finds the line number leading YAML decoding errors
+var yamlErrorLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): `)
+
+// idRef is a Discord ID found in the configuration
+type idRef struct {
+	kind  idKind
+	id    string
+	field string
+	line  int
+}
+
+// configProblem is a finding of --validate; line is 0 when it is not tied to a line
+type configProblem struct {
+	line int
+	msg  string
+}
+
+// validateConfig implements --validate: it loads the configuration at path, checks that every
+// Discord ID is shaped like one and, with live, that the servers, channels and roles exist and
+// are visible to the bot. Problems are printed with the offending line; the return value is the
+// exit status.
+func validateConfig(path string, live bool) int {
+	var problems []configProblem
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		// LoadConfig joins the errors it can report together
+		errs := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			errs = joined.Unwrap()
+		}
+		for _, e := range errs {
+			p := configProblem{msg: e.Error()}
+			if m := yamlErrorLineRe.FindStringSubmatch(p.msg); m != nil {
+				p.line, _ = strconv.Atoi(m[1])
+				p.msg = p.msg[len(m[0]):]
+			}
+			problems = append(problems, p)
+		}
+	} else if cfg.DiscordToken == "" {
+		problems = append(problems, configProblem{0, "discord_token is required (or DISCORD_TOKEN or DISCORD_TOKEN_FILE)"})
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		problems = append(problems, configProblem{0, err.Error()})
+	}
+	if os.IsNotExist(err) {
+		problems = append(problems, configProblem{0, "file not found; only environment variables would be used"})
+	}
+	var refs []idRef
+	var doc yaml.Node
+	// IDs given as ${VAR} are checked with the environment's values, while problems are shown
+	// with the lines as written so secrets are not printed
+	if len(src) > 0 && yaml.Unmarshal(src, &doc) == nil && len(doc.Content) > 0 {
+		expandEnv(&doc)
+		collectIDs(doc.Content[0], "", &refs)
+	}
+	for _, r := range refs {
+		if !snowflakeRe.MatchString(r.id) && !envRefRe.MatchString(r.id) {
+			problems = append(problems, configProblem{r.line, fmt.Sprintf("%s: %q is not a Discord ID", r.field, r.id)})
+		}
+	}
+	if live && cfg != nil && cfg.DiscordToken != "" {
+		problems = append(problems, checkIDsLive(cfg.DiscordToken, refs)...)
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("%s: OK (%d Discord IDs checked)\n", path, len(refs))
+		return 0
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].line < problems[j].line })
+	lines := strings.Split(string(src), "\n")
+	for _, p := range problems {
+		if p.line == 0 {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", path, p.msg)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, p.line, p.msg)
+		if p.line <= len(lines) {
+			fmt.Fprintf(os.Stderr, "  %4d | %s\n", p.line, lines[p.line-1])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(problems))
+	return 1
+}
+
+// collectIDs walks a YAML node and records the Discord IDs of idFields and idKeyedMaps
+func collectIDs(n *yaml.Node, path string, refs *[]idRef) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			field := k.Value
+			if path != "" {
+				field = path + "." + k.Value
+			}
+			if kind, ok := idFields[k.Value]; ok {
+				addIDRefs(v, kind, field, refs)
+			}
+			if kind, ok := idKeyedMaps[k.Value]; ok && v.Kind == yaml.MappingNode {
+				for j := 0; j+1 < len(v.Content); j += 2 {
+					key := v.Content[j]
+					*refs = append(*refs, idRef{kind: kind, id: key.Value, field: field + "." + key.Value, line: key.Line})
+				}
+			}
+			collectIDs(v, field, refs)
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			collectIDs(item, fmt.Sprintf("%s[%d]", path, i), refs)
+		}
+	}
+}
+
+// addIDRefs records an ID setting, a single value or a list; empty values are unset settings
+func addIDRefs(v *yaml.Node, kind idKind, field string, refs *[]idRef) {
+	switch v.Kind {
+	case yaml.ScalarNode:
+		if v.Value != "" {
+			*refs = append(*refs, idRef{kind: kind, id: v.Value, field: field, line: v.Line})
+		}
+	case yaml.SequenceNode:
+		for i, item := range v.Content {
+			if item.Kind == yaml.ScalarNode && item.Value != "" {
+				*refs = append(*refs, idRef{kind: kind, id: item.Value, field: fmt.Sprintf("%s[%d]", field, i), line: item.Line})
+			}
+		}
+	}
+}
+
+// checkIDsLive looks the referenced servers, channels and roles up with the bot's token. Users are
+// not checked; the bot can only see members of its servers.
+func checkIDsLive(token string, refs []idRef) []configProblem {
+	s, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return []configProblem{{0, fmt.Sprintf("live check: %v", err)}}
+	}
+	guilds, err := s.UserGuilds(200, "", "", false)
+	if err != nil {
+		return []configProblem{{0, fmt.Sprintf("live check: cannot list the bot's servers (is discord_token valid?): %v", err)}}
+	}
+	inGuild := map[string]bool{}
+	roles := map[string]bool{}
+	for _, g := range guilds {
+		inGuild[g.ID] = true
+		rs, err := s.GuildRoles(g.ID)
+		if err != nil {
+			continue
+		}
+		for _, r := range rs {
+			roles[r.ID] = true
+		}
+	}
+
+	var problems []configProblem
+	channels := map[string]*discordgo.Channel{}
+	for _, r := range refs {
+		if !snowflakeRe.MatchString(r.id) {
+			continue
+		}
+		switch r.kind {
+		case idGuild:
+			if !inGuild[r.id] {
+				problems = append(problems, configProblem{r.line, fmt.Sprintf("%s: the bot is not a member of server %s", r.field, r.id)})
+			}
+		case idRole:
+			if !roles[r.id] {
+				problems = append(problems, configProblem{r.line, fmt.Sprintf("%s: role %s does not exist in any of the bot's servers", r.field, r.id)})
+			}
+		case idChannel, idForum:
+			ch, ok := channels[r.id]
+			if !ok {
+				ch, _ = s.Channel(r.id)
+				channels[r.id] = ch
+			}
+			switch {
+			case ch == nil:
+				problems = append(problems, configProblem{r.line, fmt.Sprintf("%s: channel %s does not exist or is not visible to the bot", r.field, r.id)})
+			case r.kind == idForum && ch.Type != discordgo.ChannelTypeGuildForum:
+				problems = append(problems, configProblem{r.line, fmt.Sprintf("%s: #%s (%s) is not a forum channel", r.field, ch.Name, r.id)})
+			}
+		}
+	}
+	return problems
+}
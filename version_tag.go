@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// versionRe matches app version strings such as "v7.7.1", "7.7" or "Kotatsu 8.0"
+var versionRe = regexp.MustCompile(`(?i)(?:\bv|\bkotatsu\s*v?|\bversion\s*:?\s*v?|\bver\.?\s*v?)(\d+\.\d+(?:\.\d+)?)\b|\b(\d+\.\d+\.\d+)\b`)
+
+// nightlyRe matches mentions of nightly builds, optionally followed by a commit hash
+var nightlyRe = regexp.MustCompile(`(?i)\bnightly(?:[\s#:-]*([0-9a-f]{7,40})\b)?`)
+
+// extractVersions returns the distinct app versions mentioned in text, in order of appearance
+func extractVersions(text string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, m := range versionRe.FindAllStringSubmatch(text, -1) {
+		v := m[1]
+		if v == "" {
+			v = m[2]
+		}
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// compareVersions compares dotted numeric versions, treating missing components as zero
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(strings.ToLower(a), "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(strings.ToLower(b), "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			y, _ = strconv.Atoi(pb[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// matchVersionTag picks the most specific forum tag for a version: "7.7.1" matches a tag named
+// "v7.7.1" or "7.7.1" before "v7.7"/"7.7" and "7.x".
+func matchVersionTag(tags []forumTag, version string) (forumTag, bool) {
+	var best forumTag
+	bestLen := -1
+	for _, t := range tags {
+		name := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(t.Name)), "v")
+		name = strings.TrimSuffix(name, ".x")
+		if name == "" || name[0] < '0' || name[0] > '9' {
+			continue
+		}
+		if (version == name || strings.HasPrefix(version, name+".")) && len(name) > bestLen {
+			best, bestLen = t, len(name)
+		}
+	}
+	return best, bestLen >= 0
+}
+
+// applyVersionTag tags a new post with the app version (or nightly build) it reports, when the
+// forum defines a matching tag
+func (h *handler) applyVersionTag(s *discordgo.Session, th *discordgo.Channel, starter *discordgo.Message, fc ForumConfig) {
+	text := th.Name + "\n" + starter.Content
+	var tag string
+	if nightlyRe.MatchString(text) && fc.NightlyTag != "" {
+		tag = fc.NightlyTag
+	} else {
+		versions := extractVersions(text)
+		if len(versions) == 0 {
+			return
+		}
+		available, err := fetchForumTags(s, th.ParentID)
+		if err != nil {
+			log.Printf("version tag: failed to read tags of forum %s: %v", th.ParentID, err)
+			return
+		}
+		for _, v := range versions {
+			if t, ok := matchVersionTag(available, v); ok {
+				tag = t.Name
+				break
+			}
+		}
+	}
+	if tag == "" {
+		return
+	}
+	if _, err := editThreadTags(s, th, []string{tag}, nil); err != nil {
+		log.Printf("version tag: failed to tag %s with %q: %v", th.ID, tag, err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// onMessageReactionAdd implements community vote-to-solve: when the thread author, or enough
+// community members, react to a reply with the configured emoji, the thread is marked solved and
+// the reply is linked as the fix.
+func (h *handler) onMessageReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if h.cfg() == nil || !h.cfg().SolveVoteEnabled {
+		return
+	}
+	if s.State != nil && s.State.User != nil && r.UserID == s.State.User.ID {
+		return
+	}
+	emoji := h.cfg().SolveVoteEmoji
+	if r.Emoji.Name != emoji && r.Emoji.APIName() != emoji {
+		return
+	}
+
+	ch, err := s.Channel(r.ChannelID)
+	if err != nil {
+		log.Printf("vote: failed to fetch channel: %v", err)
+		return
+	}
+	if !isThreadChannel(ch) || !h.inWatchedForum(ch) {
+		return
+	}
+	// the starter message of a forum post shares the thread's ID; only replies can be fixes
+	if r.MessageID == ch.ID {
+		return
+	}
+	if h.threadStatus(s, ch) == "solved" {
+		return
+	}
+
+	byOP := r.UserID == ch.OwnerID
+	if !byOP {
+		msg, err := s.ChannelMessage(ch.ID, r.MessageID)
+		if err != nil {
+			log.Printf("vote: failed to fetch message %s: %v", r.MessageID, err)
+			return
+		}
+		users, err := s.MessageReactions(ch.ID, r.MessageID, r.Emoji.APIName(), 100, "", "")
+		if err != nil {
+			log.Printf("vote: failed to fetch reactions on %s: %v", r.MessageID, err)
+			return
+		}
+		votes := 0
+		for _, u := range users {
+			// the author of the proposed fix cannot vote for it
+			if u.Bot || (msg.Author != nil && u.ID == msg.Author.ID) {
+				continue
+			}
+			if u.ID == ch.OwnerID {
+				byOP = true
+			}
+			votes++
+		}
+		if !byOP && votes < h.cfg().SolveVoteThreshold {
+			return
+		}
+	}
+
+	// several reactions can arrive at once; only the first one may edit the thread
+	h.mu.Lock()
+	if h.voteSolving[ch.ID] {
+		h.mu.Unlock()
+		return
+	}
+	h.voteSolving[ch.ID] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.voteSolving, ch.ID)
+		h.mu.Unlock()
+	}()
+
+	if _, ok := h.applyStatus(s, ch, "solved"); !ok {
+		return
+	}
+	h.refreshTriagePanel(s, ch.ID, "solved", r.UserID)
+	key := "vote.solved_by_vote"
+	if byOP {
+		key = "vote.solved_by_op"
+	}
+	link := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", ch.GuildID, ch.ID, r.MessageID)
+	h.sendFeatureMessage(s, featureTriage, ch.ID, h.localizer(ch.GuildID, ch.ID, ch.ParentID).T(key, emoji, link))
+}